@@ -0,0 +1,193 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Command gosrt-file sends and receives files over SRT in file mode,
+// resuming interrupted transfers and verifying the files with SHA-256.
+//
+// Usage:
+//
+//	gosrt-file send [flags] file uri
+//	gosrt-file receive [flags] uri
+//
+// The receiver listens on an SRT URI in listener mode and stores the
+// files in a directory, and the sender connects to it as caller:
+//
+//	gosrt-file receive -dir /srv/incoming 'srt://:9000?mode=listener'
+//	gosrt-file send -retries 100 master.mxf 'srt://example.com:9000?passphrase=verylongpassword'
+//
+// The parameters of an SRT URI are described by srt.ParseURI, or by
+// srt.ParseFFmpegURI with -ffmpeg; the transmission type is file and
+// the sender may be a caller or a rendezvous peer. A broken transfer is resumed where it
+// stopped after the -retrydelay, up to -retries times. The receiver
+// keeps the part of a file received so far until the transfer is
+// resumed. SIGINT and SIGTERM end the program; the exit status is 1 if
+// a transfer failed and 2 for usage errors.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+	"github.com/xmedia-systems/gosrt/srt/filetransfer"
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s send [flags] file uri\n       %s receive [flags] uri\n", os.Args[0], os.Args[0])
+	os.Exit(2)
+}
+
+// fileMode returns a context with the transmission type file.
+func fileMode(ctx context.Context) context.Context {
+	return srt.WithConfig(ctx, &srt.Config{TransType: srt.TransTypeFile})
+}
+
+// parseURI parses an SRT URI for the file mode. Its mode must be one of
+// modes.
+func parseURI(uri string, ffmpeg bool, modes ...srt.Mode) (*srt.URI, error) {
+	parse := srt.ParseURI
+	if ffmpeg {
+		parse = srt.ParseFFmpegURI
+	}
+	u, err := parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	ok := false
+	for _, m := range modes {
+		ok = ok || u.Mode == m
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot transfer files in %v mode", u.Mode)
+	}
+	if v, _ := srt.Option(u.Context(fileMode(context.Background())), "transtype"); v != strconv.Itoa(srtapi.TypeFile) {
+		return nil, errors.New("transtype must be file")
+	}
+	return u, nil
+}
+
+// run runs f with a context canceled by SIGINT or SIGTERM, and exits
+// with the status of its result.
+func run(f func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		log.Printf("%v: shutting down", s)
+		cancel()
+		<-sig
+		os.Exit(1)
+	}()
+	err := f(ctx)
+	cancel()
+	sctx, scancel := context.WithTimeout(context.Background(), 5*time.Second)
+	srt.Shutdown(sctx)
+	scancel()
+	if err != nil && err != context.Canceled {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+func send(args []string) {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	var (
+		name       = fs.String("name", "", "name of the file at the receiver; the base name of the file by default")
+		retries    = fs.Int("retries", 10, "number of times a failed transfer is resumed")
+		retryDelay = fs.Duration("retrydelay", 5*time.Second, "delay before a failed transfer is resumed")
+		ffmpeg     = fs.Bool("ffmpeg", false, "parse the URI with the conventions of ffmpeg, latencies in microseconds")
+		quiet      = fs.Bool("q", false, "do not log the failed attempts")
+	)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+	}
+	path := fs.Arg(0)
+	if *name == "" {
+		*name = filepath.Base(path)
+	}
+	u, err := parseURI(fs.Arg(1), *ffmpeg, srt.ModeCaller, srt.ModeRendezvous)
+	if err != nil {
+		log.Printf("%s: %v", fs.Arg(1), err)
+		os.Exit(2)
+	}
+	s := &filetransfer.Sender{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			return u.Dial(fileMode(ctx))
+		},
+		Retries:    *retries,
+		RetryDelay: *retryDelay,
+	}
+	if !*quiet {
+		s.Logf = log.Printf
+	}
+	run(func(ctx context.Context) error {
+		start := time.Now()
+		if err := s.SendFile(ctx, path, *name); err != nil {
+			return err
+		}
+		log.Printf("%s: sent in %v", *name, time.Since(start).Round(time.Millisecond))
+		return nil
+	})
+}
+
+func receive(args []string) {
+	fs := flag.NewFlagSet("receive", flag.ExitOnError)
+	var (
+		dir    = fs.String("dir", ".", "directory to store the files in")
+		ffmpeg = fs.Bool("ffmpeg", false, "parse the URI with the conventions of ffmpeg, latencies in microseconds")
+		quiet  = fs.Bool("q", false, "do not log the transfers")
+	)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	u, err := parseURI(fs.Arg(0), *ffmpeg, srt.ModeListener)
+	if err != nil {
+		log.Printf("%s: %v", fs.Arg(0), err)
+		os.Exit(2)
+	}
+	if fi, err := os.Stat(*dir); err != nil || !fi.IsDir() {
+		log.Printf("-dir %s: not a directory", *dir)
+		os.Exit(2)
+	}
+	r := &filetransfer.Receiver{Dir: *dir}
+	if !*quiet {
+		r.Logf = log.Printf
+	}
+	run(func(ctx context.Context) error {
+		ln, err := u.Listen(fileMode(ctx))
+		if err != nil {
+			return err
+		}
+		log.Printf("listening on %v", ln.Addr())
+		return r.Serve(ctx, ln)
+	})
+}
+
+func main() {
+	log.SetPrefix("gosrt-file: ")
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "send":
+		send(os.Args[2:])
+	case "receive":
+		receive(os.Args[2:])
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+	"github.com/xmedia-systems/gosrt/srt/filetransfer"
+)
+
+const someTimeout = 10 * time.Second
+
+func TestParseURI(t *testing.T) {
+	for _, tt := range []struct {
+		uri    string
+		listen bool
+		ok     bool
+	}{
+		{"srt://127.0.0.1:9000", false, true},
+		{"srt://127.0.0.1:9000?mode=rendezvous&transtype=file", false, true},
+		{"srt://:9000?mode=listener&passphrase=verylongpassword", true, true},
+
+		{"srt://:9000?mode=listener", false, false},
+		{"srt://127.0.0.1:9000", true, false},
+		{"srt://127.0.0.1:9000?transtype=live", false, false},
+		{"udp://127.0.0.1:9000", false, false},
+	} {
+		modes := []srt.Mode{srt.ModeCaller, srt.ModeRendezvous}
+		if tt.listen {
+			modes = []srt.Mode{srt.ModeListener}
+		}
+		_, err := parseURI(tt.uri, false, modes...)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s: got %v; want ok %v", tt.uri, err, tt.ok)
+		}
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosrt-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data := make([]byte, 300000)
+	rand.New(rand.NewSource(1)).Read(data)
+	src := filepath.Join(dir, "in.bin")
+	if err := ioutil.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	lu, err := parseURI("srt://127.0.0.1:0?mode=listener", false, srt.ModeListener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ln, err := lu.Listen(fileMode(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- (&filetransfer.Receiver{Dir: out, Logf: t.Logf}).Serve(ctx, ln) }()
+
+	u, err := parseURI("srt://"+ln.Addr().String(), false, srt.ModeCaller)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &filetransfer.Sender{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			return u.Dial(fileMode(ctx))
+		},
+		Logf: t.Logf,
+	}
+	sctx, scancel := context.WithTimeout(ctx, someTimeout)
+	defer scancel()
+	if err := s.SendFile(sctx, src, "out.bin"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(out, "out.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Errorf("got %d bytes differing from the %d bytes sent", len(b), len(data))
+	}
+
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(someTimeout):
+		t.Fatal("receiver did not stop")
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/cmd/internal/cmdutil"
+	"github.com/xmedia-systems/gosrt/srt"
+	"github.com/xmedia-systems/gosrt/srt/gateway"
+)
+
+// A bridge runs a Gateway in one direction between a UDP address and
+// an SRT URI, connecting the SRT side again when it breaks.
+type bridge struct {
+	g   *gateway.Gateway
+	dir gateway.Direction
+
+	// udp is the address the UDP input is received on, or the UDP
+	// output is sent to.
+	udp     *net.UDPAddr
+	udpName string
+
+	srt     cmdutil.Opener
+	srtName string
+
+	// reconnect is the delay before a broken SRT connection is opened
+	// again. Zero ends the bridge with an error.
+	reconnect time.Duration
+
+	// logf logs the connections and errors.
+	logf func(format string, v ...interface{})
+
+	mu   sync.Mutex
+	conn net.Conn // nil while not connected
+}
+
+// newBridge returns the bridge from the input URI in to the output URI
+// out, one of them udp://host:port and the other an SRT URI.
+func newBridge(g *gateway.Gateway, in, out string, ffmpeg bool) (*bridge, error) {
+	b := &bridge{g: g}
+	udpURI, srtURI := in, out
+	switch {
+	case hasScheme(in, "udp") && hasScheme(out, "srt"):
+		b.dir = gateway.Ingress
+	case hasScheme(in, "srt") && hasScheme(out, "udp"):
+		b.dir = gateway.Egress
+		udpURI, srtURI = out, in
+	default:
+		return nil, fmt.Errorf("%s to %s: want udp:// to srt:// or srt:// to udp://", cmdutil.Redact(in), cmdutil.Redact(out))
+	}
+
+	u, err := url.Parse(udpURI)
+	if err != nil {
+		return nil, err
+	}
+	if u.RawQuery != "" || (u.Path != "" && u.Path != "/") {
+		return nil, fmt.Errorf("%s: unexpected path or parameters", udpURI)
+	}
+	host := strings.TrimPrefix(u.Host, "@") // multicast as written for srt-live-transmit
+	if b.udp, err = net.ResolveUDPAddr("udp", host); err != nil {
+		return nil, fmt.Errorf("%s: %v", udpURI, err)
+	}
+	if b.dir == gateway.Egress && (b.udp.IP == nil || b.udp.Port == 0) {
+		return nil, fmt.Errorf("%s: missing host or port", udpURI)
+	}
+	b.udpName = udpURI
+
+	parse := srt.ParseURI
+	if ffmpeg {
+		parse = srt.ParseFFmpegURI
+	}
+	b.srtName = cmdutil.Redact(srtURI)
+	if b.srt.URI, err = parse(srtURI); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.srtName, err)
+	}
+	return b, nil
+}
+
+func hasScheme(uri, scheme string) bool {
+	return len(uri) > len(scheme)+3 && strings.EqualFold(uri[:len(scheme)+3], scheme+"://")
+}
+
+func (b *bridge) listenUDP() (*net.UDPConn, error) {
+	if b.dir == gateway.Egress {
+		return net.ListenUDP("udp", nil)
+	}
+	if b.udp.IP.IsMulticast() {
+		return net.ListenMulticastUDP("udp", nil, b.udp)
+	}
+	return net.ListenUDP("udp", b.udp)
+}
+
+// run forwards the stream until ctx is done or, without reconnect
+// delay, the SRT connection breaks.
+func (b *bridge) run(ctx context.Context) error {
+	pc, err := b.listenUDP()
+	if err != nil {
+		return fmt.Errorf("%s: %v", b.udpName, err)
+	}
+	defer pc.Close()
+	defer b.srt.Close()
+	b.logf("%v: %s to %s", b.dir, b.input(), b.output())
+	for {
+		c, err := b.connect(ctx)
+		if c == nil {
+			return err
+		}
+		b.setConn(c)
+		if b.dir == gateway.Ingress {
+			err = b.g.UDPToSRT(ctx, pc, c)
+		} else {
+			err = b.g.SRTToUDP(ctx, c, pc, b.udp)
+		}
+		b.setConn(nil)
+		c.Close()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if b.reconnect == 0 {
+			return fmt.Errorf("%s: %v", b.srtName, err)
+		}
+		b.logf("%s: %v; reconnecting in %v", b.srtName, err, b.reconnect)
+		if !cmdutil.Sleep(ctx, b.reconnect) {
+			return nil
+		}
+	}
+}
+
+func (b *bridge) input() string {
+	if b.dir == gateway.Ingress {
+		return b.udpName
+	}
+	return b.srtName
+}
+
+func (b *bridge) output() string {
+	if b.dir == gateway.Ingress {
+		return b.srtName
+	}
+	return b.udpName
+}
+
+// connect opens the SRT connection, retrying after the reconnect delay.
+// It returns a nil connection and error if ctx is done first.
+func (b *bridge) connect(ctx context.Context) (net.Conn, error) {
+	for {
+		c, err := b.srt.Open(ctx)
+		if err == nil {
+			b.logf("%s: connected", b.srtName)
+			return c, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		if b.reconnect == 0 {
+			return nil, fmt.Errorf("%s: %v", b.srtName, err)
+		}
+		b.logf("%s: %v; retrying in %v", b.srtName, err, b.reconnect)
+		if !cmdutil.Sleep(ctx, b.reconnect) {
+			return nil, nil
+		}
+	}
+}
+
+func (b *bridge) setConn(c net.Conn) {
+	b.mu.Lock()
+	b.conn = c
+	b.mu.Unlock()
+}
+
+// A statsRecord holds the counters of a bridge, totalled since it
+// started.
+type statsRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Connected bool      `json:"connected"`
+	gateway.Stats
+
+	// SRT holds the statistics of the current SRT connection.
+	SRT *srt.Stats `json:"srt,omitempty"`
+}
+
+func (b *bridge) collect(now time.Time) statsRecord {
+	r := statsRecord{
+		Time:      now,
+		Direction: b.dir.String(),
+		Stats:     b.g.Stats(b.dir),
+	}
+	// the connection is closed only after it was unset
+	b.mu.Lock()
+	r.Connected = b.conn != nil
+	if sc, ok := b.conn.(*srt.SRTConn); ok {
+		r.SRT, _ = sc.Stats(false)
+	}
+	b.mu.Unlock()
+	return r
+}
+
+// reportStats writes the statistics of b to w as JSON lines every
+// interval until ctx is done.
+func (b *bridge) reportStats(ctx context.Context, interval time.Duration, w io.Writer) {
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			r := b.collect(now)
+			if err := enc.Encode(&r); err != nil {
+				b.logf("stats: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Command gosrt-gateway forwards an MPEG-TS stream from UDP to SRT, or
+// from SRT to UDP.
+//
+// Usage:
+//
+//	gosrt-gateway [flags] input output
+//
+// One of input and output is udp://host:port and the other an SRT URI
+// as described by srt.ParseURI, or by srt.ParseFFmpegURI with -ffmpeg.
+// For example
+//
+//	gosrt-gateway -source 127.0.0.1 udp://:5000 'srt://example.com:9000?latency=200'
+//
+// receives MPEG-TS over UDP or RTP on port 5000 from a local encoder and
+// sends it to an SRT listener, and
+//
+//	gosrt-gateway 'srt://:9000?mode=listener' udp://239.0.0.1:5000
+//
+// serves an SRT caller and sends its stream to a multicast group.
+//
+// RTP headers are stripped, and the stream is forwarded in chunks of
+// whole TS packets, -packets at most; data is discarded while the TS
+// sync is lost. A broken SRT connection is opened again after the
+// -reconnect delay, and a listener accepts the next caller. SIGINT and
+// SIGTERM end the forwarding; the exit status is 1 if the connection
+// failed for good and 2 for usage errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+	"github.com/xmedia-systems/gosrt/srt/gateway"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] input output\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+// parseSource returns the address of a -source flag, an IP address
+// with an optional port.
+func parseSource(s string) (*net.UDPAddr, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		return &net.UDPAddr{IP: ip}, nil
+	}
+	addr, err := net.ResolveUDPAddr("udp", s)
+	if err != nil {
+		return nil, err
+	}
+	if addr.IP == nil {
+		return nil, fmt.Errorf("%s: missing host", s)
+	}
+	return addr, nil
+}
+
+func main() {
+	var (
+		packets    = flag.Int("packets", gateway.DefaultPackets, "maximum number of TS packets per SRT message or UDP datagram")
+		source     = flag.String("source", "", "accept UDP input only from this `host[:port]`")
+		reconnect  = flag.Duration("reconnect", time.Second, "delay before a broken SRT connection is opened again; 0 exits instead")
+		ffmpeg     = flag.Bool("ffmpeg", false, "parse srt:// URIs with the conventions of ffmpeg, latencies in microseconds")
+		statsEvery = flag.Duration("stats", 0, "interval of the statistics output as JSON lines; 0 disables it")
+		statsFile  = flag.String("statsout", "", "file to write the statistics to instead of the standard error")
+		quiet      = flag.Bool("q", false, "do not log connections and errors")
+	)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 2 {
+		usage()
+		os.Exit(2)
+	}
+	if *packets <= 0 || *packets*gateway.TSPacketSize > 65507 {
+		fmt.Fprintln(os.Stderr, "invalid -packets")
+		os.Exit(2)
+	}
+
+	log.SetPrefix("gosrt-gateway: ")
+	logf := log.Printf
+	if *quiet {
+		logf = func(string, ...interface{}) {}
+	}
+
+	g := &gateway.Gateway{Packets: *packets}
+	if *source != "" {
+		addr, err := parseSource(*source)
+		if err != nil {
+			log.Printf("-source: %v", err)
+			os.Exit(2)
+		}
+		g.Source = addr
+	}
+	b, err := newBridge(g, flag.Arg(0), flag.Arg(1), *ffmpeg)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	if g.Source != nil && b.dir != gateway.Ingress {
+		log.Print("-source applies to UDP input only")
+		os.Exit(2)
+	}
+	b.reconnect = *reconnect
+	b.logf = logf
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		logf("%v: shutting down", s)
+		cancel()
+		<-sig
+		os.Exit(1)
+	}()
+
+	if *statsEvery > 0 {
+		w := os.Stderr
+		if *statsFile != "" {
+			f, err := os.Create(*statsFile)
+			if err != nil {
+				log.Print(err)
+				os.Exit(2)
+			}
+			defer f.Close()
+			w = f
+		}
+		go b.reportStats(ctx, *statsEvery, w)
+	}
+
+	err = b.run(ctx)
+	cancel()
+	sctx, scancel := context.WithTimeout(context.Background(), 5*time.Second)
+	srt.Shutdown(sctx)
+	scancel()
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+	"github.com/xmedia-systems/gosrt/srt/gateway"
+)
+
+const someTimeout = 10 * time.Second
+
+func TestNewBridge(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+		dir     gateway.Direction
+		ok      bool
+	}{
+		{"udp://:5000", "srt://127.0.0.1:9000", gateway.Ingress, true},
+		{"udp://@239.0.0.1:5000", "srt://:9000?mode=listener", gateway.Ingress, true},
+		{"SRT://127.0.0.1:9000", "udp://127.0.0.1:5000", gateway.Egress, true},
+
+		{"srt://127.0.0.1:9000", "udp://:5000", 0, false},
+		{"udp://:5000", "udp://127.0.0.1:5001", 0, false},
+		{"udp://:5000?ttl=2", "srt://127.0.0.1:9000", 0, false},
+		{"udp://:5000", "srt://127.0.0.1:9000?mode=push", 0, false},
+		{"file:///tmp/a.ts", "srt://127.0.0.1:9000", 0, false},
+	} {
+		b, err := newBridge(&gateway.Gateway{}, tt.in, tt.out, false)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%s to %s: got nil error", tt.in, tt.out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s to %s: %v", tt.in, tt.out, err)
+			continue
+		}
+		if b.dir != tt.dir {
+			t.Errorf("%s to %s: got %v; want %v", tt.in, tt.out, b.dir, tt.dir)
+		}
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want string // empty if invalid
+	}{
+		{"127.0.0.1", "127.0.0.1:0"},
+		{"127.0.0.1:5000", "127.0.0.1:5000"},
+		{"::1", "[::1]:0"},
+		{":5000", ""},
+		{"127.0.0.1:x", ""},
+	} {
+		addr, err := parseSource(tt.s)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %v; want error", tt.s, addr)
+			}
+			continue
+		}
+		if err != nil || addr.String() != tt.want {
+			t.Errorf("%s: got %v, %v; want %s", tt.s, addr, err, tt.want)
+		}
+	}
+}
+
+func TestBridgeIngress(t *testing.T) {
+	ln, err := srt.Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udpAddr := pc.LocalAddr().String()
+	pc.Close()
+
+	b, err := newBridge(&gateway.Gateway{}, "udp://"+udpAddr, "srt://"+ln.Addr().String(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.reconnect = 10 * time.Millisecond
+	b.logf = t.Logf
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- b.run(ctx) }()
+
+	ln.(*srt.SRTListener).SetDeadline(time.Now().Add(someTimeout))
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	uc, err := net.Dial("udp", udpAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uc.Close()
+	msg := make([]byte, 1316)
+	for i := 0; i < len(msg); i += gateway.TSPacketSize {
+		msg[i] = 0x47
+	}
+	// the bridge may not listen yet; send until a message arrives
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			uc.Write(msg)
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	sc.SetReadDeadline(time.Now().Add(someTimeout))
+	buf := make([]byte, 1500)
+	n, err := sc.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], msg) {
+		t.Errorf("got %d bytes; want the %d bytes sent", n, len(msg))
+	}
+
+	r := b.collect(time.Now())
+	if r.Direction != "ingress" || !r.Connected || r.SRT == nil || r.TSPackets == 0 {
+		t.Errorf("got %+v; want the statistics of a connected ingress", r)
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(someTimeout):
+		t.Fatal("bridge did not stop")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Command gosrt-transmit forwards a live stream from a source to one or
+// more targets, like srt-live-transmit.
+//
+// Usage:
+//
+//	gosrt-transmit [flags] source target [target...]
+//
+// The source and the targets are URIs:
+//
+//	srt://host:port?params   SRT as caller, listener or rendezvous peer
+//	udp://host:port          UDP; a source listens on host:port and
+//	                         joins the group of a multicast address
+//	file:///path             a file
+//	file://con or -          the standard input or output
+//
+// The parameters of an SRT URI are described by srt.ParseURI, or by
+// srt.ParseFFmpegURI with -ffmpeg. For example
+//
+//	gosrt-transmit -stats 5s udp://:5000 'srt://:9000?mode=listener&latency=200'
+//
+// receives MPEG-TS over UDP on port 5000 and serves it to an SRT caller
+// on port 9000.
+//
+// Each target has a queue of chunks. A target that cannot keep up with
+// a live source loses the chunks exceeding its queue, without holding
+// up the other targets. With a file or the standard input as source,
+// the targets are waited for instead, and the transmission ends at the
+// end of the input.
+//
+// A broken source or target is opened again after the -reconnect
+// delay, and listeners accept the next caller. SIGINT and SIGTERM end
+// the transmission; the exit status is 1 if an endpoint failed for
+// good and 2 for usage errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] source target [target...]\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	var (
+		chunk       = flag.Int("chunk", 1316, "size in bytes of the chunks read from a file or the standard input")
+		buffer      = flag.Int("buffer", 1000, "number of chunks queued per target")
+		reconnect   = flag.Duration("reconnect", time.Second, "delay before a broken source or target is opened again; 0 exits instead")
+		ffmpeg      = flag.Bool("ffmpeg", false, "parse srt:// URIs with the conventions of ffmpeg, latencies in microseconds")
+		statsEvery  = flag.Duration("stats", 0, "interval of the statistics output; 0 disables it")
+		statsFormat = flag.String("statsformat", "json", "format of the statistics: json or csv")
+		statsFile   = flag.String("statsout", "", "file to write the statistics to instead of the standard error")
+		quiet       = flag.Bool("q", false, "do not log connections and errors")
+	)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 2 {
+		usage()
+		os.Exit(2)
+	}
+	if *chunk <= 0 || *chunk > maxChunk || *buffer <= 0 {
+		fmt.Fprintln(os.Stderr, "invalid -chunk or -buffer")
+		os.Exit(2)
+	}
+
+	log.SetPrefix("gosrt-transmit: ")
+	logf := log.Printf
+	if *quiet {
+		logf = func(string, ...interface{}) {}
+	}
+
+	source, err := parseMedium(flag.Arg(0), false, *ffmpeg, *chunk)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	var targets []medium
+	for _, arg := range flag.Args()[1:] {
+		m, err := parseMedium(arg, true, *ffmpeg, *chunk)
+		if err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+		targets = append(targets, m)
+	}
+	t := newTransmitter(source, targets, *buffer, *reconnect, logf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		logf("%v: shutting down", s)
+		cancel()
+		<-sig
+		os.Exit(1)
+	}()
+
+	if *statsEvery > 0 {
+		var w io.Writer = os.Stderr
+		if *statsFile != "" {
+			f, err := os.Create(*statsFile)
+			if err != nil {
+				log.Print(err)
+				os.Exit(2)
+			}
+			defer f.Close()
+			w = f
+		}
+		sw, err := newStatsWriter(w, *statsFormat)
+		if err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+		go t.reportStats(ctx, *statsEvery, sw)
+	}
+
+	err = t.run(ctx)
+	cancel()
+	sctx, scancel := context.WithTimeout(context.Background(), 5*time.Second)
+	srt.Shutdown(sctx)
+	scancel()
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
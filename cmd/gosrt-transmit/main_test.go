@@ -0,0 +1,249 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+const someTimeout = 10 * time.Second
+
+func TestParseMedium(t *testing.T) {
+	for _, tt := range []struct {
+		uri   string
+		write bool
+		name  string // empty if invalid
+	}{
+		{"-", false, "stdin"},
+		{"file://con", true, "stdout"},
+		{"file:///tmp/a.ts", true, "file:///tmp/a.ts"},
+		{"udp://:5000", false, "udp://:5000"},
+		{"udp://@239.0.0.1:5000", false, "udp://@239.0.0.1:5000"},
+		{"udp://127.0.0.1:5000", true, "udp://127.0.0.1:5000"},
+		{"srt://:9000?mode=listener", false, "srt://:9000?mode=listener"},
+		{"srt://127.0.0.1:9000?passphrase=verylongpassword&latency=200", true, "srt://127.0.0.1:9000?passphrase=***&latency=200"},
+
+		{"udp://:5000", true, ""},
+		{"udp://127.0.0.1:5000?ttl=2", true, ""},
+		{"srt://127.0.0.1:9000?mode=push", true, ""},
+		{"file://", false, ""},
+		{"http://example.com/", false, ""},
+		{"/tmp/a.ts", false, ""},
+	} {
+		m, err := parseMedium(tt.uri, tt.write, false, 1316)
+		if tt.name == "" {
+			if err == nil {
+				t.Errorf("%s: got %v; want error", tt.uri, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.uri, err)
+			continue
+		}
+		if m.String() != tt.name {
+			t.Errorf("%s: got %s; want %s", tt.uri, m, tt.name)
+		}
+	}
+}
+
+func TestTransmitFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosrt-transmit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(data)
+	in := filepath.Join(dir, "in.ts")
+	if err := ioutil.WriteFile(in, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := parseMedium("file://"+in, false, false, 1316)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targets []medium
+	for _, name := range []string{"out1.ts", "out2.ts"} {
+		m, err := parseMedium("file://"+filepath.Join(dir, name), true, false, 1316)
+		if err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, m)
+	}
+	// a queue of one chunk makes the source wait for the targets
+	tr := newTransmitter(source, targets, 1, 0, t.Logf)
+	if err := tr.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"out1.ts", "out2.ts"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, data) {
+			t.Errorf("%s: got %d bytes differing from the %d bytes sent", name, len(b), len(data))
+		}
+	}
+
+	bad, err := parseMedium("file://"+filepath.Join(dir, "missing", "out.ts"), true, false, 1316)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr = newTransmitter(source, []medium{bad}, 1, 0, t.Logf)
+	if err := tr.run(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "target ") {
+		t.Errorf("got %v; want an error of the target", err)
+	}
+}
+
+func TestTransmitUDPToSRT(t *testing.T) {
+	ln, err := srt.Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udpAddr := pc.LocalAddr().String()
+	pc.Close()
+
+	source, err := parseMedium("udp://"+udpAddr, false, false, 1316)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := parseMedium("srt://"+ln.Addr().String()+"?streamid=test", true, false, 1316)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := newTransmitter(source, []medium{target}, 100, 10*time.Millisecond, t.Logf)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- tr.run(ctx) }()
+
+	ln.(*srt.SRTListener).SetDeadline(time.Now().Add(someTimeout))
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	uc, err := net.Dial("udp", udpAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uc.Close()
+	msg := bytes.Repeat([]byte{0x47}, 1316)
+	b := make([]byte, 1500)
+	deadline := time.Now().Add(someTimeout)
+	sc.SetReadDeadline(deadline)
+	// the source may not listen yet; send until a chunk arrives
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			uc.Write(msg)
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	n, err := sc.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:n], msg) {
+		t.Errorf("got %d bytes; want the %d bytes sent", n, len(msg))
+	}
+
+	recs := collect(time.Now(), tr.endpoints())
+	if len(recs) != 2 || recs[0].Role != "source" || recs[1].Role != "target" {
+		t.Fatalf("got %+v; want a source and a target", recs)
+	}
+	if !recs[1].Connected || recs[1].SRT == nil || recs[0].SRT != nil {
+		t.Errorf("got %+v; want SRT statistics of the target only", recs)
+	}
+	if recs[0].Chunks == 0 {
+		t.Errorf("got %+v; want chunks read by the source", recs[0])
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(someTimeout):
+		t.Fatal("transmitter did not stop")
+	}
+}
+
+func TestStatsWriter(t *testing.T) {
+	now := time.Unix(1600000000, 0).UTC()
+	recs := []statsRecord{
+		{Time: now, Role: "source", Endpoint: "udp://:5000", Connected: true, Chunks: 10, Bytes: 13160},
+		{Time: now, Role: "target", Endpoint: "srt://127.0.0.1:9000", Connected: true, Chunks: 9, Bytes: 11844, Dropped: 1, SRT: &srt.Stats{Link: srt.LinkStats{RTT: 1.5}}},
+	}
+
+	var buf bytes.Buffer
+	w, err := newStatsWriter(&buf, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.write(recs); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines; want 2", len(lines))
+	}
+	var got statsRecord
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Dropped != 1 || got.SRT == nil || got.SRT.Link.RTT != 1.5 {
+		t.Errorf("got %+v; want %+v", got, recs[1])
+	}
+
+	buf.Reset()
+	if w, err = newStatsWriter(&buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	w.write(recs)
+	if err := w.write(recs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "time" {
+		t.Fatalf("got %q; want a header and 3 rows", rows)
+	}
+	if rows[2][7] != "1.500" || rows[1][7] != "" {
+		t.Errorf("got RTT %q and %q; want empty and 1.500", rows[1][7], rows[2][7])
+	}
+
+	if _, err := newStatsWriter(&buf, "xml"); err == nil {
+		t.Error("got nil error for an unknown format")
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/xmedia-systems/gosrt/cmd/internal/cmdutil"
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+// A medium is the source or a target of a stream. Open returns a
+// connection to read the stream from or to write it to. A listening
+// medium blocks in Open until a peer connects, and keeps listening
+// between connections until it is closed.
+type medium interface {
+	Open(ctx context.Context) (io.ReadWriteCloser, error)
+	Close() error
+	String() string
+}
+
+// parseMedium returns the medium of uri. write tells whether the medium
+// is a target.
+//
+// uri is one of
+//
+//	srt://host:port?params   SRT in caller, listener or rendezvous mode
+//	udp://host:port          UDP, received on host:port by a source
+//	file:///path             a file
+//	file://con or -          the standard input or output
+func parseMedium(uri string, write, ffmpeg bool, chunk int) (medium, error) {
+	if uri == "-" {
+		return &stdioMedium{write: write, chunk: chunk}, nil
+	}
+	i := strings.Index(uri, "://")
+	if i < 0 {
+		return nil, fmt.Errorf("%s: missing scheme", uri)
+	}
+	switch strings.ToLower(uri[:i]) {
+	case "srt":
+		parse := srt.ParseURI
+		if ffmpeg {
+			parse = srt.ParseFFmpegURI
+		}
+		u, err := parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cmdutil.Redact(uri), err)
+		}
+		return &srtMedium{srt: cmdutil.Opener{URI: u}, name: cmdutil.Redact(uri)}, nil
+	case "udp":
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		if u.RawQuery != "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("%s: unexpected path or parameters", uri)
+		}
+		host := strings.TrimPrefix(u.Host, "@") // multicast as written for srt-live-transmit
+		addr, err := net.ResolveUDPAddr("udp", host)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", uri, err)
+		}
+		if write && (addr.IP == nil || addr.Port == 0) {
+			return nil, fmt.Errorf("%s: missing host or port", uri)
+		}
+		return &udpMedium{addr: addr, write: write, name: uri}, nil
+	case "file":
+		path := uri[i+3:]
+		if path == "con" {
+			return &stdioMedium{write: write, chunk: chunk}, nil
+		}
+		if path == "" {
+			return nil, fmt.Errorf("%s: missing path", uri)
+		}
+		return &fileMedium{path: path, write: write, chunk: chunk}, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported scheme", uri)
+}
+
+// srtMedium is an SRT connection.
+type srtMedium struct {
+	srt  cmdutil.Opener
+	name string
+}
+
+func (m *srtMedium) Open(ctx context.Context) (io.ReadWriteCloser, error) {
+	c, err := m.srt.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (m *srtMedium) Close() error { return m.srt.Close() }
+
+func (m *srtMedium) String() string { return m.name }
+
+// udpMedium is a UDP socket. A source receives the datagrams sent to
+// addr, joining the group if addr is a multicast address. A target
+// sends the datagrams to addr.
+type udpMedium struct {
+	addr  *net.UDPAddr
+	write bool
+	name  string
+}
+
+func (m *udpMedium) Open(ctx context.Context) (io.ReadWriteCloser, error) {
+	if m.write {
+		return net.DialUDP("udp", nil, m.addr)
+	}
+	if m.addr.IP.IsMulticast() {
+		return net.ListenMulticastUDP("udp", nil, m.addr)
+	}
+	return net.ListenUDP("udp", m.addr)
+}
+
+func (m *udpMedium) Close() error { return nil }
+
+func (m *udpMedium) String() string { return m.name }
+
+// fileMedium is a file. A source reads it in chunks of chunk bytes
+// and ends at the end of the file. A target truncates the file when it
+// is opened first and appends to it when it is opened again.
+type fileMedium struct {
+	path   string
+	write  bool
+	chunk  int
+	opened bool
+}
+
+func (m *fileMedium) Open(ctx context.Context) (io.ReadWriteCloser, error) {
+	if !m.write {
+		f, err := os.Open(m.path)
+		if err != nil {
+			return nil, err
+		}
+		return &chunkReader{f, m.chunk}, nil
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if m.opened {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(m.path, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+	m.opened = true
+	return f, nil
+}
+
+func (m *fileMedium) Close() error { return nil }
+
+func (m *fileMedium) String() string { return "file://" + m.path }
+
+// stdioMedium is the standard input of a source or the standard output
+// of a target.
+type stdioMedium struct {
+	write bool
+	chunk int
+}
+
+var errWriteOnly = errors.New("standard output is write only")
+
+func (m *stdioMedium) Open(ctx context.Context) (io.ReadWriteCloser, error) {
+	if m.write {
+		return stdout{}, nil
+	}
+	return &chunkReader{os.Stdin, m.chunk}, nil
+}
+
+func (m *stdioMedium) Close() error { return nil }
+
+func (m *stdioMedium) String() string {
+	if m.write {
+		return "stdout"
+	}
+	return "stdin"
+}
+
+// stdout writes to the standard output, which is left open by Close.
+type stdout struct{}
+
+func (stdout) Read(b []byte) (int, error)  { return 0, errWriteOnly }
+func (stdout) Write(b []byte) (int, error) { return os.Stdout.Write(b) }
+func (stdout) Close() error                { return nil }
+
+// chunkReader reads a file in chunks of at most n bytes.
+type chunkReader struct {
+	*os.File
+	n int
+}
+
+func (r *chunkReader) Read(b []byte) (int, error) {
+	if len(b) > r.n {
+		b = b[:r.n]
+	}
+	n, err := io.ReadFull(r.File, b)
+	if err == io.ErrUnexpectedEOF {
+		// the last chunk; the next read returns io.EOF
+		err = nil
+	}
+	return n, err
+}
+
+// isStream reports whether m is read at its own pace rather than in
+// real time. Targets are waited for instead of dropping chunks then,
+// and the end of the input ends the transmission.
+func isStream(m medium) bool {
+	switch m.(type) {
+	case *fileMedium, *stdioMedium:
+		return true
+	}
+	return false
+}
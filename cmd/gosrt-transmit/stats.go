@@ -0,0 +1,152 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+// A statsRecord holds the statistics of an endpoint over one report
+// interval.
+type statsRecord struct {
+	Time      time.Time `json:"time"`
+	Role      string    `json:"role"`
+	Endpoint  string    `json:"endpoint"`
+	Connected bool      `json:"connected"`
+
+	// Chunks and Bytes count the data read from the source or
+	// written to a target. Dropped counts the chunks a target lost
+	// because its queue was full.
+	Chunks  int64 `json:"chunks"`
+	Bytes   int64 `json:"bytes"`
+	Dropped int64 `json:"dropped"`
+
+	// SRT holds the statistics of an SRT connection.
+	SRT *srt.Stats `json:"srt,omitempty"`
+}
+
+// collect returns the statistics of the endpoints since the last call
+// and resets the interval counters.
+func collect(now time.Time, endpoints []*endpoint) []statsRecord {
+	recs := make([]statsRecord, 0, len(endpoints))
+	for _, e := range endpoints {
+		r := statsRecord{
+			Time:     now,
+			Role:     e.role,
+			Endpoint: e.String(),
+			Chunks:   atomic.SwapInt64(&e.chunks, 0),
+			Bytes:    atomic.SwapInt64(&e.bytes, 0),
+			Dropped:  atomic.SwapInt64(&e.dropped, 0),
+		}
+		// the connection is closed only after it was unset
+		e.mu.Lock()
+		r.Connected = e.conn != nil
+		if sc, ok := e.conn.(*srt.SRTConn); ok {
+			r.SRT, _ = sc.Stats(true)
+		}
+		e.mu.Unlock()
+		recs = append(recs, r)
+	}
+	return recs
+}
+
+var csvHeader = []string{
+	"time", "role", "endpoint", "connected", "chunks", "bytes", "dropped",
+	"rtt_ms", "bandwidth_mbps", "send_mbps", "recv_mbps",
+	"sent_packets", "recv_packets", "send_lost", "recv_lost",
+	"retransmitted", "send_dropped", "recv_dropped",
+	"send_buffer_ms", "recv_buffer_ms",
+}
+
+// A statsWriter writes statistics records as JSON lines or as CSV.
+type statsWriter struct {
+	enc    *json.Encoder
+	csv    *csv.Writer
+	header bool
+}
+
+func newStatsWriter(w io.Writer, format string) (*statsWriter, error) {
+	switch format {
+	case "json":
+		return &statsWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &statsWriter{csv: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown stats format %q", format)
+}
+
+func (s *statsWriter) write(recs []statsRecord) error {
+	if s.enc != nil {
+		for i := range recs {
+			if err := s.enc.Encode(&recs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !s.header {
+		s.csv.Write(csvHeader)
+		s.header = true
+	}
+	for _, r := range recs {
+		row := []string{
+			r.Time.Format(time.RFC3339Nano),
+			r.Role,
+			r.Endpoint,
+			strconv.FormatBool(r.Connected),
+			strconv.FormatInt(r.Chunks, 10),
+			strconv.FormatInt(r.Bytes, 10),
+			strconv.FormatInt(r.Dropped, 10),
+		}
+		if st := r.SRT; st != nil {
+			float := func(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) }
+			row = append(row,
+				float(st.Link.RTT),
+				float(st.Link.Bandwidth),
+				float(st.Send.MbitRate),
+				float(st.Recv.MbitRate),
+				strconv.FormatInt(st.Send.Packets, 10),
+				strconv.FormatInt(st.Recv.Packets, 10),
+				strconv.Itoa(st.Send.PacketsLost),
+				strconv.Itoa(st.Recv.PacketsLost),
+				strconv.Itoa(st.Send.PacketsRetransmitted),
+				strconv.Itoa(st.Send.PacketsDropped),
+				strconv.Itoa(st.Recv.PacketsDropped),
+				strconv.Itoa(st.Send.BufferMs),
+				strconv.Itoa(st.Recv.BufferMs),
+			)
+		} else {
+			row = append(row, make([]string, len(csvHeader)-len(row))...)
+		}
+		s.csv.Write(row)
+	}
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+// reportStats writes the statistics of the endpoints of t every
+// interval until ctx is done.
+func (t *transmitter) reportStats(ctx context.Context, interval time.Duration, s *statsWriter) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := s.write(collect(now, t.endpoints())); err != nil {
+				t.logf("stats: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/cmd/internal/cmdutil"
+)
+
+// maxChunk is the size of the read buffer, large enough for any UDP
+// datagram and SRT message.
+const maxChunk = 65536
+
+// An endpoint is the source or a target of a transmitter, with the
+// connection currently open on it and its counters.
+type endpoint struct {
+	// The counters come first to be 64-bit aligned for the atomic
+	// operations.
+	chunks  int64
+	bytes   int64
+	dropped int64
+
+	medium
+	role string
+
+	// ch queues the chunks of a target.
+	ch chan []byte
+
+	mu   sync.Mutex
+	conn io.ReadWriteCloser // nil while not connected
+}
+
+func (e *endpoint) setConn(c io.ReadWriteCloser) {
+	e.mu.Lock()
+	e.conn = c
+	e.mu.Unlock()
+}
+
+func (e *endpoint) count(n int) {
+	atomic.AddInt64(&e.chunks, 1)
+	atomic.AddInt64(&e.bytes, int64(n))
+}
+
+// A transmitter reads chunks from its source and writes them to all
+// of its targets.
+type transmitter struct {
+	source  *endpoint
+	targets []*endpoint
+
+	// reconnect is the delay before a broken source or target is
+	// opened again. Zero ends the transmission with an error.
+	reconnect time.Duration
+
+	// logf logs the connections and errors.
+	logf func(format string, v ...interface{})
+}
+
+// newTransmitter returns a transmitter from source to targets. buffer
+// is the number of chunks queued for each target.
+func newTransmitter(source medium, targets []medium, buffer int, reconnect time.Duration, logf func(string, ...interface{})) *transmitter {
+	t := &transmitter{
+		source:    &endpoint{medium: source, role: "source"},
+		reconnect: reconnect,
+		logf:      logf,
+	}
+	for _, m := range targets {
+		t.targets = append(t.targets, &endpoint{medium: m, role: "target", ch: make(chan []byte, buffer)})
+	}
+	return t
+}
+
+// endpoints returns the source and the targets of t.
+func (t *transmitter) endpoints() []*endpoint {
+	return append([]*endpoint{t.source}, t.targets...)
+}
+
+// run transmits until the end of the source, until ctx is done or
+// until an endpoint fails for good. A target which cannot keep up with
+// a live source loses the chunks that do not fit in its queue, so that
+// it does not hold up the other targets.
+func (t *transmitter) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		errc = make(chan error, len(t.targets)+1)
+	)
+	for _, e := range t.targets {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			if err := t.runTarget(ctx, e); err != nil {
+				errc <- err
+				cancel()
+			}
+		}(e)
+	}
+	if err := t.runSource(ctx); err != nil {
+		errc <- err
+	}
+	for _, e := range t.targets {
+		close(e.ch)
+	}
+	wg.Wait()
+	for _, e := range t.endpoints() {
+		e.Close()
+	}
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// open opens e, retrying after the reconnect delay. It returns nil
+// if ctx is done first.
+func (t *transmitter) open(ctx context.Context, e *endpoint) (io.ReadWriteCloser, error) {
+	for {
+		c, err := e.Open(ctx)
+		if err == nil {
+			t.logf("%s %v: connected", e.role, e)
+			return c, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		if t.reconnect == 0 {
+			return nil, fmt.Errorf("%s %v: %v", e.role, e, err)
+		}
+		t.logf("%s %v: %v; retrying in %v", e.role, e, err, t.reconnect)
+		if !cmdutil.Sleep(ctx, t.reconnect) {
+			return nil, nil
+		}
+	}
+}
+
+// broken handles the error that closed the connection of e. It reports
+// whether e is to be opened again, and otherwise returns the error
+// ending the transmission, if any.
+func (t *transmitter) broken(ctx context.Context, e *endpoint, err error) (again bool, _ error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	if t.reconnect == 0 {
+		return false, fmt.Errorf("%s %v: %v", e.role, e, err)
+	}
+	t.logf("%s %v: %v; reconnecting in %v", e.role, e, err, t.reconnect)
+	return cmdutil.Sleep(ctx, t.reconnect), nil
+}
+
+func (t *transmitter) runSource(ctx context.Context) error {
+	e := t.source
+	stream := isStream(e.medium)
+	b := make([]byte, maxChunk)
+	for {
+		c, err := t.open(ctx, e)
+		if c == nil {
+			return err
+		}
+		e.setConn(c)
+		stop := closeOnDone(ctx, c)
+		for {
+			var n int
+			n, err = c.Read(b)
+			if n > 0 {
+				e.count(n)
+				for _, tg := range t.targets {
+					t.send(ctx, tg, b[:n], stream)
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		e.setConn(nil)
+		if !stop() {
+			c.Close()
+		}
+		if err == io.EOF && stream {
+			t.logf("%s %v: end of input", e.role, e)
+			return nil
+		}
+		if again, err := t.broken(ctx, e, err); !again {
+			return err
+		}
+	}
+}
+
+// send queues a copy of b for the target e. It waits for room in the
+// queue if wait is true and drops b otherwise.
+func (t *transmitter) send(ctx context.Context, e *endpoint, b []byte, wait bool) {
+	b = append([]byte(nil), b...)
+	if wait {
+		select {
+		case e.ch <- b:
+		case <-ctx.Done():
+		}
+		return
+	}
+	select {
+	case e.ch <- b:
+	default:
+		atomic.AddInt64(&e.dropped, 1)
+	}
+}
+
+func (t *transmitter) runTarget(ctx context.Context, e *endpoint) error {
+	for {
+		c, err := t.open(ctx, e)
+		if c == nil {
+			return err
+		}
+		e.setConn(c)
+		stop := closeOnDone(ctx, c)
+		done := true
+		for b := range e.ch {
+			if _, err = c.Write(b); err != nil {
+				done = false
+				break
+			}
+			e.count(len(b))
+		}
+		e.setConn(nil)
+		if !stop() {
+			c.Close()
+		}
+		if done {
+			return nil
+		}
+		if again, err := t.broken(ctx, e, err); !again {
+			return err
+		}
+	}
+}
+
+// closeOnDone closes c when ctx is done, to interrupt a blocked read or
+// write, until stop is called. stop reports whether c was closed; the
+// caller closes it otherwise, as a connection must not be closed twice
+// concurrently.
+func closeOnDone(ctx context.Context, c io.Closer) (stop func() (closed bool)) {
+	done := make(chan struct{})
+	closed := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+			closed <- true
+		case <-done:
+			closed <- false
+		}
+	}()
+	return func() bool {
+		close(done)
+		return <-closed
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package cmdutil holds the parts the gosrt commands share to connect
+// to SRT URIs.
+package cmdutil
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+// Redact returns uri with the value of its passphrase parameter hidden,
+// for use in messages. The parameters are split like srt.ParseURI does.
+func Redact(uri string) string {
+	i := strings.IndexByte(uri, '?')
+	if i < 0 {
+		return uri
+	}
+	params := strings.Split(uri[i+1:], "&")
+	for j, kv := range params {
+		key := kv
+		if k := strings.IndexByte(kv, '='); k >= 0 {
+			key = kv[:k]
+		}
+		if name, err := url.PathUnescape(key); err == nil && name == "passphrase" {
+			params[j] = key + "=***"
+		}
+	}
+	return uri[:i+1] + strings.Join(params, "&")
+}
+
+// Sleep waits for d and reports whether ctx is still not done.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// An Opener opens the connections of an SRT URI. It dials a caller or a
+// rendezvous peer; a listener accepts the next caller, and keeps
+// listening between the callers until the Opener is closed.
+type Opener struct {
+	URI *srt.URI
+
+	ln net.Listener
+}
+
+// Open dials the URI, or accepts the next caller of a listener.
+func (o *Opener) Open(ctx context.Context) (net.Conn, error) {
+	if o.URI.Mode != srt.ModeListener {
+		return o.URI.Dial(ctx)
+	}
+	if o.ln == nil {
+		ln, err := o.URI.Listen(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o.ln = ln
+	}
+	type result struct {
+		c   net.Conn
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		c, err := o.ln.Accept()
+		ch <- result{c, err}
+	}()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			// listen again at the next attempt
+			o.Close()
+		}
+		return r.c, r.err
+	case <-ctx.Done():
+		o.Close()
+		if r := <-ch; r.c != nil {
+			r.c.Close()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the listener, if any.
+func (o *Opener) Close() error {
+	if o.ln == nil {
+		return nil
+	}
+	err := o.ln.Close()
+	o.ln = nil
+	return err
+}
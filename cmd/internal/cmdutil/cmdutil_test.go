@@ -0,0 +1,23 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package cmdutil
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	for _, tt := range []struct {
+		uri, want string
+	}{
+		{"srt://127.0.0.1:9000", "srt://127.0.0.1:9000"},
+		{"srt://127.0.0.1:9000?passphrase=verylongpassword&latency=200", "srt://127.0.0.1:9000?passphrase=***&latency=200"},
+		{"srt://127.0.0.1:9000?latency=200&passphrase=verylongpassword", "srt://127.0.0.1:9000?latency=200&passphrase=***"},
+		{"srt://127.0.0.1:9000?pass%70hrase=verylongpassword", "srt://127.0.0.1:9000?pass%70hrase=***"},
+		{"srt://127.0.0.1:9000?streamid=#!::r=passphrase=x,m=publish", "srt://127.0.0.1:9000?streamid=#!::r=passphrase=x,m=publish"},
+		{"srt://127.0.0.1:9000?passphrase", "srt://127.0.0.1:9000?passphrase=***"},
+	} {
+		if got := Redact(tt.uri); got != tt.want {
+			t.Errorf("Redact(%q) = %q; want %q", tt.uri, got, tt.want)
+		}
+	}
+}
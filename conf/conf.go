@@ -10,6 +10,7 @@
 package conf
 
 import (
+	"fmt"
 	"os"
 	"runtime"
 	"strconv"
@@ -19,21 +20,29 @@ import (
 	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
-// Conf represents a system's network configuration.
+// Conf represents a system's network configuration. It is read from
+// the environment once, and the logging and statistics settings can be
+// changed at any time with its Set methods or LoadFile.
 type Conf struct {
 	goos string // the runtime.GOOS, to ease testing
 
+	// guarded by confMu
 	verbose     bool
 	logLevel    int
 	logFAs      []int
 	logFile     string
 	logInternal bool
 	fullStats   bool
+	pollShards  int
 }
 
 var (
 	confOnce sync.Once // guards init of confVal via initConfVal
 	confVal  = &Conf{goos: runtime.GOOS}
+
+	confMu   sync.RWMutex // guards the settings of a Conf
+	changeMu sync.Mutex   // serializes the changes and their effects
+	watchers []func(*Conf)
 )
 
 var logLevels = map[string]int{
@@ -69,33 +78,15 @@ func initConfVal() {
 	if env := os.Getenv("SRT_LOGLEVEL"); env != "" {
 		if val, err := strconv.Atoi(env); err == nil {
 			confVal.logLevel = val
-		} else if val, ok := logLevels[strings.ToLower(env)]; ok {
+		} else if val, err := ParseLogLevel(env); err == nil {
 			confVal.logLevel = val
 		}
 	}
 
 	confVal.logFAs = []int{}
 	if fa := os.Getenv("SRT_LOGFA"); fa != "" {
-		if fa == "all" {
-			confVal.logFAs = append(confVal.logFAs, srtapi.LogFABstats)
-			confVal.logFAs = append(confVal.logFAs, srtapi.LogFAControl)
-			confVal.logFAs = append(confVal.logFAs, srtapi.LogFAData)
-			confVal.logFAs = append(confVal.logFAs, srtapi.LogFATsbpd)
-			confVal.logFAs = append(confVal.logFAs, srtapi.LogFARexmit)
-		} else {
-			fa = strings.ToLower(fa)
-			xfas := strings.Split(fa, ",")
-			for _, fa := range xfas {
-				nfa := 0
-				for ; nfa < len(logNames); nfa++ {
-					if fa == logNames[nfa] {
-						if nfa > 0 {
-							confVal.logFAs = append(confVal.logFAs, nfa)
-						}
-					}
-				}
-			}
-		}
+		// unknown areas are ignored
+		confVal.logFAs, _ = ParseLogFAs(fa)
 	}
 
 	confVal.logFile = os.Getenv("SRT_LOGFILE")
@@ -113,29 +104,213 @@ func initConfVal() {
 			confVal.fullStats = val
 		}
 	}
+
+	confVal.pollShards = 1
+	if env := os.Getenv("SRT_POLLSHARDS"); env != "" {
+		if val, err := strconv.Atoi(env); err == nil && val > 0 {
+			confVal.pollShards = val
+		}
+	}
+}
+
+// ParseLogLevel parses a log level given by its number or name, such
+// as "7" or "debug".
+func ParseLogLevel(s string) (int, error) {
+	if val, err := strconv.Atoi(s); err == nil {
+		if val < srtapi.LogEmerg || val > srtapi.LogDebug {
+			return 0, fmt.Errorf("log level %d out of range", val)
+		}
+		return val, nil
+	}
+	if val, ok := logLevels[strings.ToLower(s)]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// ParseLogFAs parses a comma separated list of functional areas, such
+// as "control,data", or "all" for all of them. As in SRT_LOGFA, the
+// general area is skipped; AddLogFA enables it. The known areas are
+// returned with the error about an unknown one.
+func ParseLogFAs(s string) ([]int, error) {
+	s = strings.ToLower(s)
+	if s == "all" {
+		return []int{srtapi.LogFABstats, srtapi.LogFAControl, srtapi.LogFAData, srtapi.LogFATsbpd, srtapi.LogFARexmit}, nil
+	}
+	fas := []int{}
+	var err error
+	for _, fa := range strings.Split(s, ",") {
+		fa = strings.TrimSpace(fa)
+		nfa := 0
+		for ; nfa < len(logNames); nfa++ {
+			if fa == logNames[nfa] {
+				break
+			}
+		}
+		switch {
+		case nfa == len(logNames):
+			if fa != "" && err == nil {
+				err = fmt.Errorf("unknown functional area %q", fa)
+			}
+		case nfa > 0:
+			fas = append(fas, nfa)
+		}
+	}
+	return fas, err
+}
+
+func checkFA(fa int) error {
+	if fa < srtapi.LogFAGeneral || fa >= len(logNames) {
+		return fmt.Errorf("functional area %d out of range", fa)
+	}
+	return nil
+}
+
+// Watch registers f to be called after every change of c, once the
+// change took effect in the library. f must not change c.
+func (c *Conf) Watch(f func(*Conf)) {
+	changeMu.Lock()
+	watchers = append(watchers, f)
+	changeMu.Unlock()
+}
+
+// change updates the fields of c with set, applies the change to the
+// library with apply, which may be nil, and calls the watchers.
+func (c *Conf) change(set func(), apply func()) {
+	changeMu.Lock()
+	defer changeMu.Unlock()
+	confMu.Lock()
+	set()
+	confMu.Unlock()
+	if apply != nil {
+		apply()
+	}
+	for _, f := range watchers {
+		f(c)
+	}
 }
 
 // Verbose reports whether verbose log is enabled
 func (c *Conf) Verbose() bool {
+	confMu.RLock()
+	defer confMu.RUnlock()
 	return c.verbose
 }
 
+// LogLevel returns the log level of the library.
 func (c *Conf) LogLevel() int {
+	confMu.RLock()
+	defer confMu.RUnlock()
 	return c.logLevel
 }
 
+// SetLogLevel changes the log level of the library, from
+// srtapi.LogEmerg to srtapi.LogDebug.
+func (c *Conf) SetLogLevel(level int) error {
+	if level < srtapi.LogEmerg || level > srtapi.LogDebug {
+		return fmt.Errorf("log level %d out of range", level)
+	}
+	c.change(func() { c.logLevel = level }, func() { srtapi.SetLogLevel(level) })
+	return nil
+}
+
+// LogFAs returns the functional areas enabled in the library.
 func (c *Conf) LogFAs() []int {
-	return c.logFAs
+	confMu.RLock()
+	defer confMu.RUnlock()
+	return append([]int(nil), c.logFAs...)
+}
+
+// AddLogFA enables the functional area fa in the library.
+func (c *Conf) AddLogFA(fa int) error {
+	if err := checkFA(fa); err != nil {
+		return err
+	}
+	c.change(func() {
+		for _, x := range c.logFAs {
+			if x == fa {
+				return
+			}
+		}
+		c.logFAs = append(c.logFAs, fa)
+	}, func() { srtapi.AddLogFA(fa) })
+	return nil
+}
+
+// DelLogFA disables the functional area fa in the library.
+func (c *Conf) DelLogFA(fa int) error {
+	if err := checkFA(fa); err != nil {
+		return err
+	}
+	c.change(func() {
+		fas := c.logFAs[:0:0]
+		for _, x := range c.logFAs {
+			if x != fa {
+				fas = append(fas, x)
+			}
+		}
+		c.logFAs = fas
+	}, func() { srtapi.DelLogFA(fa) })
+	return nil
 }
 
+// ResetLogFAs enables exactly the functional areas fas in the library.
+func (c *Conf) ResetLogFAs(fas []int) error {
+	for _, fa := range fas {
+		if err := checkFA(fa); err != nil {
+			return err
+		}
+	}
+	fas = append([]int{}, fas...)
+	c.change(func() { c.logFAs = fas }, func() { srtapi.ResetLogFA(fas) })
+	return nil
+}
+
+// LogFile returns the file the library logs to, if it does not log
+// internally. Empty means the standard error.
 func (c *Conf) LogFile() string {
+	confMu.RLock()
+	defer confMu.RUnlock()
 	return c.logFile
 }
 
+// SetLogFile makes the library log to the file name, appending to it,
+// or to the standard error if name is empty. The file is only used if
+// the library does not log internally.
+func (c *Conf) SetLogFile(name string) {
+	c.change(func() { c.logFile = name }, nil)
+}
+
+// LogInternal reports whether the library logs through the handler of
+// package logging.
 func (c *Conf) LogInternal() bool {
+	confMu.RLock()
+	defer confMu.RUnlock()
 	return c.logInternal
 }
 
+// SetLogInternal changes whether the library logs through the handler
+// of package logging.
+func (c *Conf) SetLogInternal(internal bool) {
+	c.change(func() { c.logInternal = internal }, nil)
+}
+
+// FullStats reports whether the statistics are cumulated over the
+// lifetime of the connections rather than reset at every report.
 func (c *Conf) FullStats() bool {
+	confMu.RLock()
+	defer confMu.RUnlock()
 	return c.fullStats
 }
+
+// SetFullStats changes the statistics mode reported by FullStats.
+func (c *Conf) SetFullStats(full bool) {
+	c.change(func() { c.fullStats = full }, nil)
+}
+
+// PollShards returns the number of epoll shards of the poller
+func (c *Conf) PollShards() int {
+	confMu.RLock()
+	defer confMu.RUnlock()
+	return c.pollShards
+}
@@ -10,7 +10,10 @@
 package conf
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/xmedia-systems/gosrt/srtapi"
@@ -24,6 +27,7 @@ func TestConf(t *testing.T) {
 		logFile     string
 		logInternal string
 		fullStats   string
+		pollShards  string
 		want        Conf
 	}{
 		{
@@ -33,6 +37,7 @@ func TestConf(t *testing.T) {
 			logFile:     "",
 			logInternal: "false",
 			fullStats:   "false",
+			pollShards:  "",
 			want: Conf{
 				verbose:     true,
 				logLevel:    srtapi.LogDebug,
@@ -40,6 +45,7 @@ func TestConf(t *testing.T) {
 				logFile:     "",
 				logInternal: false,
 				fullStats:   false,
+				pollShards:  1,
 			},
 		},
 		{
@@ -49,6 +55,7 @@ func TestConf(t *testing.T) {
 			logFile:     "/path/gosrt.log",
 			logInternal: "true",
 			fullStats:   "true",
+			pollShards:  "4",
 			want: Conf{
 				verbose:     false,
 				logLevel:    srtapi.LogFatal,
@@ -56,6 +63,7 @@ func TestConf(t *testing.T) {
 				logFile:     "/path/gosrt.log",
 				logInternal: true,
 				fullStats:   true,
+				pollShards:  4,
 			},
 		},
 	}
@@ -67,6 +75,7 @@ func TestConf(t *testing.T) {
 		os.Setenv("SRT_LOGFILE", tt.logFile)
 		os.Setenv("SRT_LOGINTERNAL", tt.logInternal)
 		os.Setenv("SRT_FULLSTATS", tt.fullStats)
+		os.Setenv("SRT_POLLSHARDS", tt.pollShards)
 		initConfVal()
 		if confVal.Verbose() != tt.want.verbose {
 			t.Errorf("verbose = %v; want %v", confVal.Verbose(), tt.want.verbose)
@@ -95,9 +104,111 @@ func TestConf(t *testing.T) {
 		if confVal.FullStats() != tt.want.fullStats {
 			t.Errorf("fullStats = %v; want %v", confVal.FullStats(), tt.want.fullStats)
 		}
+		if confVal.PollShards() != tt.want.pollShards {
+			t.Errorf("pollShards = %v; want %v", confVal.PollShards(), tt.want.pollShards)
+		}
 	}
 }
 
 func TestSystemConf(t *testing.T) {
 	SystemConf()
 }
+
+func TestParseLog(t *testing.T) {
+	for s, want := range map[string]int{"7": srtapi.LogDebug, "Warning": srtapi.LogWarning, "crit": srtapi.LogFatal} {
+		if got, err := ParseLogLevel(s); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"8", "-1", "verbose", ""} {
+		if _, err := ParseLogLevel(s); err == nil {
+			t.Errorf("ParseLogLevel(%q): got nil error", s)
+		}
+	}
+	fas, err := ParseLogFAs("control, data,general")
+	if err != nil || fmt.Sprint(fas) != fmt.Sprint([]int{srtapi.LogFAControl, srtapi.LogFAData}) {
+		t.Errorf("got %v, %v; want control and data", fas, err)
+	}
+	fas, err = ParseLogFAs("tsbpd,bogus")
+	if err == nil || fmt.Sprint(fas) != fmt.Sprint([]int{srtapi.LogFATsbpd}) {
+		t.Errorf("got %v, %v; want tsbpd and an error", fas, err)
+	}
+}
+
+func TestSetConf(t *testing.T) {
+	c := SystemConf()
+	var changes int
+	c.Watch(func(*Conf) { changes++ })
+
+	if err := c.SetLogLevel(srtapi.LogDebug); err != nil || c.LogLevel() != srtapi.LogDebug {
+		t.Errorf("got %d, %v; want debug", c.LogLevel(), err)
+	}
+	if err := c.SetLogLevel(8); err == nil || c.LogLevel() != srtapi.LogDebug {
+		t.Errorf("got %d, %v; want an error and debug", c.LogLevel(), err)
+	}
+	c.ResetLogFAs([]int{srtapi.LogFAControl})
+	c.AddLogFA(srtapi.LogFAData)
+	c.AddLogFA(srtapi.LogFAData)
+	c.AddLogFA(srtapi.LogFAGeneral)
+	c.DelLogFA(srtapi.LogFAControl)
+	if got := fmt.Sprint(c.LogFAs()); got != fmt.Sprint([]int{srtapi.LogFAData, srtapi.LogFAGeneral}) {
+		t.Errorf("got %s; want data and general", got)
+	}
+	if err := c.AddLogFA(6); err == nil {
+		t.Error("got nil error for an unknown area")
+	}
+	c.LogFAs()[0] = srtapi.LogFARexmit
+	if c.LogFAs()[0] != srtapi.LogFAData {
+		t.Error("LogFAs returned the areas of c")
+	}
+	c.SetLogFile("/tmp/srt.log")
+	c.SetLogInternal(true)
+	c.SetFullStats(true)
+	if c.LogFile() != "/tmp/srt.log" || !c.LogInternal() || !c.FullStats() {
+		t.Errorf("got %q, %v, %v", c.LogFile(), c.LogInternal(), c.FullStats())
+	}
+	if changes != 9 {
+		t.Errorf("got %d changes; want 9", changes)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	c := SystemConf()
+	c.SetLogLevel(srtapi.LogError)
+	c.ResetLogFAs(nil)
+	c.SetFullStats(false)
+	c.SetLogFile("")
+
+	for _, in := range []string{
+		"loglevel debug",
+		"loglevel = verbose",
+		"logfa = control,bogus",
+		"fullstats = maybe",
+		"pollshards = 4",
+	} {
+		if err := c.Load(strings.NewReader("logfile = /tmp/srt.log\n" + in)); err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+			t.Errorf("%q: got %v; want an error at line 2", in, err)
+		}
+	}
+	if c.LogFile() != "" {
+		t.Errorf("got log file %q after invalid settings", c.LogFile())
+	}
+
+	f, err := ioutil.TempFile("", "gosrt-conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# debug the connections\n\nLogLevel = debug\nlogfa = control, data\nlogfile = /tmp/srt.log\nloginternal = false\nfullstats = true\n")
+	f.Close()
+	if err := c.LoadFile(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if c.LogLevel() != srtapi.LogDebug || fmt.Sprint(c.LogFAs()) != fmt.Sprint([]int{srtapi.LogFAControl, srtapi.LogFAData}) ||
+		c.LogFile() != "/tmp/srt.log" || c.LogInternal() || !c.FullStats() {
+		t.Errorf("got %+v", c)
+	}
+	if err := c.LoadFile(f.Name() + ".missing"); err == nil {
+		t.Error("got nil error for a missing file")
+	}
+}
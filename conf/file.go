@@ -0,0 +1,105 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package conf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Load changes the settings of c to the ones read from r, which has a
+// "key = value" line per setting. Empty lines and lines starting with
+// '#' are ignored. The keys are the names of the environment variables
+// without the SRT_ prefix, in lower case:
+//
+//	# debug the connections
+//	loglevel = debug
+//	logfa = control,data
+//	logfile = /var/log/srt.log
+//	loginternal = false
+//	fullstats = true
+//
+// logfa sets exactly the functional areas listed, and an empty logfile
+// the standard error. The settings not in r are left alone. If any line
+// is invalid, no setting is changed.
+func (c *Conf) Load(r io.Reader) error {
+	var changes []func()
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		change, err := c.parseSetting(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		changes = append(changes, change)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		change()
+	}
+	return nil
+}
+
+// LoadFile changes the settings of c to the ones of the file name; see
+// Load.
+func (c *Conf) LoadFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.Load(f); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// parseSetting parses a "key = value" line and returns the change of c
+// it makes.
+func (c *Conf) parseSetting(line string) (func(), error) {
+	i := strings.Index(line, "=")
+	if i < 0 {
+		return nil, errors.New(`missing "="`)
+	}
+	key := strings.ToLower(strings.TrimSpace(line[:i]))
+	value := strings.TrimSpace(line[i+1:])
+	switch key {
+	case "loglevel":
+		level, err := ParseLogLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		return func() { c.SetLogLevel(level) }, nil
+	case "logfa":
+		fas, err := ParseLogFAs(value)
+		if err != nil {
+			return nil, err
+		}
+		return func() { c.ResetLogFAs(fas) }, nil
+	case "logfile":
+		return func() { c.SetLogFile(value) }, nil
+	case "loginternal", "fullstats":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid boolean %q", key, value)
+		}
+		if key == "loginternal" {
+			return func() { c.SetLogInternal(b) }, nil
+		}
+		return func() { c.SetFullStats(b) }, nil
+	}
+	return nil, fmt.Errorf("unknown setting %q", key)
+}
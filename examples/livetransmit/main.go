@@ -12,11 +12,10 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/xmedia-systems/gosrt/conf"
 	"github.com/xmedia-systems/gosrt/srt"
-	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
 func main() {
@@ -42,41 +41,30 @@ func main() {
 		println(buf)
 	})
 
-	defer srt.Shutdown()
+	defer srt.Shutdown(context.Background())
 	ctx := srt.WithOptions(context.Background(), srt.Options("payloadsize", strconv.Itoa(chunksize)))
-	ctx = srt.WithListenCallback(ctx, func(ns int, hsversion int, peeraddr syscall.Sockaddr, streamID string) int {
+	ctx = srt.WithAcceptPolicy(ctx, func(req *srt.AcceptRequest) (*srt.Config, error) {
 		passwd := map[string]string{
 			"admin": "thelocalmanager",
 			"user":  "verylongpassword",
 		}
-		username := ""
-		if err != nil {
-			log.Fatal(err)
+		// By default the whole streamid is username
+		username := req.StreamID.Plain
+		if username == "" {
+			username = req.StreamID.User
 		}
-		if strings.HasPrefix(streamID, "#!::") {
-			items := strings.Split(streamID[4:], ",")
-			for i := range items {
-				kv := strings.Split(items[i], "=")
-				if len(kv) == 2 && kv[0] == "u" {
-					username = kv[1]
-				}
-			}
-			if username == "" {
-				fmt.Println("USER NOT FOUND")
-				return -1
-			}
-		} else {
-			// By default the whole streamid is username
-			username = streamID
+		if username == "" {
+			fmt.Println("USER NOT FOUND")
+			return nil, srt.RejectUnauthorized
 		}
 		fmt.Printf("username is %s\n", username)
 
 		expPw, ok := passwd[username]
 		if ok {
 			fmt.Printf("setting password %s\n", expPw)
-			srtapi.SetsockflagString(ns, int(srtapi.OptionPassphrase), expPw)
+			return &srt.Config{Passphrase: expPw}, nil
 		}
-		return 0
+		return nil, nil
 	})
 	fmt.Println("listen")
 	l, err := srt.ListenContext(ctx, "srt", ":"+sport)
@@ -126,7 +114,11 @@ func main() {
 }
 
 func printSrtStats(conn net.Conn) {
-	mon := conn.(*srt.SRTConn).Stats()
+	mon, err := conn.(*srt.SRTConn).Stats(!conf.SystemConf().FullStats())
+	if err != nil {
+		log.Println(err)
+		return
+	}
 	s, _ := json.MarshalIndent(mon, "", "\t")
 	fmt.Println(string(s))
 }
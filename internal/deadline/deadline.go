@@ -0,0 +1,32 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package deadline interrupts blocking I/O when a context is done.
+package deadline
+
+import (
+	"context"
+	"time"
+)
+
+// Interrupt calls set with a deadline in the past when ctx is done, so
+// that the pending and future I/O it guards fails, until stop is
+// called. set is a deadline setter such as the SetDeadline or
+// SetReadDeadline method of a net.Conn. stop returns once set is not
+// called any more, so that the connection can be closed.
+func Interrupt(ctx context.Context, set func(t time.Time) error) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			set(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
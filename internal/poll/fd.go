@@ -48,3 +48,26 @@ func (e *TimeoutError) Timeout() bool { return true }
 
 // Temporary return if it is temprary error
 func (e *TimeoutError) Temporary() bool { return true }
+
+// PollerError is returned when a wait for I/O failed because the
+// poller failed. The poller rebuilds itself, so the operation can be
+// retried.
+type PollerError struct {
+	Err error // the failure of the poller
+}
+
+func (e *PollerError) Error() string {
+	if e.Err == nil {
+		return "poller failed"
+	}
+	return "poller failed: " + e.Err.Error()
+}
+
+// Unwrap returns the failure of the poller.
+func (e *PollerError) Unwrap() error { return e.Err }
+
+// Timeout return if it is timeout error
+func (e *PollerError) Timeout() bool { return false }
+
+// Temporary return if it is temprary error
+func (e *PollerError) Temporary() bool { return true }
@@ -10,7 +10,6 @@ package poll
 
 import (
 	"errors"
-	"sync"
 	"time"
 
 	"github.com/xmedia-systems/gosrt/internal/poll/runtime"
@@ -20,10 +19,10 @@ type pollDesc struct {
 	runtimeCtx runtime.PollDesc
 }
 
-var serverInit sync.Once
-
 func (pd *pollDesc) init(fd *FD) error {
-	serverInit.Do(runtime.PollServerInit)
+	if err := runtime.PollServerInit(0); err != nil {
+		return err
+	}
 	ctx, err := runtime.PollOpen(fd.Sysfd)
 	if err != nil {
 		if ctx != nil {
@@ -73,6 +72,9 @@ func (pd *pollDesc) wait(mode int) error {
 		return errors.New("waiting for unsupported file type")
 	}
 	res := pd.runtimeCtx.Wait(mode)
+	if res == 3 {
+		return &PollerError{Err: pd.runtimeCtx.PollerErr()}
+	}
 	return convertErr(res)
 }
 
@@ -84,6 +86,16 @@ func (pd *pollDesc) waitWrite() error {
 	return pd.wait('w')
 }
 
+// hasReadDeadline reports whether a read deadline is set.
+func (pd *pollDesc) hasReadDeadline() bool {
+	return pd.runtimeCtx != nil && pd.runtimeCtx.HasDeadline('r')
+}
+
+// hasWriteDeadline reports whether a write deadline is set.
+func (pd *pollDesc) hasWriteDeadline() bool {
+	return pd.runtimeCtx != nil && pd.runtimeCtx.HasDeadline('w')
+}
+
 func (pd *pollDesc) pollable() bool {
 	return pd.runtimeCtx != nil
 }
@@ -117,7 +129,15 @@ func (fd *FD) SetWriteDeadline(t time.Time) error {
 }
 
 func setDeadlineImpl(fd *FD, t time.Time, mode int) error {
-	d := time.Until(t)
+	// A zero d is no deadline; a zero t must not be taken for a
+	// deadline long ago.
+	var d time.Duration
+	if !t.IsZero() {
+		d = time.Until(t)
+		if d == 0 {
+			d = -1 // don't confuse deadline right now with no deadline
+		}
+	}
 	if fd.pd.runtimeCtx == nil {
 		return ErrNoDeadline
 	}
@@ -138,6 +138,62 @@ func (fd *FD) Write(p []byte) (int, error) {
 	}
 }
 
+// ReadMsg wraps the srt_recvmsg2 call. It receives a single message
+// and fills mctrl with the message control information.
+func (fd *FD) ReadMsg(p []byte, mctrl *srtapi.MsgCtrl) (int, error) {
+	if err := fd.readLock(); err != nil {
+		return 0, err
+	}
+	defer fd.readUnlock()
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := fd.pd.prepareRead(); err != nil {
+		return 0, err
+	}
+	if len(p) > maxRW {
+		p = p[:maxRW]
+	}
+	for {
+		n, err := srtapi.Recvmsg2(fd.Sysfd, p, mctrl)
+		if err != nil {
+			n = 0
+			if err == srtapi.EASYNCRCV && fd.pd.pollable() {
+				if err = fd.pd.waitRead(); err == nil {
+					continue
+				}
+			}
+		}
+		err = fd.eofError(n, err)
+		return n, err
+	}
+}
+
+// WriteMsg wraps the srt_sendmsg2 call. The whole of p is sent as a
+// single message; mctrl carries the message control information and
+// is updated with the values assigned by the library.
+func (fd *FD) WriteMsg(p []byte, mctrl *srtapi.MsgCtrl) (int, error) {
+	if err := fd.writeLock(); err != nil {
+		return 0, err
+	}
+	defer fd.writeUnlock()
+	if err := fd.pd.prepareWrite(); err != nil {
+		return 0, err
+	}
+	for {
+		n, err := srtapi.Sendmsg2(fd.Sysfd, p, mctrl)
+		if err == srtapi.EASYNCSND && fd.pd.pollable() {
+			if err = fd.pd.waitWrite(); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+}
+
 // Accept wraps the accept network call.
 func (fd *FD) Accept() (int, syscall.Sockaddr, string, error) {
 	if err := fd.readLock(); err != nil {
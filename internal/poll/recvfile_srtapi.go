@@ -0,0 +1,65 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package poll
+
+import (
+	"io"
+	"os"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// RecvFile wraps the srt_recvfile call. It receives up to remain bytes
+// into f from its current offset, and leaves the offset of f past the
+// data received.
+//
+// srt_recvfile cannot be interrupted once data arrives, so RecvFile
+// performs no work if a read deadline is set; handled is false then.
+// The poller is waited on while the library reports no data available.
+// A close of the peer ends the data with a nil error.
+func RecvFile(srcFD *FD, f *os.File, remain int64) (received int64, handled bool, err error) {
+	if err := srcFD.readLock(); err != nil {
+		return 0, true, err
+	}
+	defer srcFD.readUnlock()
+	if err := srcFD.pd.prepareRead(); err != nil {
+		return 0, true, err
+	}
+	if srcFD.pd.hasReadDeadline() {
+		return 0, false, nil
+	}
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false, nil
+	}
+	count := int(^uint(0) >> 1)
+	if int64(count) > remain {
+		count = int(remain)
+	}
+	src := srcFD.Sysfd
+	off := start
+	for {
+		_, err = srtapi.Recvfile(src, f, &off, count)
+		if err == srtapi.EASYNCRCV && off == start && srcFD.pd.pollable() {
+			if err = srcFD.pd.waitRead(); err == nil {
+				continue
+			}
+		}
+		break
+	}
+	if err == srtapi.ECONNLOST {
+		// srt_recvfile fails at the end of the data also if the peer
+		// closed the connection normally; srt_recv reports the end of
+		// the stream then.
+		var b [1]byte
+		if n, rerr := srtapi.Read(src, b[:]); n == 0 && rerr == nil {
+			err = nil
+		}
+	}
+	received = off - start
+	if _, serr := f.Seek(off, io.SeekStart); serr != nil && err == nil {
+		err = serr
+	}
+	return received, true, err
+}
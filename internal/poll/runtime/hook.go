@@ -0,0 +1,11 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package runtime
+
+import (
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// EpollUwaitFunc is used to hook the srt_epoll_uwait call.
+var EpollUwaitFunc = srtapi.EpollUwait
@@ -9,8 +9,12 @@
 package runtime
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/xmedia-systems/gosrt/conf"
 )
 
 // PollDesc - Network poller descriptor.
@@ -19,34 +23,148 @@ type PollDesc interface {
 	Wait(mode int) int
 	Reset(mode int) int
 	SetDeadline(d time.Duration, mode int)
+	HasDeadline(mode int) bool
 	Unblock()
+	PollerErr() error
 }
 
 type pollDesc struct {
 	lock    sync.Mutex // protects the following fields
 	fd      int
 	closing bool
-	seq     int // protects from stale timers and ready notifications
+	seq     int   // protects from stale timers and ready notifications
+	perr    error // last poller failure
 	rrdy    bool
+	rwait   int  // number of blocked readers
+	rfail   bool // the poller failed while readers were blocked
 	rl      sync.Mutex
 	rc      *sync.Cond
 	rt      *time.Timer   // read deadline timer
 	rd      time.Duration // read deadline
 	wrdy    bool
+	wwait   int  // number of blocked writers
+	wfail   bool // the poller failed while writers were blocked
 	wl      sync.Mutex
 	wc      *sync.Cond
 	wt      *time.Timer   // write deadline timer
 	wd      time.Duration // write deadline
 }
 
-// PollServerInit initialize the poller
-func PollServerInit() {
-	netpollinit()
+var (
+	serverLock    sync.Mutex // protects serverRunning
+	serverRunning bool
+)
+
+// errPollerStopped is returned for a descriptor opened while the poller
+// is not running.
+var errPollerStopped = errors.New("poller not running")
+
+// PollServerInit starts the poller with the given number of shards if
+// it is not running yet. Zero shards means the configured number, or
+// any number if the poller is running; another number fails then.
+func PollServerInit(shards int) error {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	if serverRunning {
+		if n := netpollshards(); shards != 0 && shards != n {
+			return fmt.Errorf("poller running with %d shards, not %d", n, shards)
+		}
+		return nil
+	}
+	if shards == 0 {
+		shards = conf.SystemConf().PollShards()
+	}
+	if err := netpollinitShards(shards); err != nil {
+		return err
+	}
+	serverRunning = true
+	return nil
+}
+
+// PollServerRunning reports whether the poller is running.
+func PollServerRunning() bool {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	return serverRunning
 }
 
-// PollServerShutdown shutdown the pollder
+// PollServerDrain waits until all descriptors are closed or done is
+// closed. It reports whether all descriptors were closed.
+func PollServerDrain(done <-chan struct{}) bool {
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+	for netpollcount() > 0 {
+		select {
+		case <-done:
+			return netpollcount() == 0
+		case <-t.C:
+		}
+	}
+	return true
+}
+
+// PollServerShutdown shutdown the poller. It closes the remaining
+// descriptors, wakes up their waiters and cleans up the srt library.
+// The poller can be started again with PollServerInit.
 func PollServerShutdown() {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	if !serverRunning {
+		return
+	}
 	netpollshutdown()
+	serverRunning = false
+}
+
+// PollServerHealth describes the health of the poller.
+type PollServerHealth struct {
+	Running       bool      // the poller is running
+	Shards        int       // number of epoll shards
+	Broken        int       // number of shards with a failed epoll
+	Failures      uint64    // number of failed epoll waits or rebuilds
+	Rebuilds      uint64    // number of rebuilt epolls
+	LastError     error     // last failure
+	LastErrorTime time.Time // time of the last failure
+}
+
+var health struct {
+	sync.Mutex
+	failures      uint64
+	rebuilds      uint64
+	lastError     error
+	lastErrorTime time.Time
+}
+
+func netpollfailed(err error) {
+	health.Lock()
+	defer health.Unlock()
+	health.failures++
+	health.lastError = err
+	health.lastErrorTime = time.Now()
+}
+
+func netpollrebuilt() {
+	health.Lock()
+	defer health.Unlock()
+	health.rebuilds++
+}
+
+// PollServerStatus returns the health of the poller.
+func PollServerStatus() PollServerHealth {
+	serverLock.Lock()
+	h := PollServerHealth{Running: serverRunning}
+	if serverRunning {
+		h.Shards = netpollshards()
+		h.Broken = netpollbroken()
+	}
+	serverLock.Unlock()
+	health.Lock()
+	h.Failures = health.failures
+	h.Rebuilds = health.rebuilds
+	h.LastError = health.lastError
+	h.LastErrorTime = health.lastErrorTime
+	health.Unlock()
+	return h
 }
 
 // PollServerDescriptor returns the descriptor being used
@@ -78,8 +196,7 @@ func (pd *pollDesc) Wait(mode int) int {
 	if err != 0 {
 		return err
 	}
-	netpollblock(pd, mode)
-	return 0
+	return netpollblock(pd, mode)
 }
 
 func (pd *pollDesc) Reset(mode int) int {
@@ -142,6 +259,17 @@ func (pd *pollDesc) SetDeadline(d time.Duration, mode int) {
 	}
 }
 
+// HasDeadline reports whether a deadline is set for mode, pending or
+// expired.
+func (pd *pollDesc) HasDeadline(mode int) bool {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	if mode == 'r' {
+		return pd.rd != 0
+	}
+	return pd.wd != 0
+}
+
 func (pd *pollDesc) Unblock() {
 	pd.lock.Lock()
 	defer pd.lock.Unlock()
@@ -162,6 +290,13 @@ func (pd *pollDesc) Unblock() {
 	}
 }
 
+// PollerErr returns the poller failure that made a wait fail.
+func (pd *pollDesc) PollerErr() error {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	return pd.perr
+}
+
 func netpollready(pd *pollDesc, mode int) {
 	if mode == 'r' || mode == 'r'+'w' {
 		netpollunblock(pd, 'r', true)
@@ -172,6 +307,9 @@ func netpollready(pd *pollDesc, mode int) {
 }
 
 func netpollcheckerr(pd *pollDesc, mode int) int {
+	// the deadlines may be set concurrently
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
 	if pd.closing {
 		return 1 // errClosing
 	}
@@ -181,12 +319,14 @@ func netpollcheckerr(pd *pollDesc, mode int) int {
 	return 0
 }
 
-func netpollblock(pd *pollDesc, mode int) {
+// netpollblock blocks until pd is ready for mode. It returns 3
+// (errPoller) if the poller failed in the meantime.
+func netpollblock(pd *pollDesc, mode int) int {
 	c := pd.rc
-	rdy := &pd.rrdy
+	rdy, waiting, failed := &pd.rrdy, &pd.rwait, &pd.rfail
 	if mode == 'w' {
 		c = pd.wc
-		rdy = &pd.wrdy
+		rdy, waiting, failed = &pd.wrdy, &pd.wwait, &pd.wfail
 		netpoll_wait_for_write(pd.fd, true)
 		defer netpoll_wait_for_write(pd.fd, false)
 	}
@@ -194,9 +334,41 @@ func netpollblock(pd *pollDesc, mode int) {
 	c.L.Lock()
 	defer c.L.Unlock()
 	if !*rdy {
+		*waiting++
 		c.Wait()
+		*waiting--
 	}
 	*rdy = false
+	if *failed {
+		if *waiting == 0 {
+			*failed = false
+		}
+		return 3 // errPoller
+	}
+	return 0
+}
+
+// netpollfail fails the blocked waits on pd because of the poller
+// failure err.
+func netpollfail(pd *pollDesc, err error) {
+	pd.lock.Lock()
+	pd.perr = err
+	pd.lock.Unlock()
+	for _, c := range []struct {
+		c       *sync.Cond
+		waiting *int
+		failed  *bool
+	}{
+		{pd.rc, &pd.rwait, &pd.rfail},
+		{pd.wc, &pd.wwait, &pd.wfail},
+	} {
+		c.c.L.Lock()
+		if *c.waiting > 0 {
+			*c.failed = true
+			c.c.Broadcast()
+		}
+		c.c.L.Unlock()
+	}
 }
 
 func netpollunblock(pd *pollDesc, mode int, ioready bool) {
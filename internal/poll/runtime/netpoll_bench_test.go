@@ -0,0 +1,170 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package runtime
+
+import (
+	"fmt"
+	goruntime "runtime"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// benchPair is a connected pair of SRT sockets over loopback. Messages
+// are written to w and read from r, which is registered with the
+// poller.
+type benchPair struct {
+	w, r int
+	pd   PollDesc
+}
+
+// newBenchPairs connects n socket pairs. The poller must be running.
+func newBenchPairs(b *testing.B, n int) []benchPair {
+	ln, err := srtapi.Socket()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer srtapi.Close(ln)
+	if err := srtapi.Bind(ln, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		b.Fatal(err)
+	}
+	if err := srtapi.Listen(ln, n); err != nil {
+		b.Fatal(err)
+	}
+	sa, err := srtapi.Getsockname(ln)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pairs := make([]benchPair, 0, n)
+	for i := 0; i < n; i++ {
+		w, err := srtapi.Socket()
+		if err != nil {
+			b.Fatal(err)
+		}
+		// Deliver messages right away instead of after the default
+		// latency of the live mode.
+		if err := srtapi.SetsockoptInt(w, 0, srtapi.OptionLatency, 0); err != nil {
+			b.Fatal(err)
+		}
+		if err := srtapi.Connect(w, sa); err != nil {
+			b.Fatal(err)
+		}
+		r, _, err := srtapi.Accept(ln)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := srtapi.SetNonblock(r, true); err != nil {
+			b.Fatal(err)
+		}
+		pd, err := PollOpen(r)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pairs = append(pairs, benchPair{w: w, r: r, pd: pd})
+	}
+	return pairs
+}
+
+func closeBenchPairs(pairs []benchPair) {
+	for _, p := range pairs {
+		p.pd.Unblock()
+		p.pd.Close()
+		srtapi.Close(p.r)
+		srtapi.Close(p.w)
+	}
+}
+
+// roundTrip writes msg to p.w and waits until it can be read from p.r.
+func (p *benchPair) roundTrip(b *testing.B, msg, buf []byte) {
+	if _, err := srtapi.Write(p.w, msg); err != nil {
+		b.Error(err)
+		return
+	}
+	for {
+		_, err := srtapi.Read(p.r, buf)
+		if err == nil {
+			return
+		}
+		if err != srtapi.EASYNCRCV {
+			b.Error(err)
+			return
+		}
+		p.pd.Wait('r')
+	}
+}
+
+var benchSizes = []int{100, 1000, 10000}
+
+func benchShards() []int {
+	if n := goruntime.GOMAXPROCS(0); n > 1 {
+		return []int{1, n}
+	}
+	return []int{1}
+}
+
+func runPollBenchmark(b *testing.B, f func(b *testing.B, pairs []benchPair)) {
+	for _, n := range benchSizes {
+		for _, shards := range benchShards() {
+			b.Run(fmt.Sprintf("sockets=%d/shards=%d", n, shards), func(b *testing.B) {
+				if testing.Short() && n > 1000 {
+					b.Skip("skipping in short mode")
+				}
+				if err := PollServerInit(shards); err != nil {
+					b.Fatal(err)
+				}
+				defer PollServerShutdown()
+				pairs := newBenchPairs(b, n)
+				defer closeBenchPairs(pairs)
+				b.ResetTimer()
+				f(b, pairs)
+			})
+		}
+	}
+}
+
+// BenchmarkPollWakeup measures the time from a write until the waiting
+// reader of the peer socket is woken up, with all other sockets idle.
+func BenchmarkPollWakeup(b *testing.B) {
+	runPollBenchmark(b, func(b *testing.B, pairs []benchPair) {
+		msg := []byte("wakeup")
+		buf := make([]byte, 1500)
+		for i := 0; i < b.N; i++ {
+			pairs[i%len(pairs)].roundTrip(b, msg, buf)
+		}
+	})
+}
+
+// BenchmarkPollThroughput measures the message rate with many sockets
+// active at once.
+func BenchmarkPollThroughput(b *testing.B) {
+	runPollBenchmark(b, func(b *testing.B, pairs []benchPair) {
+		msg := make([]byte, 1316)
+		b.SetBytes(int64(len(msg)))
+		workers := 4 * goruntime.GOMAXPROCS(0)
+		if workers > len(pairs) {
+			workers = len(pairs)
+		}
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				buf := make([]byte, 1500)
+				// Worker w owns the pairs w, w+workers, ... and does
+				// every workers-th iteration.
+				j := w
+				for i := w; i < b.N; i += workers {
+					pairs[j].roundTrip(b, msg, buf)
+					if j += workers; j >= len(pairs) {
+						j = w
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	})
+}
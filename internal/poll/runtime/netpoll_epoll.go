@@ -11,146 +11,322 @@ package runtime
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/xmedia-systems/gosrt/logging"
 	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
+// poller is a shard of the network poller. Each shard has its own SRT
+// epoll, descriptor table and dispatch goroutine, so that sockets in
+// different shards do not contend with each other.
+type poller struct {
+	pdsLock sync.RWMutex // protects the following fields
+	epfd    int          // epoll descriptor
+	pds     map[int]*pollDesc
+	broken  bool // the epoll failed and is not rebuilt yet
+	done    chan bool
+}
+
 var (
-	epfd     = -1 // epoll descriptor
-	pds      = make(map[int]*pollDesc)
-	pdsLock  = &sync.RWMutex{}
+	pollers  atomic.Value // []*poller of the running poller, empty if stopped
 	intState int32
-	done     = make(chan bool, 1)
 )
 
-func netpollinit() {
-	srtapi.Startup()
+func init() {
+	pollers.Store([]*poller(nil))
+}
+
+// loadPollers returns the shards of the running poller.
+func loadPollers() []*poller {
+	return pollers.Load().([]*poller)
+}
+
+const (
+	// pollTimeout is the time in milliseconds a single wait blocks at
+	// most, so that the poller notices a shutdown.
+	pollTimeout = 100
+
+	// minEvents and maxEvents bound the size of the event buffer.
+	// The buffer grows when more sockets are ready than fit into it.
+	minEvents = 128
+	maxEvents = 1 << 16
+
+	// pollEvents are the events every socket is registered for.
+	// EpollOut is only added while a writer waits, see
+	// netpoll_wait_for_write.
+	pollEvents = srtapi.EpollIn | srtapi.EpollErr | srtapi.EpollUpdate | srtapi.EpollEt
+)
+
+// netpollinitShards starts the poller with n shards.
+func netpollinitShards(n int) error {
+	if n < 1 {
+		n = 1
+	}
+	if err := srtapi.Startup(); err != nil {
+		return err
+	}
 	logging.Init()
-	var err error
-	epfd, err = srtapi.EpollCreate()
-	if err == nil {
-		go run()
-		return
+	ps := make([]*poller, n)
+	for i := range ps {
+		epfd, err := newEpoll()
+		if err != nil {
+			srtapi.Cleanup()
+			return err
+		}
+		ps[i] = &poller{
+			epfd: epfd,
+			pds:  make(map[int]*pollDesc),
+			done: make(chan bool, 1),
+		}
+	}
+	atomic.StoreInt32(&intState, 0)
+	pollers.Store(ps)
+	for _, p := range ps {
+		go p.run()
 	}
-	println("runtime: srt_epoll_create failed with", err.Error())
-	panic("runtime: netpollinit failed")
+	return nil
 }
 
 func netpollshutdown() {
-	atomic.CompareAndSwapInt32(&intState, 0, 1)
-	if epfd >= 0 {
-		<-done
+	if !atomic.CompareAndSwapInt32(&intState, 0, 1) {
+		return
+	}
+	for _, p := range loadPollers() {
+		<-p.done
 	}
+	pollers.Store([]*poller(nil))
+	srtapi.Cleanup()
+}
+
+// netpollcount returns the number of open descriptors.
+func netpollcount() int {
+	n := 0
+	for _, p := range loadPollers() {
+		p.pdsLock.RLock()
+		n += len(p.pds)
+		p.pdsLock.RUnlock()
+	}
+	return n
 }
 
 func netpolldescriptor() int {
-	return epfd
+	ps := loadPollers()
+	if len(ps) == 0 {
+		return -1
+	}
+	ps[0].pdsLock.RLock()
+	defer ps[0].pdsLock.RUnlock()
+	return ps[0].epfd
+}
+
+// netpollshards returns the number of shards of the running poller.
+func netpollshards() int {
+	return len(loadPollers())
+}
+
+// pollerOf returns the shard of the socket fd, or nil if the poller is
+// not running.
+func pollerOf(fd int) *poller {
+	ps := loadPollers()
+	if len(ps) == 0 {
+		return nil
+	}
+	return ps[uint(fd)%uint(len(ps))]
+}
+
+// netpollbroken returns the number of shards whose epoll failed and
+// is not rebuilt yet.
+func netpollbroken() int {
+	n := 0
+	for _, p := range loadPollers() {
+		p.pdsLock.RLock()
+		if p.broken {
+			n++
+		}
+		p.pdsLock.RUnlock()
+	}
+	return n
+}
+
+// newEpoll creates an epoll for a shard.
+func newEpoll() (int, error) {
+	epfd, err := srtapi.EpollCreate()
+	if err != nil {
+		return -1, err
+	}
+	// Allow waiting on an epoll without sockets; the flag stays set
+	// for the lifetime of the epoll.
+	if _, err = srtapi.EpollSet(epfd, srtapi.EpollEnableEmpty); err != nil {
+		srtapi.EpollRelease(epfd)
+		return -1, err
+	}
+	return epfd, nil
 }
 
 func netpollopen(fd int, pd *pollDesc) error {
-	events := srtapi.EpollIn | srtapi.EpollErr | srtapi.EpollEt
-	pdsLock.Lock()
-	pds[fd] = pd
-	pdsLock.Unlock()
-	return srtapi.EpollAddUsock(epfd, fd, events)
+	p := pollerOf(fd)
+	if p == nil {
+		return errPollerStopped
+	}
+	p.pdsLock.Lock()
+	defer p.pdsLock.Unlock()
+	p.pds[fd] = pd
+	if p.broken {
+		// The socket is added to the new epoll when it is rebuilt.
+		return nil
+	}
+	return srtapi.EpollAddUsock(p.epfd, fd, pollEvents)
 }
 
 func netpollclose(fd int) error {
-	pdsLock.Lock()
-	delete(pds, fd)
-	pdsLock.Unlock()
-	return srtapi.EpollRemoveUsock(epfd, fd)
+	p := pollerOf(fd)
+	if p == nil {
+		// The shutdown has closed fd already.
+		return nil
+	}
+	p.pdsLock.Lock()
+	defer p.pdsLock.Unlock()
+	delete(p.pds, fd)
+	return srtapi.EpollRemoveUsock(p.epfd, fd)
 }
 
 func netpoll_wait_for_write(fd int, enable bool) {
-	events := srtapi.EpollIn | srtapi.EpollErr | srtapi.EpollEt
+	events := pollEvents
 	if enable {
 		events |= srtapi.EpollOut
 	}
-	srtapi.EpollUpdateUsock(epfd, fd, events)
+	p := pollerOf(fd)
+	if p == nil {
+		return
+	}
+	p.pdsLock.RLock()
+	srtapi.EpollUpdateUsock(p.epfd, fd, events)
+	p.pdsLock.RUnlock()
+}
+
+// eventMode converts the epoll events of a socket into the mode of the
+// waiters to wake up.
+func eventMode(events int) int {
+	if events&srtapi.EpollErr != 0 {
+		// A broken socket, e.g. a connect to a closed peer, is
+		// reported as an error only. Wake up both directions, so
+		// that a writer waiting for the connection sees the error.
+		return 'r' + 'w'
+	}
+	mode := 0
+	if events&(srtapi.EpollIn|srtapi.EpollUpdate) != 0 {
+		mode += 'r'
+	}
+	if events&srtapi.EpollOut != 0 {
+		mode += 'w'
+	}
+	return mode
 }
 
-func run() {
-	var rfdslen, wfdslen int
-	var rfds, wfds [128]srtapi.SrtSocket
+// eventBufferSize returns the size of the event buffer to use after n
+// sockets were reported ready with a buffer of the given size.
+func eventBufferSize(size, n int) int {
+	for n >= size && size < maxEvents {
+		size *= 2
+	}
+	return size
+}
+
+func (p *poller) run() {
+	events := make([]srtapi.SrtEpollEvent, minEvents)
+	failing := false
 
 	defer func() {
-		for s, pd := range pds {
+		// Close the sockets that are still open and wake up their
+		// waiters, which then fail on the closed socket.
+		p.pdsLock.RLock()
+		for s, pd := range p.pds {
 			if !pd.closing {
 				srtapi.Close(s)
+				netpollready(pd, 'r'+'w')
 			}
 		}
-		srtapi.Cleanup()
-		done <- true
+		p.pdsLock.RUnlock()
+		p.done <- true
 	}()
 
 	for atomic.LoadInt32(&intState) == 0 {
-		rfdslen = len(rfds)
-		wfdslen = len(wfds)
-
-		if _, err := srtapi.EpollSet(epfd, srtapi.EpollEnableEmpty); err != nil {
-			println("runtime: srt_epoll_set failed with", err.Error())
-			panic("runtime: netpoll::run failed")
-		}
-		n := srtapi.EpollWait(epfd, &rfds[0], &rfdslen, &wfds[0], &wfdslen, 100)
-		if n > 0 {
-			pdsLock.RLock()
-			for i := 0; i < rfdslen; i++ {
-				fd := int(rfds[i])
-				if pd := pds[fd]; pd != nil {
-					netpollready(pd, 'r')
-				}
-			}
-			for i := 0; i < wfdslen; i++ {
-				fd := int(wfds[i])
-				if pd := pds[fd]; pd != nil {
-					netpollready(pd, 'w')
-				}
+		p.pdsLock.RLock()
+		epfd, broken := p.epfd, p.broken
+		p.pdsLock.RUnlock()
+		if broken {
+			if err := p.rebuild(); err != nil {
+				netpollfailed(err)
+				time.Sleep(pollTimeout * time.Millisecond)
 			}
-			pdsLock.RUnlock()
+			continue
 		}
-	}
-}
 
-// this version may be better but it get deadlock state when tring to connect to closed SRT socket currently
-/*func runUWaitVersion() {
-	const fdsSize = 128
-	var fdsSet [fdsSize]srtapi.SrtEpollEvent
-
-	defer func() {
-		for s, pd := range pds {
-			if !pd.closing {
-				srtapi.Close(s)
+		n, err := EpollUwaitFunc(epfd, &events[0], len(events), pollTimeout)
+		if err != nil {
+			p.fail(err)
+			if failing {
+				// The rebuilt epoll failed again; back off.
+				time.Sleep(pollTimeout * time.Millisecond)
 			}
+			failing = true
+			continue
 		}
-		srtapi.Cleanup()
-		done <- true
-	}()
-
-	for atomic.LoadInt32(&intState) == 0 {
-		if _, err := srtapi.EpollSet(epfd, srtapi.EpollEnableEmpty); err != nil {
-			println("runtime: srt_epoll_set failed with", err.Error())
-			panic("runtime: netpoll::run failed")
+		failing = false
+		if n == 0 {
+			continue
 		}
-		n := srtapi.EpollUwait(epfd, &fdsSet[0], fdsSize, 100)
-		if n > 0 {
-			pdsLock.RLock()
-			for i := 0; i < n; i++ {
-				fds := fdsSet[i]
-				fd := int(srtapi.GetFdFromEpollEvent(&fds))
-				events := srtapi.GetEventsFromEpollEvent(&fds)
-				if pd := pds[fd]; pd != nil {
-					var mode int
-					if (events & srtapi.EpollOut) == 0 {
-						mode = 'r'
-					} else {
-						mode = 'w'
-					}
-					netpollready(pd, mode)
-				}
+		ready := n
+		if ready > len(events) {
+			ready = len(events)
+		}
+		p.pdsLock.RLock()
+		for i := 0; i < ready; i++ {
+			fd := int(srtapi.GetFdFromEpollEvent(&events[i]))
+			mode := eventMode(srtapi.GetEventsFromEpollEvent(&events[i]))
+			if pd := p.pds[fd]; pd != nil && mode != 0 {
+				netpollready(pd, mode)
 			}
-			pdsLock.RUnlock()
+		}
+		p.pdsLock.RUnlock()
+		if size := eventBufferSize(len(events), n); size != len(events) {
+			events = make([]srtapi.SrtEpollEvent, size)
 		}
 	}
-}*/
+}
+
+// fail marks the epoll of p as broken and fails the pending waits on
+// its sockets, which might have missed events.
+func (p *poller) fail(err error) {
+	netpollfailed(err)
+	p.pdsLock.Lock()
+	p.broken = true
+	p.pdsLock.Unlock()
+	p.pdsLock.RLock()
+	for _, pd := range p.pds {
+		netpollfail(pd, err)
+	}
+	p.pdsLock.RUnlock()
+}
+
+// rebuild replaces the broken epoll of p with a new one containing the
+// same sockets.
+func (p *poller) rebuild() error {
+	epfd, err := newEpoll()
+	if err != nil {
+		return err
+	}
+	p.pdsLock.Lock()
+	defer p.pdsLock.Unlock()
+	for fd := range p.pds {
+		// A socket that cannot be added is broken itself; its next
+		// I/O reports that.
+		srtapi.EpollAddUsock(epfd, fd, pollEvents)
+	}
+	srtapi.EpollRelease(p.epfd)
+	p.epfd = epfd
+	p.broken = false
+	netpollrebuilt()
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package runtime
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// The deadlines of a descriptor are checked by its readers and writers
+// while another goroutine sets them; run with -race.
+func TestCheckErrSetDeadline(t *testing.T) {
+	if err := PollServerInit(0); err != nil {
+		t.Fatal(err)
+	}
+	s, err := srtapi.Socket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srtapi.Close(s)
+	pd, err := PollOpen(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+
+	const n = 1000
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			// in the past, none and in the future
+			pd.SetDeadline(time.Duration(i%3-1)*time.Hour, 'r'+'w')
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			pd.Reset('r')
+			pd.Reset('w')
+		}
+	}()
+	wg.Wait()
+}
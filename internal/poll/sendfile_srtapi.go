@@ -8,39 +8,45 @@
 package poll
 
 import (
-	"io"
+	"os"
 
 	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
-// maxSendfileSize is the largest chunk size we ask the kernel to copy
+// maxSendfileSize is the largest chunk size we ask the library to copy
 // at a time.
 const maxSendfileSize int = 4 << 20
 
-// SendFile wraps the sendfile system call.
-func SendFile(dstFD *FD, r io.Reader, remain int64) (int64, error) {
+// SendFile wraps the srt_sendfile call. It sends remain bytes of f from
+// offset on, in blocks of block bytes, and leaves the offset of f
+// alone.
+//
+// srt_sendfile cannot be interrupted once it started, so SendFile
+// performs no work if a write deadline is set; handled is false then.
+func SendFile(dstFD *FD, f *os.File, offset, remain int64, block int) (written int64, handled bool, err error) {
 	if err := dstFD.writeLock(); err != nil {
-		return 0, err
+		return 0, true, err
 	}
 	defer dstFD.writeUnlock()
+	if err := dstFD.pd.prepareWrite(); err != nil {
+		return 0, true, err
+	}
+	if dstFD.pd.hasWriteDeadline() {
+		return 0, false, nil
+	}
 
-	dst := int(dstFD.Sysfd)
-	var written int64
-	var err error
+	dst := dstFD.Sysfd
+	off := offset
 	for remain > 0 {
 		n := maxSendfileSize
 		if int64(n) > remain {
 			n = int(remain)
 		}
-		n, err1 := srtapi.Sendfile(dst, r, nil, n)
-		if n > 0 {
-			written += int64(n)
-			remain -= int64(n)
-		}
-		if n == 0 && err1 == nil {
-			break
-		}
-		if err1 == srtapi.EASYNCSND {
+		start := off
+		_, err1 := srtapi.SendfileBlock(dst, f, &off, n, block)
+		written += off - start
+		remain -= off - start
+		if err1 == srtapi.EASYNCSND && dstFD.pd.pollable() {
 			if err1 = dstFD.pd.waitWrite(); err1 == nil {
 				continue
 			}
@@ -49,6 +55,10 @@ func SendFile(dstFD *FD, r io.Reader, remain int64) (int64, error) {
 			err = err1
 			break
 		}
+		if off == start {
+			// the file is shorter than it was
+			break
+		}
 	}
-	return written, err
+	return written, true, err
 }
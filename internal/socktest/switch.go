@@ -128,6 +128,7 @@ const (
 	FilterGetsockoptInt                    // for GetsockoptInt
 	FilterGetsockflagInt                   // for GetsockflagInt
 	FilterClose                            // for Close or Closesocket
+	FilterEpollWait                        // for EpollUwait
 )
 
 // A Filter represents a socket system call filter.
@@ -228,3 +228,25 @@ func (sw *Switch) GetsockflagInt(s, opt int) (status int, err error) {
 	}
 	return status, nil
 }
+
+// EpollUwait wraps srtapi.EpollUwait.
+//
+// Unlike the other filters, the filter for FilterEpollWait is applied
+// to every epoll, with a Status that has only Err set after the call.
+func (sw *Switch) EpollUwait(epfd int, fdsSet *srtapi.SrtEpollEvent, fdsSize int, msTimeOut int64) (n int, err error) {
+	sw.once.Do(sw.init)
+	so := &Status{}
+	sw.fmu.RLock()
+	f := sw.fltab[FilterEpollWait]
+	sw.fmu.RUnlock()
+
+	af, err := f.apply(so)
+	if err != nil {
+		return 0, err
+	}
+	n, so.Err = srtapi.EpollUwait(epfd, fdsSet, fdsSize, msTimeOut)
+	if err = af.apply(so); err != nil {
+		return 0, err
+	}
+	return n, so.Err
+}
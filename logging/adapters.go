@@ -0,0 +1,147 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger returns a Logger that writes the records to l, one line
+// each, as "[file:line(area)]{level} message".
+func NewStdLogger(l *log.Logger) Logger {
+	return stdLogger{l}
+}
+
+func (s stdLogger) Log(r Record) {
+	s.l.Printf("[%s:%d(%s)]{%v} %s", r.File, r.Line, r.Area, r.Level, r.Message)
+}
+
+// jsonRecord is the JSON form of a Record.
+type jsonRecord struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	FA      string    `json:"fa"`
+	Area    string    `json:"area"`
+	File    string    `json:"file"`
+	Line    int       `json:"line"`
+	Message string    `json:"msg"`
+}
+
+type jsonLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLogger returns a Logger that writes the records to w as JSON
+// objects, one per line, with the fields time, level, fa, area, file,
+// line and msg. Write errors are ignored.
+func NewJSONLogger(w io.Writer) Logger {
+	return &jsonLogger{enc: json.NewEncoder(w)}
+}
+
+func (j *jsonLogger) Log(r Record) {
+	jr := jsonRecord{
+		Time:    r.Time,
+		Level:   r.Level.String(),
+		FA:      r.FA.String(),
+		Area:    r.Area,
+		File:    r.File,
+		Line:    r.Line,
+		Message: r.Message,
+	}
+	j.mu.Lock()
+	j.enc.Encode(&jr)
+	j.mu.Unlock()
+}
+
+// LeveledLogger is the interface of structured loggers with a method
+// per level taking alternating keys and values, such as
+// zap.SugaredLogger. Other loggers, such as a logrus.FieldLogger, are
+// adapted to it in a few lines.
+type LeveledLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type leveledLogger struct {
+	l LeveledLogger
+}
+
+// NewLeveledLogger returns a Logger that passes the records to l, with
+// the keys "fa", "area", "file" and "line". The levels more severe than
+// LevelError are logged as errors, and LevelNote as info.
+func NewLeveledLogger(l LeveledLogger) Logger {
+	return leveledLogger{l}
+}
+
+func (a leveledLogger) Log(r Record) {
+	kv := []interface{}{"fa", r.FA.String(), "area", r.Area, "file", r.File, "line", r.Line}
+	switch {
+	case r.Level >= LevelDebug:
+		a.l.Debugw(r.Message, kv...)
+	case r.Level >= LevelNote:
+		a.l.Infow(r.Message, kv...)
+	case r.Level == LevelWarning:
+		a.l.Warnw(r.Message, kv...)
+	default:
+		a.l.Errorw(r.Message, kv...)
+	}
+}
+
+// A Filter passes the records of each functional area up to a level to
+// a Logger. The library must log at the most verbose of the levels for
+// the records to reach the Filter. The levels can be changed at any
+// time.
+type Filter struct {
+	logger Logger
+	levels []int32 // by FA, accessed atomically
+}
+
+// NewFilter returns a Filter passing the records up to level of all
+// functional areas to l.
+func NewFilter(l Logger, level Level) *Filter {
+	f := &Filter{logger: l, levels: make([]int32, len(faNames))}
+	for i := range f.levels {
+		f.levels[i] = int32(level)
+	}
+	return f
+}
+
+// index returns the index of fa in f.levels; unknown areas count as
+// FAGeneral.
+func (f *Filter) index(fa FA) int {
+	if fa < 0 || int(fa) >= len(f.levels) {
+		return int(FAGeneral)
+	}
+	return int(fa)
+}
+
+// SetLevel sets the level of the records of fa passed.
+func (f *Filter) SetLevel(fa FA, level Level) {
+	atomic.StoreInt32(&f.levels[f.index(fa)], int32(level))
+}
+
+// Level returns the level of the records of fa passed.
+func (f *Filter) Level(fa FA) Level {
+	return Level(atomic.LoadInt32(&f.levels[f.index(fa)]))
+}
+
+// Log passes r to the Logger of f if its level is at most the level of
+// its functional area.
+func (f *Filter) Log(r Record) {
+	if r.Level <= f.Level(r.FA) {
+		f.logger.Log(r)
+	}
+}
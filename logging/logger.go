@@ -0,0 +1,192 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package logging routes the log messages of the SRT library to a
+// Logger.
+//
+// The library logs the functional areas enabled with SRT_LOGFA at the
+// level of SRT_LOGLEVEL (see package conf). Once a Logger is set with
+// SetLogger, the messages are passed to it as Records instead of being
+// written by the library:
+//
+//	logging.SetLogger(logging.NewJSONLogger(os.Stdout))
+//
+// Messages of gosrt itself, such as the panics of listen callbacks,
+// are passed to the Logger with the area "gosrt" (see Logf).
+//
+// Loggers are called from the threads of the library, concurrently;
+// SetLogger can be called at any time.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// Level is the severity of a log message, as in syslog.
+type Level int
+
+// Log levels
+const (
+	LevelEmerg   Level = srtapi.LogEmerg
+	LevelAlert   Level = srtapi.LogAlert
+	LevelFatal   Level = srtapi.LogFatal
+	LevelError   Level = srtapi.LogError
+	LevelWarning Level = srtapi.LogWarning
+	LevelNote    Level = srtapi.LogNote
+	LevelInfo    Level = srtapi.LogInfo
+	LevelDebug   Level = srtapi.LogDebug
+)
+
+var levelNames = []string{"emerg", "alert", "fatal", "error", "warning", "note", "info", "debug"}
+
+func (l Level) String() string {
+	if l >= 0 && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return "Level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// FA is a functional area of the library, the part of it a log message
+// comes from.
+type FA int
+
+// Functional areas
+const (
+	FAGeneral FA = srtapi.LogFAGeneral
+	FABstats  FA = srtapi.LogFABstats
+	FAControl FA = srtapi.LogFAControl
+	FAData    FA = srtapi.LogFAData
+	FATsbpd   FA = srtapi.LogFATsbpd
+	FARexmit  FA = srtapi.LogFARexmit
+)
+
+var faNames = []string{"general", "bstats", "control", "data", "tsbpd", "rexmit"}
+
+func (fa FA) String() string {
+	if fa >= 0 && int(fa) < len(faNames) {
+		return faNames[fa]
+	}
+	return "FA(" + strconv.Itoa(int(fa)) + ")"
+}
+
+// faOf returns the functional area of a logger name of the library,
+// such as "SRT.c" for the control area.
+func faOf(area string) FA {
+	if !strings.HasPrefix(area, "SRT.") || len(area) < 5 {
+		return FAGeneral
+	}
+	switch area[4] {
+	case 'b':
+		return FABstats
+	case 'c':
+		return FAControl
+	case 'd':
+		return FAData
+	case 't':
+		return FATsbpd
+	case 'r':
+		return FARexmit
+	}
+	return FAGeneral
+}
+
+// A Record is a log message of the library.
+type Record struct {
+	Time  time.Time
+	Level Level
+
+	// FA is the functional area derived from Area, the name of the
+	// logger of the library.
+	FA   FA
+	Area string
+
+	// File and Line locate the message in the sources of the library.
+	File string
+	Line int
+
+	Message string
+}
+
+// A Logger receives the log messages of the library. Log is called
+// concurrently, from the threads of the library, and must not block
+// for long.
+type Logger interface {
+	Log(r Record)
+}
+
+// HandlerFunc logging handler function type. It is a Logger of the
+// level, file, line, area and message of the records.
+type HandlerFunc func(level int, file string, line int, area string, message string)
+
+// Log calls f with the fields of r.
+func (f HandlerFunc) Log(r Record) {
+	f(int(r.Level), r.File, r.Line, r.Area, r.Message)
+}
+
+// defaultLogger writes to the standard error when the library logs
+// through the handler without a Logger set.
+var defaultLogger Logger = NewStdLogger(log.New(os.Stderr, "", log.LstdFlags|log.Lmicroseconds))
+
+// loggerHolder gives the values of current a single concrete type.
+type loggerHolder struct {
+	Logger
+}
+
+var current atomic.Value // of loggerHolder
+
+// SetLogger makes the library pass its log messages to l. A nil l
+// restores the output of the library configured by package conf.
+func SetLogger(l Logger) {
+	current.Store(loggerHolder{l})
+	setHandler(l != nil)
+}
+
+// SetHandler set handler
+func SetHandler(h HandlerFunc) {
+	if h == nil {
+		SetLogger(nil)
+		return
+	}
+	SetLogger(h)
+}
+
+// logger returns the current Logger, or nil if none is set.
+func logger() Logger {
+	h, _ := current.Load().(loggerHolder)
+	return h.Logger
+}
+
+// Logf passes a message of gosrt itself, formatted as by fmt.Sprintf,
+// to the current Logger. The record has the area "gosrt" and the
+// location of the caller.
+func Logf(level Level, format string, v ...interface{}) {
+	_, file, line, _ := runtime.Caller(1)
+	dispatch(int(level), filepath.Base(file), line, "gosrt", fmt.Sprintf(format, v...))
+}
+
+// dispatch passes a message of the library to the current Logger.
+func dispatch(level int, file string, line int, area, message string) {
+	l := logger()
+	if l == nil {
+		l = defaultLogger
+	}
+	l.Log(Record{
+		Time:    time.Now(),
+		Level:   Level(level),
+		FA:      faOf(area),
+		Area:    area,
+		File:    file,
+		Line:    line,
+		Message: message,
+	})
+}
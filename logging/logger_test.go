@@ -0,0 +1,217 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a Logger keeping the records.
+type recorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (r *recorder) Log(rec Record) {
+	r.mu.Lock()
+	r.records = append(r.records, rec)
+	r.mu.Unlock()
+}
+
+func (r *recorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records)
+}
+
+func TestFAOf(t *testing.T) {
+	for area, fa := range map[string]FA{
+		"SRT.g": FAGeneral,
+		"SRT.b": FABstats,
+		"SRT.c": FAControl,
+		"SRT.d": FAData,
+		"SRT.t": FATsbpd,
+		"SRT.r": FARexmit,
+		"SRT.m": FAGeneral,
+		"SRT.":  FAGeneral,
+		"":      FAGeneral,
+		"c":     FAGeneral,
+	} {
+		if got := faOf(area); got != fa {
+			t.Errorf("%q: got %v; want %v", area, got, fa)
+		}
+	}
+	if s := FA(9).String(); s != "FA(9)" {
+		t.Errorf("got %s", s)
+	}
+	if s := LevelWarning.String(); s != "warning" {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	defer SetLogger(nil)
+	var rec recorder
+	SetLogger(&rec)
+	dispatch(int(LevelNote), "core.cpp", 42, "SRT.c", "connected")
+	if len(rec.records) != 1 {
+		t.Fatalf("got %d records; want 1", len(rec.records))
+	}
+	r := rec.records[0]
+	if r.Level != LevelNote || r.FA != FAControl || r.Area != "SRT.c" || r.File != "core.cpp" || r.Line != 42 || r.Message != "connected" || r.Time.IsZero() {
+		t.Errorf("got %+v", r)
+	}
+
+	var got string
+	SetHandler(func(level int, file string, line int, area string, message string) {
+		got = fmt.Sprintf("%d %s:%d %s %s", level, file, line, area, message)
+	})
+	dispatch(int(LevelDebug), "queue.cpp", 7, "SRT.d", "packet")
+	if want := "7 queue.cpp:7 SRT.d packet"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	SetHandler(nil)
+	if logger() != nil {
+		t.Error("got a logger after SetHandler(nil)")
+	}
+
+	// the loggers can be swapped while the library logs
+	var a, b recorder
+	SetLogger(&a)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				dispatch(int(LevelError), "core.cpp", 1, "SRT.g", "message")
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		SetLogger(&b)
+		SetLogger(&a)
+	}
+	wg.Wait()
+	if n := a.len() + b.len(); n != 4000 {
+		t.Errorf("got %d records; want 4000", n)
+	}
+}
+
+func TestLogf(t *testing.T) {
+	defer SetLogger(nil)
+	var rec recorder
+	SetLogger(&rec)
+	Logf(LevelError, "listen callback: %v", "panic")
+	if len(rec.records) != 1 {
+		t.Fatalf("got %d records; want 1", len(rec.records))
+	}
+	r := rec.records[0]
+	if r.Level != LevelError || r.Area != "gosrt" || r.File != "logger_test.go" || r.Line == 0 || r.Message != "listen callback: panic" {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(log.New(&buf, "", 0))
+	l.Log(Record{Level: LevelError, Area: "SRT.c", File: "core.cpp", Line: 42, Message: "failed"})
+	if got, want := buf.String(), "[core.cpp:42(SRT.c)]{error} failed\n"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewJSONLogger(&buf)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	l.Log(Record{Time: now, Level: LevelDebug, FA: FATsbpd, Area: "SRT.t", File: "buffer.cpp", Line: 7, Message: "drop"})
+	l.Log(Record{Time: now, Level: LevelInfo, Message: "second"})
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines; want 2", len(lines))
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(lines[0], &m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"time":  "2020-01-02T03:04:05Z",
+		"level": "debug",
+		"fa":    "tsbpd",
+		"area":  "SRT.t",
+		"file":  "buffer.cpp",
+		"line":  float64(7),
+		"msg":   "drop",
+	}
+	if fmt.Sprint(m) != fmt.Sprint(want) {
+		t.Errorf("got %v; want %v", m, want)
+	}
+}
+
+// sugared records the calls of a LeveledLogger.
+type sugared struct {
+	calls []string
+}
+
+func (s *sugared) log(level, msg string, kv []interface{}) {
+	s.calls = append(s.calls, fmt.Sprint(level, " ", msg, " ", kv))
+}
+
+func (s *sugared) Debugw(msg string, kv ...interface{}) { s.log("debug", msg, kv) }
+func (s *sugared) Infow(msg string, kv ...interface{})  { s.log("info", msg, kv) }
+func (s *sugared) Warnw(msg string, kv ...interface{})  { s.log("warn", msg, kv) }
+func (s *sugared) Errorw(msg string, kv ...interface{}) { s.log("error", msg, kv) }
+
+func TestLeveledLogger(t *testing.T) {
+	var s sugared
+	l := NewLeveledLogger(&s)
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelNote, LevelWarning, LevelError, LevelEmerg} {
+		l.Log(Record{Level: level, FA: FAData, Area: "SRT.d", File: "core.cpp", Line: 3, Message: "m"})
+	}
+	want := []string{
+		"debug m [fa data area SRT.d file core.cpp line 3]",
+		"info m [fa data area SRT.d file core.cpp line 3]",
+		"info m [fa data area SRT.d file core.cpp line 3]",
+		"warn m [fa data area SRT.d file core.cpp line 3]",
+		"error m [fa data area SRT.d file core.cpp line 3]",
+		"error m [fa data area SRT.d file core.cpp line 3]",
+	}
+	if fmt.Sprint(s.calls) != fmt.Sprint(want) {
+		t.Errorf("got %q; want %q", s.calls, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var rec recorder
+	f := NewFilter(&rec, LevelWarning)
+	f.SetLevel(FAControl, LevelDebug)
+	f.SetLevel(FA(42), LevelError) // sets FAGeneral
+	for _, r := range []Record{
+		{Level: LevelDebug, FA: FAControl, Message: "pass"},
+		{Level: LevelDebug, FA: FAData},
+		{Level: LevelWarning, FA: FAData, Message: "pass"},
+		{Level: LevelWarning, FA: FAGeneral},
+		{Level: LevelError, FA: FA(42), Message: "pass"},
+	} {
+		f.Log(r)
+	}
+	if len(rec.records) != 3 {
+		t.Fatalf("got %+v; want 3 records", rec.records)
+	}
+	for _, r := range rec.records {
+		if r.Message != "pass" {
+			t.Errorf("got %+v", r)
+		}
+	}
+	if f.Level(FAControl) != LevelDebug || f.Level(FATsbpd) != LevelWarning {
+		t.Errorf("got levels %v, %v", f.Level(FAControl), f.Level(FATsbpd))
+	}
+}
@@ -1,6 +1,8 @@
 // Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
 // https://github.com/openfresh/gosrt
 
+// +build !srtmock
+
 package logging
 
 // #cgo LDFLAGS: -lsrt
@@ -11,49 +13,78 @@ package logging
 */
 import "C"
 import (
-	"fmt"
-	"time"
+	"sync"
 	"unsafe"
 
 	"github.com/xmedia-systems/gosrt/conf"
 	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
-// HandlerFunc logging handler function type
-type HandlerFunc func(level int, file string, line int, area string, message string)
-
-var handler HandlerFunc
-
 //export logHandler
 func logHandler(opaque unsafe.Pointer, level C.int, file *C.char, line C.int, area *C.char, message *C.char) {
-	if handler != nil {
-		handler(int(level), C.GoString(file), int(line), C.GoString(area), C.GoString(message))
+	dispatch(int(level), C.GoString(file), int(line), C.GoString(area), C.GoString(message))
+}
+
+var (
+	// handlerMu serializes the changes of the output of the library.
+	handlerMu sync.Mutex
+	logFile   string // the log file set in the library
+
+	watchOnce sync.Once
+)
+
+// installHandler makes the library log through logHandler, or through
+// its own output if on is false.
+func installHandler(on bool) {
+	if on {
+		srtapi.SetLogFlags(0 | srtapi.LogFlagDisableTime | srtapi.LogFlagDisableSeverity | srtapi.LogFlagDisableThreadname | srtapi.LogFlagDisableEOF)
+		C.srt_setloghandler(nil, (*C.SRT_LOG_HANDLER_FN)(C.logHandler_cgo))
 	} else {
-		now := time.Now()
-		buf := fmt.Sprintf("[%v, %s:%d(%s)]{%d} %s", now, C.GoString(file), line, C.GoString(area), level, C.GoString(message))
-		println(buf)
+		srtapi.SetLogFlags(0)
+		C.srt_setloghandler(nil, nil)
 	}
 }
 
-// Init initialize logging function
-func Init() {
-	srtapi.SetLogLevel(conf.SystemConf().LogLevel())
-	for fa := range conf.SystemConf().LogFAs() {
-		srtapi.AddLogFA(fa)
-	}
-	NAME := C.CString("SRTLIB")
-	defer C.free(unsafe.Pointer(NAME))
-	if conf.SystemConf().LogInternal() {
-		srtapi.SetLogFlags(0 | srtapi.LogFlagDisableTime | srtapi.LogFlagDisableSeverity | srtapi.LogFlagDisableThreadname | srtapi.LogFlagDisableEOF)
-		C.srt_setloghandler(unsafe.Pointer(NAME), (*C.SRT_LOG_HANDLER_FN)(C.logHandler_cgo))
-	} else if logFile := conf.SystemConf().LogFile(); logFile != "" {
-		p := C.CString(logFile)
+// setHandler installs the handler if a Logger is set or the library is
+// configured to log internally.
+func setHandler(logger bool) {
+	handlerMu.Lock()
+	defer handlerMu.Unlock()
+	installHandler(logger || conf.SystemConf().LogInternal())
+}
+
+// applyOutput sets the log file and the handler of the library as
+// configured by c. handlerMu must be held.
+func applyOutput(c *conf.Conf) {
+	if name := c.LogFile(); name != logFile {
+		p := C.CString(name)
 		defer C.free(unsafe.Pointer(p))
-		C.udtSetLogStream(p)
+		if C.udtSetLogStream(p) == 0 {
+			logFile = name
+		} else {
+			dispatch(srtapi.LogError, "", 0, "gosrt", "cannot open log file "+name)
+		}
 	}
+	installHandler(logger() != nil || c.LogInternal())
 }
 
-// SetHandler set handler
-func SetHandler(h HandlerFunc) {
-	handler = h
+// watch applies the changes of the configuration to the library.
+func watch(c *conf.Conf) {
+	handlerMu.Lock()
+	defer handlerMu.Unlock()
+	applyOutput(c)
+}
+
+// Init initialize logging function. The later changes of
+// conf.SystemConf take effect immediately.
+func Init() {
+	watchOnce.Do(func() { conf.SystemConf().Watch(watch) })
+	handlerMu.Lock()
+	defer handlerMu.Unlock()
+	c := conf.SystemConf()
+	srtapi.SetLogLevel(c.LogLevel())
+	for _, fa := range c.LogFAs() {
+		srtapi.AddLogFA(fa)
+	}
+	applyOutput(c)
 }
@@ -0,0 +1,16 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build srtmock
+
+package logging
+
+// Init initialize logging function. The mock backend of srtapi does
+// not log, so there is nothing to set up.
+func Init() {
+}
+
+// setHandler has no handler to install, as the mock backend of srtapi
+// does not log.
+func setHandler(logger bool) {
+}
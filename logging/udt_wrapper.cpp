@@ -1,22 +1,37 @@
 // Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
 // https://github.com/openfresh/gosrt
 
+// +build !srtmock
+
 #include "udt_wrapper.h"
 #include <srt/udt.h>
+#include <iostream>
+
+// the stream set in the library, if it is a file
+static std::ofstream* log_stream;
 
 extern "C" {
     int udtSetLogStream(const char* logfile) {
-        std::ofstream logfile_stream;
-        logfile_stream.open(logfile);
-        if ( !logfile_stream )
+        if ( logfile == NULL || *logfile == '\0' )
         {
-            return SRT_ERROR;
+            UDT::setlogstream(std::cerr);
+            delete log_stream;
+            log_stream = NULL;
+            return 0;
         }
-        else
+
+        std::ofstream* stream = new std::ofstream(logfile, std::ios::app);
+        if ( !*stream )
         {
-            UDT::setlogstream(logfile_stream);
+            delete stream;
+            return SRT_ERROR;
         }
-        
+        // the library no longer writes to the previous stream once
+        // setlogstream returns
+        UDT::setlogstream(*stream);
+        delete log_stream;
+        log_stream = stream;
+
         return 0;
     }
 
@@ -0,0 +1,122 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/xmedia-systems/gosrt/srt/streamid"
+)
+
+// AcceptRequest describes a pending connection passed to an
+// AcceptPolicy.
+type AcceptRequest struct {
+	// PeerAddr is the address of the caller.
+	PeerAddr net.Addr
+
+	// HSVersion is the handshake version of the caller.
+	HSVersion int
+
+	// StreamID is the parsed stream ID sent by the caller.
+	StreamID *streamid.StreamID
+}
+
+// AcceptPolicy decides whether a pending connection is accepted and
+// with which options.
+//
+// Returning a non-nil error rejects the connection, as for a
+// ListenCallbackFunc. Otherwise the connection is accepted and the
+// non-zero fields of the returned Config, if any, are applied to it
+// on top of the options of the listener, also to the options such as
+// InputBW and OheadBW that are set on a connection once it is
+// accepted. That way every connection
+// can get its own Passphrase, PBKeyLen, Latency or AllowUnencrypted
+// setting, e.g. depending on StreamID.User.
+type AcceptPolicy func(req *AcceptRequest) (*Config, error)
+
+// WithAcceptPolicy returns a new context.Context with the accept
+// policy. It replaces a listen callback set with WithListenCallback.
+//
+// Connections with a malformed stream ID are rejected with
+// RejectBadRequest before the policy is called. If the returned Config
+// is invalid or cannot be applied, the connection is rejected with
+// RejectFallback.
+func WithAcceptPolicy(ctx context.Context, policy AcceptPolicy) context.Context {
+	po := &policyOptions{m: make(map[int]map[string]bool)}
+	ctx = context.WithValue(ctx, policyOptionsContextKey{}, po)
+	return WithListenCallback(ctx, ParseStreamID(func(ns int, hsversion int, peeraddr syscall.Sockaddr, id *streamid.StreamID) error {
+		c, err := policy(&AcceptRequest{
+			PeerAddr:  sockaddrToSRT(peeraddr),
+			HSVersion: hsversion,
+			StreamID:  id,
+		})
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return nil
+		}
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if err := c.apply(ns); err != nil {
+			return err
+		}
+		po.add(ns, c)
+		return nil
+	}))
+}
+
+type policyOptionsContextKey struct{}
+
+// policyOptions records the options an accept policy set on the
+// pending connections of a listener, by socket, so that the options of
+// the listener set after accepting do not override them. The records
+// of connections that fail before they are accepted go with the
+// listener.
+type policyOptions struct {
+	sync.Mutex
+	m map[int]map[string]bool
+}
+
+func (po *policyOptions) add(ns int, c *Config) {
+	names := make(map[string]bool)
+	for _, o := range c.options().list {
+		names[o.key] = true
+	}
+	po.Lock()
+	po.m[ns] = names
+	po.Unlock()
+}
+
+// takePolicyOptions returns the names of the options the accept policy
+// of ctx set on the socket s, and forgets them.
+func takePolicyOptions(ctx context.Context, s int) map[string]bool {
+	po, _ := ctx.Value(policyOptionsContextKey{}).(*policyOptions)
+	if po == nil {
+		return nil
+	}
+	po.Lock()
+	defer po.Unlock()
+	names := po.m[s]
+	delete(po.m, s)
+	return names
+}
+
+// apply sets the options of c on the socket s.
+func (c *Config) apply(s int) error {
+	for _, o := range c.options().list {
+		so := lookupOption(o.key)
+		if so == nil {
+			continue
+		}
+		if err := so.apply(s, o.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
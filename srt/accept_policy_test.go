@@ -0,0 +1,169 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/logging"
+	"github.com/xmedia-systems/gosrt/srt/streamid"
+)
+
+var acceptPolicyTests = []struct {
+	user, passphrase string
+	reason           RejectReason // zero means accepted
+}{
+	{"alice", "alice-secret-key", 0},
+	{"bob", "bob-secret-key-1", 0},
+	{"alice", "bob-secret-key-1", RejectBadSecret},
+	{"mallory", "mallory-secret-1", RejectUnauthorized},
+	{"", "", RejectUnauthorized},
+}
+
+func TestAcceptPolicy(t *testing.T) {
+	passphrases := map[string]string{
+		"alice": "alice-secret-key",
+		"bob":   "bob-secret-key-1",
+	}
+	ctx := WithAcceptPolicy(context.Background(), func(req *AcceptRequest) (*Config, error) {
+		if req.PeerAddr == nil {
+			t.Error("missing peer address")
+		}
+		pw, ok := passphrases[req.StreamID.User]
+		if !ok {
+			return nil, RejectUnauthorized
+		}
+		return &Config{Passphrase: pw, PBKeyLen: 16, Latency: 300 * time.Millisecond}, nil
+	})
+	ln, err := newLocalListenerContext(ctx, "srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// the accepted connections are kept open until the caller is done
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	for i, tt := range acceptPolicyTests {
+		d := Dialer{
+			StreamID: &streamid.StreamID{User: tt.user, Resource: "live"},
+			Config:   &Config{Passphrase: tt.passphrase, PBKeyLen: 16},
+		}
+		c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+		if tt.reason == 0 {
+			if err != nil {
+				t.Errorf("#%d: %v", i, err)
+				continue
+			}
+			c.Close()
+			sc := <-accepted
+			if l, _ := sc.(*SRTConn).Latency(); l != 300*time.Millisecond {
+				t.Errorf("#%d: got latency %v; want 300ms", i, l)
+			}
+			sc.Close()
+			continue
+		}
+		if err == nil {
+			c.Close()
+			t.Errorf("#%d: should fail", i)
+			continue
+		}
+		rerr, ok := err.(*OpError).Err.(*RejectError)
+		if !ok {
+			t.Errorf("#%d: got %v; want *RejectError", i, err)
+			continue
+		}
+		if rerr.Reason != tt.reason {
+			t.Errorf("#%d: got reason %v; want %v", i, rerr.Reason, tt.reason)
+		}
+	}
+}
+
+func TestAcceptPolicyPostOptions(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("inputbw", "5000", "oheadbw", "30"))
+	ctx = WithAcceptPolicy(ctx, func(req *AcceptRequest) (*Config, error) {
+		return &Config{InputBW: 1000}, nil
+	})
+	ln, err := newLocalListenerContext(ctx, "srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			accepted <- c
+		}
+	}()
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := (<-accepted).(*SRTConn)
+	defer sc.Close()
+
+	// the policy overrides the listener, which sets the rest
+	if bw, err := sc.InputBW(); err != nil || bw != 1000 {
+		t.Errorf("got inputbw %d, %v; want 1000", bw, err)
+	}
+	if oh, err := sc.OheadBW(); err != nil || oh != 30 {
+		t.Errorf("got oheadbw %d, %v; want 30", oh, err)
+	}
+}
+
+// panicRecorder is a logging.Logger keeping the messages.
+type panicRecorder struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *panicRecorder) Log(rec logging.Record) {
+	r.mu.Lock()
+	r.msgs = append(r.msgs, rec.Message)
+	r.mu.Unlock()
+}
+
+func TestAcceptPolicyPanic(t *testing.T) {
+	var rec panicRecorder
+	logging.SetLogger(&rec)
+	defer logging.SetLogger(nil)
+	ctx := WithAcceptPolicy(context.Background(), func(req *AcceptRequest) (*Config, error) {
+		panic("policy failed")
+	})
+	ln, err := newLocalListenerContext(ctx, "srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatal("should fail")
+	}
+	rerr, ok := err.(*OpError).Err.(*RejectError)
+	if !ok || rerr.Reason != RejectFallback {
+		t.Errorf("got %v; want %v", err, RejectFallback)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.msgs) != 1 || !strings.Contains(rec.msgs[0], "panic: policy failed") || !strings.Contains(rec.msgs[0], "goroutine ") {
+		t.Errorf("got log %q; want the panic and its stack", rec.msgs)
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// TransType is the transmission type of a connection. It selects a
+// set of defaults for the other socket options.
+type TransType int
+
+// Transmission types
+const (
+	// TransTypeDefault leaves the transmission type of the library,
+	// which is TransTypeLive.
+	TransTypeDefault TransType = iota
+
+	// TransTypeLive is used for live streaming.
+	TransTypeLive
+
+	// TransTypeFile is used for file transfers.
+	TransTypeFile
+)
+
+func (t TransType) String() string {
+	switch t {
+	case TransTypeDefault:
+		return "default"
+	case TransTypeLive:
+		return "live"
+	case TransTypeFile:
+		return "file"
+	}
+	return "TransType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Config is the typed form of the SRT socket options. The zero value
+// of each field leaves the default of the library.
+//
+// A Config is applied with WithConfig, Dialer.Config or
+// ListenConfig.Config. It is validated before a socket is created.
+// Options not covered by Config can still be set with WithOptions.
+type Config struct {
+	// TransType is the transmission type (SRTO_TRANSTYPE).
+	TransType TransType
+
+	// Latency is the receiver and sender latency (SRTO_LATENCY).
+	// RcvLatency and PeerLatency override it for one direction.
+	// The latencies have a millisecond granularity.
+	Latency     time.Duration
+	RcvLatency  time.Duration
+	PeerLatency time.Duration
+
+	// ConnTimeout is the connect timeout (SRTO_CONNTIMEO).
+	ConnTimeout time.Duration
+
+	// PeerIdleTimeout is the time after which a silent peer is
+	// considered lost (SRTO_PEERIDLETIMEO).
+	PeerIdleTimeout time.Duration
+
+	// Passphrase enables encryption (SRTO_PASSPHRASE). It must be
+	// 10 to 79 characters long.
+	Passphrase string
+
+	// PBKeyLen is the encryption key length in bytes, one of 16, 24
+	// or 32 (SRTO_PBKEYLEN).
+	PBKeyLen int
+
+	// AllowUnencrypted allows a connection between an encrypted and
+	// an unencrypted peer (SRTO_ENFORCEDENCRYPTION set to false).
+	AllowUnencrypted bool
+
+	// KMRefreshRate and KMPreAnnounce control the rotation of the
+	// encryption key, in packets (SRTO_KMREFRESHRATE,
+	// SRTO_KMPREANNOUNCE).
+	KMRefreshRate int
+	KMPreAnnounce int
+
+	// MaxBW is the maximum sending bandwidth in bytes per second
+	// (SRTO_MAXBW). -1 means unlimited.
+	MaxBW int64
+
+	// InputBW is the input rate of the sender in bytes per second
+	// (SRTO_INPUTBW).
+	InputBW int64
+
+	// OheadBW is the recovery bandwidth overhead above the input
+	// rate in percent, from 5 to 100 (SRTO_OHEADBW).
+	OheadBW int
+
+	// MSS is the maximum segment size in bytes, from 76 to 1500
+	// (SRTO_MSS).
+	MSS int
+
+	// FlowControl is the flow control window size in packets, at
+	// least 32 (SRTO_FC).
+	FlowControl int
+
+	// SndBuf and RcvBuf are the buffer sizes in bytes (SRTO_SNDBUF,
+	// SRTO_RCVBUF).
+	SndBuf int
+	RcvBuf int
+
+	// PayloadSize is the maximum payload size of a packet in bytes
+	// (SRTO_PAYLOADSIZE).
+	PayloadSize int
+
+	// LossMaxTTL is the reorder tolerance in packets
+	// (SRTO_LOSSMAXTTL).
+	LossMaxTTL int
+
+	// StreamID is the stream id sent to the listener
+	// (SRTO_STREAMID). It is at most 512 bytes long.
+	StreamID string
+
+	// Congestion is the congestion controller, "live" or "file"
+	// (SRTO_CONGESTION).
+	Congestion string
+
+	// PacketFilter is the packet filter configuration, such as
+	// "fec,cols:10,rows:5" (SRTO_PACKETFILTER).
+	PacketFilter string
+}
+
+// ConfigError is returned when a socket option has an invalid value.
+type ConfigError struct {
+	// Field is the Config field or the option name.
+	Field string
+
+	// Value is the invalid value.
+	Value interface{}
+
+	// Err is the reason the value is invalid.
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %v", e.Field, fmt.Sprint(e.Value), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+const maxStreamIDLen = 512
+
+func checkRange(field string, v, min, max int) error {
+	if v < min || v > max {
+		return &ConfigError{Field: field, Value: v, Err: fmt.Errorf("out of range [%d, %d]", min, max)}
+	}
+	return nil
+}
+
+func checkDuration(field string, d time.Duration) error {
+	if d < 0 {
+		return &ConfigError{Field: field, Value: d, Err: errors.New("negative duration")}
+	}
+	if d%time.Millisecond != 0 {
+		return &ConfigError{Field: field, Value: d, Err: errors.New("not a multiple of a millisecond")}
+	}
+	return nil
+}
+
+// Validate reports the first field of c with an invalid value as a
+// *ConfigError.
+func (c *Config) Validate() error {
+	switch c.TransType {
+	case TransTypeDefault, TransTypeLive, TransTypeFile:
+	default:
+		return &ConfigError{Field: "TransType", Value: c.TransType, Err: errors.New("unknown transmission type")}
+	}
+	durations := []struct {
+		field string
+		d     time.Duration
+	}{
+		{"Latency", c.Latency},
+		{"RcvLatency", c.RcvLatency},
+		{"PeerLatency", c.PeerLatency},
+		{"ConnTimeout", c.ConnTimeout},
+		{"PeerIdleTimeout", c.PeerIdleTimeout},
+	}
+	for _, d := range durations {
+		if err := checkDuration(d.field, d.d); err != nil {
+			return err
+		}
+	}
+	if n := len(c.Passphrase); n != 0 && (n < 10 || n > 79) {
+		// do not leak the passphrase into error messages
+		return &ConfigError{Field: "Passphrase", Value: strings.Repeat("*", n), Err: errors.New("length must be 10 to 79 characters")}
+	}
+	switch c.PBKeyLen {
+	case 0, 16, 24, 32:
+	default:
+		return &ConfigError{Field: "PBKeyLen", Value: c.PBKeyLen, Err: errors.New("must be 16, 24 or 32")}
+	}
+	if c.KMRefreshRate < 0 {
+		return &ConfigError{Field: "KMRefreshRate", Value: c.KMRefreshRate, Err: errors.New("negative value")}
+	}
+	if c.KMPreAnnounce < 0 {
+		return &ConfigError{Field: "KMPreAnnounce", Value: c.KMPreAnnounce, Err: errors.New("negative value")}
+	}
+	if c.KMRefreshRate != 0 && c.KMPreAnnounce > c.KMRefreshRate/2 {
+		return &ConfigError{Field: "KMPreAnnounce", Value: c.KMPreAnnounce, Err: errors.New("greater than half of KMRefreshRate")}
+	}
+	if c.MaxBW < -1 {
+		return &ConfigError{Field: "MaxBW", Value: c.MaxBW, Err: errors.New("must be -1 or positive")}
+	}
+	if c.InputBW < 0 {
+		return &ConfigError{Field: "InputBW", Value: c.InputBW, Err: errors.New("negative value")}
+	}
+	if c.OheadBW != 0 {
+		if err := checkRange("OheadBW", c.OheadBW, 5, 100); err != nil {
+			return err
+		}
+	}
+	if c.MSS != 0 {
+		if err := checkRange("MSS", c.MSS, 76, 1500); err != nil {
+			return err
+		}
+	}
+	if c.FlowControl != 0 && c.FlowControl < 32 {
+		return &ConfigError{Field: "FlowControl", Value: c.FlowControl, Err: errors.New("less than 32")}
+	}
+	ints := []struct {
+		field string
+		v     int
+	}{
+		{"SndBuf", c.SndBuf},
+		{"RcvBuf", c.RcvBuf},
+		{"PayloadSize", c.PayloadSize},
+		{"LossMaxTTL", c.LossMaxTTL},
+	}
+	for _, i := range ints {
+		if i.v < 0 {
+			return &ConfigError{Field: i.field, Value: i.v, Err: errors.New("negative value")}
+		}
+	}
+	if len(c.StreamID) > maxStreamIDLen {
+		return &ConfigError{Field: "StreamID", Value: c.StreamID, Err: fmt.Errorf("longer than %d bytes", maxStreamIDLen)}
+	}
+	switch c.Congestion {
+	case "", "live", "file":
+	default:
+		return &ConfigError{Field: "Congestion", Value: c.Congestion, Err: errors.New(`must be "live" or "file"`)}
+	}
+	if c.PacketFilter != "" {
+		if err := validatePacketFilter(c.PacketFilter); err != nil {
+			return &ConfigError{Field: "PacketFilter", Value: c.PacketFilter, Err: err}
+		}
+	}
+	return nil
+}
+
+// validatePacketFilter checks the syntax of a packet filter
+// configuration: a filter type followed by comma separated key:value
+// parameters.
+func validatePacketFilter(s string) error {
+	parts := strings.Split(s, ",")
+	if parts[0] == "" || strings.Contains(parts[0], ":") {
+		return errors.New("missing filter type")
+	}
+	for _, p := range parts[1:] {
+		i := strings.Index(p, ":")
+		if i <= 0 || i == len(p)-1 {
+			return fmt.Errorf("malformed parameter %q", p)
+		}
+	}
+	return nil
+}
+
+func msString(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
+
+// options returns the string form of the non-zero fields of c.
+func (c *Config) options() OptionSet {
+	var o OptionSet
+	add := func(key, value string) {
+		o.list = append(o.list, option{key: key, value: value})
+	}
+	switch c.TransType {
+	case TransTypeLive:
+		add("transtype", strconv.Itoa(srtapi.TypeLive))
+	case TransTypeFile:
+		add("transtype", strconv.Itoa(srtapi.TypeFile))
+	}
+	durations := []struct {
+		key string
+		d   time.Duration
+	}{
+		{"latency", c.Latency},
+		{"rcvlatency", c.RcvLatency},
+		{"peerlatency", c.PeerLatency},
+		{"conntimeo", c.ConnTimeout},
+		{"peeridletimeo", c.PeerIdleTimeout},
+	}
+	for _, d := range durations {
+		if d.d != 0 {
+			add(d.key, msString(d.d))
+		}
+	}
+	ints := []struct {
+		key string
+		v   int64
+	}{
+		{"pbkeylen", int64(c.PBKeyLen)},
+		{"kmrefreshrate", int64(c.KMRefreshRate)},
+		{"kmpreannounce", int64(c.KMPreAnnounce)},
+		{"maxbw", c.MaxBW},
+		{"inputbw", c.InputBW},
+		{"oheadbw", int64(c.OheadBW)},
+		{"mss", int64(c.MSS)},
+		{"fc", int64(c.FlowControl)},
+		{"sndbuf", int64(c.SndBuf)},
+		{"rcvbuf", int64(c.RcvBuf)},
+		{"payloadsize", int64(c.PayloadSize)},
+		{"lossmaxttl", int64(c.LossMaxTTL)},
+	}
+	for _, i := range ints {
+		if i.v != 0 {
+			add(i.key, strconv.FormatInt(i.v, 10))
+		}
+	}
+	if c.AllowUnencrypted {
+		add("enforcedencryption", "false")
+	}
+	strs := []struct {
+		key, v string
+	}{
+		{"passphrase", c.Passphrase},
+		{"streamid", c.StreamID},
+		{"congestion", c.Congestion},
+		{"packetfilter", c.PacketFilter},
+	}
+	for _, s := range strs {
+		if s.v != "" {
+			add(s.key, s.v)
+		}
+	}
+	return o
+}
+
+// configContextKey is the type of contextKeys used for configs.
+type configContextKey struct{}
+
+// WithConfig returns a new context.Context with the options of c added.
+// The non-zero fields of c overwrite prior options with the same key.
+// c is validated when a socket is created with the context.
+func WithConfig(ctx context.Context, c *Config) context.Context {
+	parent, _ := ctx.Value(configContextKey{}).([]Config)
+	configs := make([]Config, len(parent), len(parent)+1)
+	copy(configs, parent)
+	configs = append(configs, *c)
+	ctx = context.WithValue(ctx, configContextKey{}, configs)
+	return WithOptions(ctx, c.options())
+}
+
+// validateContext validates the configs added to ctx with WithConfig.
+func validateContext(ctx context.Context) error {
+	configs, _ := ctx.Value(configContextKey{}).([]Config)
+	for i := range configs {
+		if err := configs[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
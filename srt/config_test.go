@@ -0,0 +1,136 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+var configValidateTests = []struct {
+	c     Config
+	field string // empty if valid
+}{
+	{Config{}, ""},
+	{Config{TransType: TransTypeFile, Congestion: "file", MaxBW: -1}, ""},
+	{Config{Latency: 400 * time.Millisecond, Passphrase: "0123456789", PBKeyLen: 16}, ""},
+	{Config{PacketFilter: "fec,cols:10,rows:5"}, ""},
+	{Config{KMRefreshRate: 1000, KMPreAnnounce: 500}, ""},
+
+	{Config{TransType: 3}, "TransType"},
+	{Config{Latency: -time.Millisecond}, "Latency"},
+	{Config{RcvLatency: 1500 * time.Microsecond}, "RcvLatency"},
+	{Config{ConnTimeout: -1}, "ConnTimeout"},
+	{Config{Passphrase: "short"}, "Passphrase"},
+	{Config{PBKeyLen: 20}, "PBKeyLen"},
+	{Config{KMRefreshRate: 1000, KMPreAnnounce: 501}, "KMPreAnnounce"},
+	{Config{MaxBW: -2}, "MaxBW"},
+	{Config{OheadBW: 4}, "OheadBW"},
+	{Config{MSS: 1501}, "MSS"},
+	{Config{FlowControl: 31}, "FlowControl"},
+	{Config{RcvBuf: -1}, "RcvBuf"},
+	{Config{StreamID: string(make([]byte, 513))}, "StreamID"},
+	{Config{Congestion: "flow"}, "Congestion"},
+	{Config{PacketFilter: "cols:10"}, "PacketFilter"},
+	{Config{PacketFilter: "fec,cols"}, "PacketFilter"},
+}
+
+func TestConfigValidate(t *testing.T) {
+	for i, tt := range configValidateTests {
+		err := tt.c.Validate()
+		if tt.field == "" {
+			if err != nil {
+				t.Errorf("#%d: %v", i, err)
+			}
+			continue
+		}
+		ce, ok := err.(*ConfigError)
+		if !ok {
+			t.Errorf("#%d: got %v; want *ConfigError", i, err)
+			continue
+		}
+		if ce.Field != tt.field {
+			t.Errorf("#%d: got field %s; want %s", i, ce.Field, tt.field)
+		}
+	}
+}
+
+func TestConfigOptions(t *testing.T) {
+	c := Config{
+		TransType:  TransTypeFile,
+		Latency:    400 * time.Millisecond,
+		MaxBW:      -1,
+		Passphrase: "0123456789",
+		StreamID:   "stream",
+	}
+	ctx := WithConfig(context.Background(), &c)
+	want := optionMap{
+		"transtype":  "1",
+		"latency":    "400",
+		"maxbw":      "-1",
+		"passphrase": "0123456789",
+		"streamid":   "stream",
+	}
+	if got := optionValue(ctx); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	// a later config overrides only its non-zero fields
+	ctx = WithConfig(ctx, &Config{Latency: 200 * time.Millisecond})
+	if v, _ := Option(ctx, "latency"); v != "200" {
+		t.Errorf("got latency %s; want 200", v)
+	}
+	if v, _ := Option(ctx, "streamid"); v != "stream" {
+		t.Errorf("got streamid %s; want stream", v)
+	}
+}
+
+func TestInvalidConfigDial(t *testing.T) {
+	d := Dialer{Config: &Config{PBKeyLen: 7}}
+	_, err := d.Dial("srt", "127.0.0.1:1")
+	if perr := parseDialError(err); perr != nil {
+		t.Fatal(perr)
+	}
+	if _, ok := err.(*OpError).Err.(*ConfigError); !ok {
+		t.Fatalf("got %v; want *ConfigError", err)
+	}
+
+	// a malformed string option is no longer ignored
+	ctx := WithOptions(context.Background(), Options("latency", "fast"))
+	_, err = ListenContext(ctx, "srt", "127.0.0.1:0")
+	if _, ok := err.(*ConfigError); !ok {
+		t.Fatalf("got %v; want *ConfigError", err)
+	}
+
+	// the passphrase is not shown
+	ctx = WithOptions(context.Background(), Options("passphrase", "secret"))
+	_, err = ListenContext(ctx, "srt", "127.0.0.1:0")
+	if ce, ok := err.(*ConfigError); !ok || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("got %v; want *ConfigError without the passphrase", err)
+	} else if ce.Value != "******" {
+		t.Errorf("got value %v; want ******", ce.Value)
+	}
+}
+
+func TestListenConfig(t *testing.T) {
+	lc := ListenConfig{Config: &Config{Latency: 300 * time.Millisecond}}
+	ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	fd := ln.(*SRTListener).fd.pfd.Sysfd
+	latency, err := srtapi.GetsockflagInt(fd, srtapi.OptionLatency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latency != 300 {
+		t.Errorf("got latency %d; want 300", latency)
+	}
+}
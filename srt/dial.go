@@ -15,6 +15,7 @@ import (
 
 	"github.com/xmedia-systems/gosrt/internal/nettrace"
 	"github.com/xmedia-systems/gosrt/internal/poll"
+	"github.com/xmedia-systems/gosrt/srt/streamid"
 )
 
 // A Dialer contains options for connecting to an address.
@@ -63,6 +64,22 @@ type Dialer struct {
 
 	// Resolver optionally specifies an alternate resolver to use.
 	Resolver *Resolver
+
+	// Rendezvous enables the SRT rendezvous mode. Both peers dial
+	// each other at the same time from a fixed local address and
+	// the connection is established when they meet; no side has to
+	// listen. LocalAddr must be a *SRTAddr with a non-zero port.
+	Rendezvous bool
+
+	// Config optionally specifies the socket options of the
+	// connection. It is applied on top of the options held by the
+	// context passed to DialContext.
+	Config *Config
+
+	// StreamID optionally specifies the stream ID sent to the
+	// listener. It overrides the "streamid" option and
+	// Config.StreamID.
+	StreamID *streamid.StreamID
 }
 
 func minNonzeroTime(a, b time.Time) time.Time {
@@ -230,6 +247,22 @@ func DialTimeout(network, address string, timeout time.Duration) (net.Conn, erro
 	return d.Dial(network, address)
 }
 
+// DialRendezvous connects to the address raddr on the named network
+// in rendezvous mode, using the provided context. The local address
+// laddr must have a non-zero port and the peer must dial back to it
+// at the same time.
+//
+// See func Dial for a description of the network and address
+// parameters.
+func DialRendezvous(ctx context.Context, network, laddr, raddr string) (net.Conn, error) {
+	la, err := ResolveSRTAddr(network, laddr)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	d := Dialer{LocalAddr: la, Rendezvous: true}
+	return d.DialContext(ctx, network, raddr)
+}
+
 // dialParam contains a Dial's parameters and configuration.
 type dialParam struct {
 	Dialer
@@ -275,6 +308,26 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 		}
 	}
 
+	if d.Rendezvous {
+		if la, ok := d.LocalAddr.(*SRTAddr); !ok || la.Port == 0 {
+			return nil, &OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: nil, Err: errRendezvousLocalAddr}
+		}
+		ctx = WithOptions(ctx, Options("rendezvous", "true"))
+	}
+
+	if d.Config != nil {
+		if err := d.Config.Validate(); err != nil {
+			return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		ctx = WithConfig(ctx, d.Config)
+	}
+	if d.StreamID != nil {
+		if err := d.StreamID.Validate(); err != nil {
+			return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		ctx = WithOptions(ctx, Options("streamid", d.StreamID.String()))
+	}
+
 	// Shadow the nettrace (if any) during resolve so Connect events don't fire for DNS lookups.
 	resolveCtx := ctx
 	if trace, _ := ctx.Value(nettrace.TraceKey{}).(*nettrace.Trace); trace != nil {
@@ -461,6 +514,28 @@ func dialSingle(ctx context.Context, dp *dialParam, ra net.Addr) (c net.Conn, er
 	return c, nil
 }
 
+// ListenConfig contains options for listening to an address.
+type ListenConfig struct {
+	// Config optionally specifies the socket options of the
+	// listener and of the accepted connections. It is applied on
+	// top of the options held by the context passed to Listen.
+	Config *Config
+}
+
+// Listen announces on the local network address.
+//
+// See func ListenContext for a description of the network and address
+// parameters.
+func (lc *ListenConfig) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	if lc.Config != nil {
+		if err := lc.Config.Validate(); err != nil {
+			return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		ctx = WithConfig(ctx, lc.Config)
+	}
+	return ListenContext(ctx, network, address)
+}
+
 // Listen announces on the local network address.
 func Listen(network, address string) (net.Listener, error) {
 	return ListenContext(context.Background(), network, address)
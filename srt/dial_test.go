@@ -78,6 +78,7 @@ func TestDialLocal(t *testing.T) {
 }
 
 func TestDialerDualStackFDLeak(t *testing.T) {
+	mustHaveDualStack(t)
 	if testing.Short() {
 		t.Skipf("skipping test: not supported yet")
 	}
@@ -193,6 +194,7 @@ func dialClosedPort() (actual, expected time.Duration) {
 }
 
 func TestDialParallel(t *testing.T) {
+	mustHaveDualStack(t)
 	testenv.MustHaveExternalNetwork(t)
 
 	if !supportsIPv4() || !supportsIPv6() {
@@ -364,6 +366,7 @@ func lookupSlowFast(ctx context.Context, fn func(context.Context, string) ([]net
 }
 
 func TestDialerFallbackDelay(t *testing.T) {
+	mustHaveDualStack(t)
 	testenv.MustHaveExternalNetwork(t)
 
 	if !supportsIPv4() || !supportsIPv6() {
@@ -432,6 +435,7 @@ func TestDialerFallbackDelay(t *testing.T) {
 }
 
 func TestDialParallelSpuriousConnection(t *testing.T) {
+	mustHaveDualStack(t)
 	if testing.Short() {
 		t.Skipf("skipping test: not supported yet")
 	}
@@ -563,3 +567,78 @@ func TestDialListenerAddr(t *testing.T) {
 	}
 	c.Close()
 }
+
+// freeUDPPort returns a port which was free on the loopback
+// interface at the time of the call.
+func freeUDPPort(t *testing.T) int {
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	return c.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestDialRendezvous(t *testing.T) {
+	if !supportsIPv4() {
+		t.Skip("ipv4 is not supported")
+	}
+	addrs := []string{
+		net.JoinHostPort("127.0.0.1", itoa(freeUDPPort(t))),
+		net.JoinHostPort("127.0.0.1", itoa(freeUDPPort(t))),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), someTimeout)
+	defer cancel()
+
+	type result struct {
+		c   net.Conn
+		err error
+	}
+	ch := make(chan result, 2)
+	for i := range addrs {
+		go func(laddr, raddr string) {
+			c, err := DialRendezvous(ctx, "srt", laddr, raddr)
+			ch <- result{c, err}
+		}(addrs[i], addrs[1-i])
+	}
+	var cs []net.Conn
+	for range addrs {
+		r := <-ch
+		if r.err != nil {
+			t.Error(r.err)
+			continue
+		}
+		defer r.c.Close()
+		cs = append(cs, r.c)
+	}
+	if len(cs) != 2 {
+		t.FailNow()
+	}
+
+	msg := []byte("RENDEZVOUS TEST")
+	if _, err := cs[0].Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	cs[1].SetReadDeadline(time.Now().Add(someTimeout))
+	b := make([]byte, 128)
+	n, err := cs[1].Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != string(msg) {
+		t.Errorf("got %q; want %q", b[:n], msg)
+	}
+}
+
+func TestDialRendezvousWithoutLocalAddr(t *testing.T) {
+	d := Dialer{Rendezvous: true}
+	c, err := d.Dial("srt", "127.0.0.1:5000")
+	if err == nil {
+		c.Close()
+		t.Fatal("should fail")
+	}
+	if perr := parseDialError(err); perr != nil {
+		t.Error(perr)
+	}
+}
@@ -70,7 +70,7 @@ second:
 		return nil
 	}
 	switch err := nestedErr.(type) {
-	case *net.AddrError, *net.DNSError, net.InvalidAddrError, *net.ParseError, *poll.TimeoutError, net.UnknownNetworkError:
+	case *net.AddrError, *net.DNSError, net.InvalidAddrError, *net.ParseError, *poll.TimeoutError, net.UnknownNetworkError, *RejectError, *ConfigError:
 		return nil
 	case *os.SyscallError:
 		nestedErr = err.Err
@@ -81,7 +81,7 @@ second:
 	}
 	switch nestedErr {
 	case errCanceled, poll.ErrNetClosing, errMissingAddress, errNoSuitableAddress,
-		context.DeadlineExceeded, context.Canceled:
+		errRendezvousLocalAddr, context.DeadlineExceeded, context.Canceled:
 		return nil
 	}
 	return fmt.Errorf("unexpected type on 2nd nested level: %T", nestedErr)
@@ -343,6 +343,8 @@ second:
 		return nil
 	}
 	switch err := nestedErr.(type) {
+	case *poll.PollerError:
+		return nil
 	case *os.SyscallError:
 		nestedErr = err.Err
 		goto third
@@ -383,7 +385,7 @@ second:
 		return nil
 	}
 	switch err := nestedErr.(type) {
-	case *net.AddrError, *net.DNSError, net.InvalidAddrError, *net.ParseError, *poll.TimeoutError, net.UnknownNetworkError:
+	case *net.AddrError, *net.DNSError, net.InvalidAddrError, *net.ParseError, *poll.TimeoutError, net.UnknownNetworkError, *poll.PollerError:
 		return nil
 	case *os.SyscallError:
 		nestedErr = err.Err
@@ -506,6 +508,8 @@ second:
 		return nil
 	}
 	switch err := nestedErr.(type) {
+	case *poll.PollerError:
+		return nil
 	case *os.SyscallError:
 		nestedErr = err.Err
 		goto third
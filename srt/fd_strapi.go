@@ -57,21 +57,50 @@ func (fd *netFD) setAddr(laddr, raddr net.Addr) {
 
 func (fd *netFD) connect(ctx context.Context, la, ra syscall.Sockaddr) (rsa syscall.Sockaddr, ret error) {
 	if err := connectFunc(fd.pfd.Sysfd, ra); err != nil {
+		if err == srtapi.ECONNREJ {
+			if rerr := fd.rejectError(); rerr != nil {
+				return nil, rerr
+			}
+		}
 		return nil, os.NewSyscallError("connect", err)
 	}
+	return nil, fd.waitConnect(ctx, fd.connectState)
+}
+
+// connectState reports whether the connection of fd has been
+// established.
+func (fd *netFD) connectState() (bool, error) {
 	state, err := getsockoptIntFunc(fd.pfd.Sysfd, 0, srtapi.OptionState)
 	if err != nil {
-		return nil, os.NewSyscallError("getsockopt", err)
+		return false, os.NewSyscallError("getsockopt", err)
 	}
 	switch state {
 	case srtapi.StatusConnecting:
+		return false, nil
 	case srtapi.StatusConnected:
-		return nil, nil
-	default:
-		return nil, fmt.Errorf("unexpected socket state %d", state)
+		return true, nil
+	case srtapi.StatusBroken, srtapi.StatusClosing, srtapi.StatusClosed, srtapi.StatusNonexist:
+		if rerr := fd.rejectError(); rerr != nil {
+			return false, rerr
+		}
+		// the connection failed, or was closed by the peer before it
+		// was seen established
+		if state == srtapi.StatusBroken {
+			return false, os.NewSyscallError("connect", srtapi.ECONNLOST)
+		}
+		return false, os.NewSyscallError("connect", srtapi.ENOCONN)
+	}
+	return false, fmt.Errorf("unexpected socket state %d", state)
+}
+
+// waitConnect waits until connected reports that the connection
+// started on fd has been established, ctx is done or an error occurs.
+func (fd *netFD) waitConnect(ctx context.Context, connected func() (bool, error)) (ret error) {
+	if ok, err := connected(); ok || err != nil {
+		return err
 	}
 	if err := fd.pfd.Init(fd.net, true); err != nil {
-		return nil, err
+		return err
 	}
 	if deadline, _ := ctx.Deadline(); !deadline.IsZero() {
 		fd.pfd.SetWriteDeadline(deadline)
@@ -121,21 +150,13 @@ func (fd *netFD) connect(ctx context.Context, la, ra syscall.Sockaddr) (rsa sysc
 		if err := fd.pfd.WaitWrite(); err != nil {
 			select {
 			case <-ctx.Done():
-				return nil, mapErr(ctx.Err())
+				return mapErr(ctx.Err())
 			default:
 			}
-			return nil, err
+			return err
 		}
-		state, err := getsockoptIntFunc(fd.pfd.Sysfd, 0, srtapi.OptionState)
-		if err != nil {
-			return nil, os.NewSyscallError("getsockopt", err)
-		}
-		switch state {
-		case srtapi.StatusConnecting:
-		case srtapi.StatusConnected:
-			return nil, nil
-		default:
-			return nil, fmt.Errorf("unexpected socket state %d", state)
+		if ok, err := connected(); ok || err != nil {
+			return err
 		}
 	}
 }
@@ -155,6 +176,16 @@ func (fd *netFD) Write(p []byte) (nn int, err error) {
 	return nn, wrapSyscallError("write", err)
 }
 
+func (fd *netFD) readMsg(p []byte, mctrl *srtapi.MsgCtrl) (n int, err error) {
+	n, err = fd.pfd.ReadMsg(p, mctrl)
+	return n, wrapSyscallError("recvmsg2", err)
+}
+
+func (fd *netFD) writeMsg(p []byte, mctrl *srtapi.MsgCtrl) (n int, err error) {
+	n, err = fd.pfd.WriteMsg(p, mctrl)
+	return n, wrapSyscallError("sendmsg2", err)
+}
+
 func (fd *netFD) accept() (netfd *netFD, err error) {
 	d, rsa, errcall, err := fd.pfd.Accept()
 	if err != nil {
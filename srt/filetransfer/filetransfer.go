@@ -0,0 +1,225 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package filetransfer transfers files over SRT connections in file
+// mode, resuming interrupted transfers and verifying the files with
+// SHA-256.
+//
+// The connections must be streams, as made by srt.TransTypeFile:
+//
+//	ctx = srt.WithConfig(ctx, &srt.Config{TransType: srt.TransTypeFile})
+//
+// A transfer starts with the sender offering a file with its name, size
+// and digest. The receiver replies with the offset up to which it holds
+// the file from an interrupted transfer of the same file, and the
+// sender sends the rest. The receiver then verifies the digest of the
+// whole file and acknowledges it. A Sender resumes a broken transfer on
+// a new connection, and a Receiver keeps the part of a file received so
+// far until the transfer is resumed.
+//
+// All messages have the same layout, with integers in big-endian
+// order:
+//
+//	magic   [4]byte  "GSFT"
+//	version uint8    1
+//	kind    uint8    1 offer, 2 reply, 3 acknowledgement
+//	status  uint8    see Status
+//	namelen uint16
+//	name    [namelen]byte
+//	size    uint64
+//	offset  uint64
+//	digest  [32]byte SHA-256 of the whole file
+package filetransfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/internal/deadline"
+)
+
+const (
+	magic   = "GSFT"
+	version = 1
+
+	// MaxNameLen is the maximum length of a file name.
+	MaxNameLen = 255
+)
+
+type kind uint8
+
+const (
+	kindOffer kind = iota + 1
+	kindReply
+	kindAck
+)
+
+// Status is the answer of a receiver to an offer or a transfer.
+type Status uint8
+
+// Statuses
+const (
+	// StatusOK accepts an offer or acknowledges a verified file.
+	StatusOK Status = iota
+
+	// StatusInvalid refuses an offer with an invalid name or size.
+	StatusInvalid
+
+	// StatusBusy refuses an offer of a file being received on another
+	// connection.
+	StatusBusy
+
+	// StatusFailed reports that the receiver could not store the file.
+	StatusFailed
+
+	// StatusDigestMismatch reports that the file received does not
+	// have the digest offered. The receiver discards it.
+	StatusDigestMismatch
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusInvalid:
+		return "invalid offer"
+	case StatusBusy:
+		return "busy"
+	case StatusFailed:
+		return "failed"
+	case StatusDigestMismatch:
+		return "digest mismatch"
+	}
+	return "Status(" + strconv.Itoa(int(s)) + ")"
+}
+
+// A StatusError is returned when the receiver refuses or fails a
+// transfer.
+type StatusError struct {
+	Status Status
+}
+
+func (e *StatusError) Error() string {
+	return "filetransfer: receiver: " + e.Status.String()
+}
+
+// Temporary reports whether the transfer can succeed when it is tried
+// again.
+func (e *StatusError) Temporary() bool {
+	return e.Status != StatusInvalid
+}
+
+var errProtocol = errors.New("filetransfer: protocol error")
+
+// Header describes a file being transferred.
+type Header struct {
+	// Name is the name of the file at the receiver, without directory.
+	Name string
+
+	// Size is the size of the file, and Offset the start of the data
+	// sent in the transfer.
+	Size   int64
+	Offset int64
+
+	// Digest is the SHA-256 digest of the whole file.
+	Digest [sha256.Size]byte
+}
+
+func (h *Header) same(o *Header) bool {
+	return h.Name == o.Name && h.Size == o.Size && h.Digest == o.Digest
+}
+
+// ValidName reports whether name can be the name of a file at a
+// receiver: a non-empty file name without directory.
+func ValidName(name string) bool {
+	return name != "" && len(name) <= MaxNameLen && name != "." && name != ".." &&
+		!strings.ContainsAny(name, "/\\\x00") && filepath.Base(name) == name
+}
+
+// A message is made of a head up to the name length, the name and a
+// tail.
+const (
+	headLen = len(magic) + 3 + 2
+	tailLen = 8 + 8 + sha256.Size
+)
+
+func writeMessage(w io.Writer, k kind, st Status, h *Header) error {
+	if len(h.Name) > MaxNameLen {
+		return fmt.Errorf("filetransfer: name longer than %d bytes", MaxNameLen)
+	}
+	b := make([]byte, 0, headLen+len(h.Name)+tailLen)
+	b = append(b, magic...)
+	b = append(b, version, byte(k), byte(st))
+	var n [8]byte
+	binary.BigEndian.PutUint16(n[:], uint16(len(h.Name)))
+	b = append(b, n[:2]...)
+	b = append(b, h.Name...)
+	binary.BigEndian.PutUint64(n[:], uint64(h.Size))
+	b = append(b, n[:]...)
+	binary.BigEndian.PutUint64(n[:], uint64(h.Offset))
+	b = append(b, n[:]...)
+	b = append(b, h.Digest[:]...)
+	_, err := w.Write(b)
+	return err
+}
+
+func readMessage(r io.Reader) (k kind, st Status, h Header, err error) {
+	b := make([]byte, headLen)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return
+	}
+	if string(b[:4]) != magic {
+		err = errProtocol
+		return
+	}
+	if b[4] != version {
+		err = fmt.Errorf("filetransfer: unsupported version %d", b[4])
+		return
+	}
+	k, st = kind(b[5]), Status(b[6])
+	n := int(binary.BigEndian.Uint16(b[7:]))
+	if n > MaxNameLen {
+		err = errProtocol
+		return
+	}
+	b = make([]byte, n+tailLen)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return
+	}
+	h.Name = string(b[:n])
+	h.Size = int64(binary.BigEndian.Uint64(b[n:]))
+	h.Offset = int64(binary.BigEndian.Uint64(b[n+8:]))
+	copy(h.Digest[:], b[n+16:])
+	if h.Size < 0 || h.Offset < 0 || h.Offset > h.Size {
+		err = errProtocol
+	}
+	return
+}
+
+// deadliner is implemented by net.Conn and *srt.SRTListener.
+type deadliner interface {
+	SetDeadline(t time.Time) error
+}
+
+// interrupt makes the pending and future I/O of d fail when ctx is
+// done, until stop is called. stop returns once d is left alone, so
+// that it can be closed.
+func interrupt(ctx context.Context, d deadliner) (stop func()) {
+	return deadline.Interrupt(ctx, d.SetDeadline)
+}
+
+// ctxErr returns the error of ctx if it is done, and err otherwise.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package filetransfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+const someTimeout = 10 * time.Second
+
+func TestMessage(t *testing.T) {
+	h := Header{Name: "a.mxf", Size: 1 << 40, Offset: 12345}
+	h.Digest[0], h.Digest[31] = 1, 2
+	var buf bytes.Buffer
+	if err := writeMessage(&buf, kindReply, StatusBusy, &h); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	k, st, got, err := readMessage(bytes.NewReader(b))
+	if err != nil || k != kindReply || st != StatusBusy || got != h {
+		t.Errorf("got %v, %v, %+v, %v; want %v, %v, %+v", k, st, got, err, kindReply, StatusBusy, h)
+	}
+
+	for _, tt := range []struct {
+		name string
+		b    []byte
+	}{
+		{"magic", append([]byte("XXXX"), b[4:]...)},
+		{"version", append(append([]byte(nil), b[:4]...), append([]byte{2}, b[5:]...)...)},
+		{"short", b[:len(b)-1]},
+		{"offset", func() []byte {
+			h := h
+			h.Offset = h.Size + 1
+			var buf bytes.Buffer
+			writeMessage(&buf, kindOffer, StatusOK, &h)
+			return buf.Bytes()
+		}()},
+	} {
+		if _, _, _, err := readMessage(bytes.NewReader(tt.b)); err == nil {
+			t.Errorf("%s: got nil error", tt.name)
+		}
+	}
+	if err := writeMessage(&buf, kindOffer, StatusOK, &Header{Name: strings.Repeat("a", MaxNameLen+1)}); err == nil {
+		t.Error("got nil error for a long name")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"a.mxf":                           true,
+		".hidden":                         true,
+		"":                                false,
+		".":                               false,
+		"..":                              false,
+		"../a.mxf":                        false,
+		"dir/a.mxf":                       false,
+		`dir\a.mxf`:                       false,
+		"a\x00":                           false,
+		"/a.mxf":                          false,
+		strings.Repeat("a", MaxNameLen+1): false,
+	} {
+		if got := ValidName(name); got != want {
+			t.Errorf("ValidName(%q) = %v; want %v", name, got, want)
+		}
+	}
+}
+
+// setup returns a directory with a random file of size bytes, and a
+// Receiver serving an SRT listener in a directory of its own.
+func setup(t *testing.T, size int) (src string, r *Receiver, addr string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gosrt-filetransfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	src = filepath.Join(dir, "src.mxf")
+	if err := ioutil.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r = &Receiver{Dir: filepath.Join(dir, "dst"), Logf: t.Logf}
+	if err := os.Mkdir(r.Dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := srt.ListenContext(fileMode(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Serve(ctx, ln)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return src, r, ln.Addr().String()
+}
+
+func fileMode() context.Context {
+	return srt.WithConfig(context.Background(), &srt.Config{TransType: srt.TransTypeFile})
+}
+
+// checkReceived checks that the receiver stored the file at src as name
+// and no part of it.
+func checkReceived(t *testing.T, src string, r *Receiver, name string) {
+	t.Helper()
+	want, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(r.Dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes differing from the %d bytes sent", len(got), len(want))
+	}
+	parts, _ := filepath.Glob(filepath.Join(r.Dir, "*.part"))
+	if len(parts) != 0 {
+		t.Errorf("got parts %q left", parts)
+	}
+}
+
+func TestSendFile(t *testing.T) {
+	src, r, addr := setup(t, 3<<20)
+	s := &Sender{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			var d srt.Dialer
+			return d.DialContext(fileMode(), "srt", addr)
+		},
+		Logf: t.Logf,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), someTimeout)
+	defer cancel()
+	if err := s.SendFile(ctx, src, "a.mxf"); err != nil {
+		t.Fatal(err)
+	}
+	checkReceived(t, src, r, "a.mxf")
+
+	if err := s.SendFile(ctx, src, "../a.mxf"); err == nil {
+		t.Error("got nil error for an invalid name")
+	}
+}
+
+var errBroken = errors.New("connection broken by the test")
+
+// breakingConn closes its connection after writing budget bytes.
+type breakingConn struct {
+	net.Conn
+	budget  int64
+	written *int64
+	once    sync.Once
+}
+
+func (c *breakingConn) Write(b []byte) (int, error) {
+	if int64(len(b)) <= c.budget {
+		n, err := c.Conn.Write(b)
+		c.budget -= int64(n)
+		*c.written += int64(n)
+		return n, err
+	}
+	n, _ := c.Conn.Write(b[:c.budget])
+	*c.written += int64(n)
+	c.Close()
+	return n, errBroken
+}
+
+func (c *breakingConn) Close() error {
+	c.once.Do(func() { c.Conn.Close() })
+	return nil
+}
+
+func TestResume(t *testing.T) {
+	const size = 3 << 20
+	src, r, addr := setup(t, size)
+	var (
+		budgets = []int64{1 << 20, 1 << 20, 1 << 62}
+		written int64
+		retries int
+	)
+	s := &Sender{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			var d srt.Dialer
+			c, err := d.DialContext(fileMode(), "srt", addr)
+			if err != nil {
+				return nil, err
+			}
+			budget := budgets[0]
+			if len(budgets) > 1 {
+				budgets = budgets[1:]
+			}
+			return &breakingConn{Conn: c, budget: budget, written: &written}, nil
+		},
+		Retries:    10,
+		RetryDelay: 10 * time.Millisecond,
+		Logf: func(format string, v ...interface{}) {
+			retries++
+			t.Logf(format, v...)
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), someTimeout)
+	defer cancel()
+	if err := s.SendFile(ctx, src, "a.mxf"); err != nil {
+		t.Fatal(err)
+	}
+	checkReceived(t, src, r, "a.mxf")
+	if retries < 2 {
+		t.Errorf("got %d retries; want at least 2", retries)
+	}
+	// The messages make a few more bytes; resending the file from the
+	// start would make at least 2 MiB more.
+	if written > size+64<<10 {
+		t.Errorf("wrote %d bytes for a file of %d bytes", written, size)
+	}
+
+	s.Retries = 0
+	budgets = []int64{1 << 10}
+	if err := s.SendFile(ctx, src, "b.mxf"); err != errBroken {
+		t.Errorf("got %v; want %v without retries", err, errBroken)
+	}
+}
+
+func TestDigestMismatch(t *testing.T) {
+	src, r, addr := setup(t, 100000)
+	var d srt.Dialer
+	c, err := d.DialContext(fileMode(), "srt", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	f, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h := &Header{Name: "a.mxf", Size: 100000, Digest: sha256.Sum256([]byte("other"))}
+	ctx, cancel := context.WithTimeout(context.Background(), someTimeout)
+	defer cancel()
+	_, err = Send(ctx, c, f, h)
+	if se, ok := err.(*StatusError); !ok || se.Status != StatusDigestMismatch {
+		t.Errorf("got %v; want a digest mismatch", err)
+	}
+	files, _ := ioutil.ReadDir(r.Dir)
+	if len(files) != 0 {
+		t.Errorf("got %d files stored; want none", len(files))
+	}
+}
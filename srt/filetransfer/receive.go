@@ -0,0 +1,215 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package filetransfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// copyBufferSize is the size of the buffer the data of a transfer is
+// received in.
+const copyBufferSize = 256 << 10
+
+// A Receiver stores the files sent to it in a directory. The part of a
+// file received so far is kept in the directory until the transfer is
+// resumed, in a file named after the file and its digest with the
+// suffix ".part".
+type Receiver struct {
+	// Dir is the directory the files are stored in.
+	Dir string
+
+	// Logf, if not nil, logs the transfers.
+	Logf func(format string, v ...interface{})
+
+	mu     sync.Mutex
+	active map[string]bool // names of the files being received
+}
+
+func (r *Receiver) logf(format string, v ...interface{}) {
+	if r.Logf != nil {
+		r.Logf(format, v...)
+	}
+}
+
+// partPath returns the path of the part of the file of h received so
+// far.
+func (r *Receiver) partPath(h *Header) string {
+	return filepath.Join(r.Dir, h.Name+"."+hex.EncodeToString(h.Digest[:8])+".part")
+}
+
+func (r *Receiver) acquire(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active[name] {
+		return false
+	}
+	if r.active == nil {
+		r.active = make(map[string]bool)
+	}
+	r.active[name] = true
+	return true
+}
+
+func (r *Receiver) release(name string) {
+	r.mu.Lock()
+	delete(r.active, name)
+	r.mu.Unlock()
+}
+
+// Receive receives a file on c, resuming a previous transfer of the
+// same file. It returns the header of the transfer when the file is
+// verified and stored, or when the transfer fails after the offer was
+// read.
+func (r *Receiver) Receive(ctx context.Context, c net.Conn) (*Header, error) {
+	defer interrupt(ctx, c)()
+	k, _, h, err := readMessage(c)
+	if err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	if k != kindOffer {
+		return nil, errProtocol
+	}
+	if !ValidName(h.Name) {
+		writeMessage(c, kindReply, StatusInvalid, &h)
+		return &h, &StatusError{Status: StatusInvalid}
+	}
+	if !r.acquire(h.Name) {
+		writeMessage(c, kindReply, StatusBusy, &h)
+		return &h, &StatusError{Status: StatusBusy}
+	}
+	defer r.release(h.Name)
+
+	part := r.partPath(&h)
+	f, digest, err := r.resume(part, &h)
+	if err != nil {
+		writeMessage(c, kindReply, StatusFailed, &h)
+		return &h, err
+	}
+	if err := writeMessage(c, kindReply, StatusOK, &h); err != nil {
+		f.Close()
+		return &h, ctxErr(ctx, err)
+	}
+	if h.Offset > 0 {
+		r.logf("%s: resuming at %d of %d bytes", h.Name, h.Offset, h.Size)
+	}
+
+	w := io.MultiWriter(f, digest)
+	n, err := io.CopyBuffer(w, io.LimitReader(c, h.Size-h.Offset), make([]byte, copyBufferSize))
+	if err == nil && n < h.Size-h.Offset {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		// The part received is kept for the next transfer.
+		f.Close()
+		return &h, ctxErr(ctx, err)
+	}
+
+	st := StatusOK
+	if !bytes.Equal(digest.Sum(nil), h.Digest[:]) {
+		st, err = StatusDigestMismatch, &StatusError{Status: StatusDigestMismatch}
+	} else {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case st == StatusDigestMismatch:
+		os.Remove(part)
+	case err == nil:
+		err = os.Rename(part, filepath.Join(r.Dir, h.Name))
+	}
+	if err != nil && st == StatusOK {
+		st = StatusFailed
+	}
+	if werr := writeMessage(c, kindAck, st, &h); werr != nil && err == nil {
+		err = ctxErr(ctx, werr)
+	}
+	return &h, err
+}
+
+// resume opens the part of the file of h and sets h.Offset to its
+// size. The returned digest holds the content of the part.
+func (r *Receiver) resume(part string, h *Header) (*os.File, hash.Hash, error) {
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	h.Offset = fi.Size()
+	if h.Offset > h.Size {
+		// not a part of this file
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		h.Offset = 0
+	}
+	digest := sha256.New()
+	if _, err := io.CopyN(digest, f, h.Offset); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("filetransfer: %s: %v", part, err)
+	}
+	if _, err := f.Seek(h.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, digest, nil
+}
+
+// Serve accepts connections on ln and receives a file on each of them,
+// until accepting fails or ctx is done. ln is closed when Serve
+// returns.
+func (r *Receiver) Serve(ctx context.Context, ln net.Listener) error {
+	defer ln.Close()
+	if d, ok := ln.(deadliner); ok {
+		defer interrupt(ctx, d)()
+	} else {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				ln.Close()
+			case <-stop:
+			}
+		}()
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return ctxErr(ctx, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer c.Close()
+			h, err := r.Receive(ctx, c)
+			switch {
+			case err != nil && h != nil:
+				r.logf("%s from %v: %v", h.Name, c.RemoteAddr(), err)
+			case err != nil:
+				r.logf("%v: %v", c.RemoteAddr(), err)
+			default:
+				r.logf("%s from %v: received %d bytes", h.Name, c.RemoteAddr(), h.Size)
+			}
+		}()
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build srtmock
+
+package filetransfer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// TestResumeSendfile breaks the SRT connections themselves in the middle
+// of the file, so that each attempt sends the file with srt_sendfile
+// from the offset the receiver resumes at.
+func TestResumeSendfile(t *testing.T) {
+	// srt_sendfile is called for 4 MiB at a time; the second call of
+	// the first two connections fails as if the peer was lost.
+	const size = 10 << 20
+	var (
+		mu     sync.Mutex
+		calls  = make(map[int]int)
+		broken int
+	)
+	srtapi.SetMockNetwork(&srtapi.MockNetwork{
+		Fail: func(call string, id int) error {
+			if call != "srt_sendfile" {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			calls[id]++
+			if calls[id] == 2 && broken < 2 {
+				broken++
+				return srtapi.ECONNLOST
+			}
+			return nil
+		},
+	})
+	defer srtapi.SetMockNetwork(nil)
+
+	src, r, addr := setup(t, size)
+	var resumed []string
+	r.Logf = func(format string, v ...interface{}) {
+		if msg := fmt.Sprintf(format, v...); strings.Contains(msg, "resuming") {
+			mu.Lock()
+			resumed = append(resumed, msg)
+			mu.Unlock()
+		}
+	}
+	s := &Sender{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			var d srt.Dialer
+			return d.DialContext(fileMode(), "srt", addr)
+		},
+		Retries:    10,
+		RetryDelay: 10 * time.Millisecond,
+		Logf:       t.Logf,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), someTimeout)
+	defer cancel()
+	if err := s.SendFile(ctx, src, "a.mxf"); err != nil {
+		t.Fatal(err)
+	}
+	checkReceived(t, src, r, "a.mxf")
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		fmt.Sprintf("a.mxf: resuming at %d of %d bytes", 4<<20, size),
+		fmt.Sprintf("a.mxf: resuming at %d of %d bytes", 8<<20, size),
+	}
+	if fmt.Sprint(resumed) != fmt.Sprint(want) {
+		t.Errorf("got %q; want %q", resumed, want)
+	}
+	// Resending from the start would call srt_sendfile 3 times on the
+	// last connection.
+	n := 0
+	for _, c := range calls {
+		n += c
+	}
+	if n != 5 {
+		t.Errorf("got %d calls of srt_sendfile; want 5", n)
+	}
+}
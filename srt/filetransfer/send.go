@@ -0,0 +1,143 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package filetransfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// Digest returns the SHA-256 digest of the content of f from its
+// start.
+func Digest(f *os.File) (d [sha256.Size]byte, err error) {
+	h := sha256.New()
+	if _, err = io.Copy(h, io.NewSectionReader(f, 0, 1<<62)); err != nil {
+		return
+	}
+	copy(d[:], h.Sum(nil))
+	return
+}
+
+// Send offers f to the receiver at the other end of c as described by
+// h, sends the part of f the receiver does not hold yet and waits for
+// the receiver to verify the file. h.Offset is set to the offset the
+// transfer resumed at. Send returns the number of bytes of f sent.
+//
+// If the receiver refuses or fails the transfer, the error is a
+// *StatusError.
+func Send(ctx context.Context, c net.Conn, f *os.File, h *Header) (sent int64, err error) {
+	defer interrupt(ctx, c)()
+	h.Offset = 0
+	if err := writeMessage(c, kindOffer, StatusOK, h); err != nil {
+		return 0, ctxErr(ctx, err)
+	}
+	k, st, reply, err := readMessage(c)
+	if err != nil {
+		return 0, ctxErr(ctx, err)
+	}
+	if k != kindReply || st == StatusOK && !reply.same(h) {
+		return 0, errProtocol
+	}
+	if st != StatusOK {
+		return 0, &StatusError{Status: st}
+	}
+	h.Offset = reply.Offset
+
+	// c sends the rest of the file with srt_sendfile.
+	n := h.Size - h.Offset
+	if _, err := f.Seek(h.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	sent, err = io.Copy(c, io.LimitReader(f, n))
+	if err != nil {
+		return sent, ctxErr(ctx, err)
+	}
+	if sent < n {
+		return sent, fmt.Errorf("filetransfer: %s: file shorter than %d bytes", f.Name(), h.Size)
+	}
+
+	k, st, _, err = readMessage(c)
+	if err != nil {
+		return sent, ctxErr(ctx, err)
+	}
+	if k != kindAck {
+		return sent, errProtocol
+	}
+	if st != StatusOK {
+		return sent, &StatusError{Status: st}
+	}
+	return sent, nil
+}
+
+// A Sender sends files, resuming broken transfers on new connections.
+type Sender struct {
+	// Dial opens a connection to the receiver in file mode.
+	Dial func(ctx context.Context) (net.Conn, error)
+
+	// Retries is the number of times a failed transfer is tried
+	// again, and RetryDelay the delay before each retry.
+	Retries    int
+	RetryDelay time.Duration
+
+	// Logf, if not nil, logs the failed attempts.
+	Logf func(format string, v ...interface{})
+}
+
+// SendFile sends the file at path to the receiver as name. It returns
+// the error of the last attempt if all of them fail.
+func (s *Sender) SendFile(ctx context.Context, path, name string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("filetransfer: invalid name %q", name)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	h := Header{Name: name, Size: fi.Size()}
+	if h.Digest, err = Digest(f); err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err = s.send(ctx, f, &h)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if se, ok := err.(*StatusError); ok && !se.Temporary() {
+			return err
+		}
+		if attempt == s.Retries {
+			return err
+		}
+		if s.Logf != nil {
+			s.Logf("%s: %v; retrying in %v", name, err, s.RetryDelay)
+		}
+		timer := time.NewTimer(s.RetryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Sender) send(ctx context.Context, f *os.File, h *Header) error {
+	c, err := s.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = Send(ctx, c, f, h)
+	return err
+}
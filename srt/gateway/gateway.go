@@ -0,0 +1,231 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package gateway forwards MPEG-TS between plain UDP and SRT.
+//
+// Encoders usually send MPEG-TS over UDP, with or without RTP, in
+// datagrams of 7 TS packets. A Gateway reads such a stream from a UDP
+// socket, strips the RTP headers and writes the TS packets to an SRT
+// connection in chunks of whole packets, and forwards the messages of
+// an SRT connection to a UDP address the same way:
+//
+//	pc, err := net.ListenPacket("udp", "127.0.0.1:5000")
+//	...
+//	c, err := srt.Dial("srt", "example.com:9000")
+//	...
+//	var g gateway.Gateway
+//	err = g.UDPToSRT(ctx, pc, c)
+//
+// The stream is checked for the TS sync byte at the start of every
+// packet. When the sync is lost, the data is discarded until it is
+// found again, so that only whole TS packets are forwarded.
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/internal/deadline"
+)
+
+// Direction is a forwarding direction of a Gateway.
+type Direction int
+
+// Forwarding directions
+const (
+	// Ingress forwards from UDP to SRT.
+	Ingress Direction = iota
+
+	// Egress forwards from SRT to UDP.
+	Egress
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Ingress:
+		return "ingress"
+	case Egress:
+		return "egress"
+	}
+	return "Direction(" + strconv.Itoa(int(d)) + ")"
+}
+
+// Stats holds the counters of a direction of a Gateway.
+type Stats struct {
+	// Datagrams and Bytes count the UDP datagrams or the SRT
+	// messages read, including their RTP headers.
+	Datagrams int64 `json:"datagrams"`
+	Bytes     int64 `json:"bytes"`
+
+	// Rejected is the number of datagrams ignored because they were
+	// not sent from Gateway.Source.
+	Rejected int64 `json:"rejected"`
+
+	// RTP is the number of datagrams with an RTP header, and RTPLost
+	// the number of RTP packets missing from the sequence.
+	RTP     int64 `json:"rtp"`
+	RTPLost int64 `json:"rtpLost"`
+
+	// TSPackets and Chunks count the TS packets forwarded and the
+	// chunks they were written in.
+	TSPackets int64 `json:"tsPackets"`
+	Chunks    int64 `json:"chunks"`
+
+	// SyncLosses is the number of times the TS sync was lost, and
+	// Discarded the number of bytes discarded until it was found
+	// again.
+	SyncLosses int64 `json:"syncLosses"`
+	Discarded  int64 `json:"discarded"`
+}
+
+func (s *Stats) load() Stats {
+	return Stats{
+		Datagrams:  atomic.LoadInt64(&s.Datagrams),
+		Bytes:      atomic.LoadInt64(&s.Bytes),
+		Rejected:   atomic.LoadInt64(&s.Rejected),
+		RTP:        atomic.LoadInt64(&s.RTP),
+		RTPLost:    atomic.LoadInt64(&s.RTPLost),
+		TSPackets:  atomic.LoadInt64(&s.TSPackets),
+		Chunks:     atomic.LoadInt64(&s.Chunks),
+		SyncLosses: atomic.LoadInt64(&s.SyncLosses),
+		Discarded:  atomic.LoadInt64(&s.Discarded),
+	}
+}
+
+// A Gateway forwards MPEG-TS between UDP and SRT. Each direction can
+// be run once at a time; UDPToSRT and SRTToUDP can run concurrently.
+// The zero value is ready to use.
+type Gateway struct {
+	// stats comes first to be 64-bit aligned for the atomic
+	// operations.
+	stats [2]Stats
+
+	// Packets is the maximum number of TS packets per chunk. Zero
+	// means DefaultPackets.
+	Packets int
+
+	// Source, if not nil, restricts the UDP input to datagrams sent
+	// from Source.IP, and from Source.Port if it is not zero.
+	Source *net.UDPAddr
+}
+
+// Stats returns the counters of the direction d.
+func (g *Gateway) Stats(d Direction) Stats {
+	return g.stats[d].load()
+}
+
+func (g *Gateway) aligner(d Direction) *aligner {
+	n := g.Packets
+	if n <= 0 {
+		n = DefaultPackets
+	}
+	return &aligner{packets: n, st: &g.stats[d]}
+}
+
+func (g *Gateway) accept(addr net.Addr) bool {
+	if g.Source == nil {
+		return true
+	}
+	ua, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	return ua.IP.Equal(g.Source.IP) && (g.Source.Port == 0 || ua.Port == g.Source.Port)
+}
+
+// deadliner is implemented by net.Conn and net.PacketConn.
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// interrupt makes the reads of r fail when ctx is done, if r supports
+// deadlines, until stop is called. stop returns once r is left alone,
+// so that it can be closed.
+func interrupt(ctx context.Context, r interface{}) (stop func()) {
+	dl, ok := r.(deadliner)
+	if !ok {
+		return func() {}
+	}
+	return deadline.Interrupt(ctx, dl.SetReadDeadline)
+}
+
+// UDPToSRT reads MPEG-TS from pc and writes it to the SRT connection w
+// in chunks of whole TS packets, until reading or writing fails or ctx
+// is done. RTP headers are stripped. A datagram's packets are written
+// when it is read, so the chunks are shorter than Packets if the
+// datagrams are.
+func (g *Gateway) UDPToSRT(ctx context.Context, pc net.PacketConn, w io.Writer) error {
+	defer interrupt(ctx, pc)()
+	st := &g.stats[Ingress]
+	a := g.aligner(Ingress)
+	emit := func(b []byte) error {
+		_, err := w.Write(b)
+		return err
+	}
+	var (
+		b       = make([]byte, 65536)
+		lastSeq uint16
+		seqOK   bool
+	)
+	for {
+		n, addr, err := pc.ReadFrom(b)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if !g.accept(addr) {
+			atomic.AddInt64(&st.Rejected, 1)
+			continue
+		}
+		atomic.AddInt64(&st.Datagrams, 1)
+		atomic.AddInt64(&st.Bytes, int64(n))
+		p := b[:n]
+		if payload, seq, ok := parseRTP(p); ok {
+			atomic.AddInt64(&st.RTP, 1)
+			if gap := seq - lastSeq - 1; seqOK && gap != 0 && gap < 0x8000 {
+				atomic.AddInt64(&st.RTPLost, int64(gap))
+			}
+			lastSeq, seqOK = seq, true
+			p = payload
+		}
+		if err := a.write(p, emit); err != nil {
+			return err
+		}
+	}
+}
+
+// SRTToUDP reads MPEG-TS from the SRT connection r and sends it to addr
+// through pc in datagrams of whole TS packets, until reading or
+// writing fails or ctx is done.
+func (g *Gateway) SRTToUDP(ctx context.Context, r io.Reader, pc net.PacketConn, addr net.Addr) error {
+	defer interrupt(ctx, r)()
+	st := &g.stats[Egress]
+	a := g.aligner(Egress)
+	emit := func(b []byte) error {
+		_, err := pc.WriteTo(b, addr)
+		return err
+	}
+	b := make([]byte, 65536)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			atomic.AddInt64(&st.Datagrams, 1)
+			atomic.AddInt64(&st.Bytes, int64(n))
+			if err := a.write(b[:n], emit); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+const someTimeout = 10 * time.Second
+
+// srtPair returns the two ends of an SRT connection.
+func srtPair(t *testing.T) (caller, listener net.Conn) {
+	t.Helper()
+	ln, err := srt.Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	type result struct {
+		c   net.Conn
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		c, err := ln.Accept()
+		ch <- result{c, err}
+	}()
+	caller, err = srt.Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-ch
+	if r.err != nil {
+		caller.Close()
+		t.Fatal(r.err)
+	}
+	return caller, r.c
+}
+
+func rtpPacket(seq uint16, payload []byte) []byte {
+	b := []byte{0x80, 33, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	binary.BigEndian.PutUint16(b[2:], seq)
+	return append(b, payload...)
+}
+
+func TestUDPToSRT(t *testing.T) {
+	w, r := srtPair(t)
+	defer w.Close()
+	defer r.Close()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	src, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	other, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	g := &Gateway{Source: src.LocalAddr().(*net.UDPAddr)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- g.UDPToSRT(ctx, pc, w) }()
+
+	ts := tsStream(21)
+	other.Write(ts[:1316])
+	src.Write(rtpPacket(1, ts[:1316]))
+	src.Write(rtpPacket(2, ts[1316:2632]))
+	// sequence number 3 is lost, and the datagram is not RTP
+	src.Write(ts[2632:])
+
+	r.SetReadDeadline(time.Now().Add(someTimeout))
+	b := make([]byte, 1500)
+	var got []byte
+	for len(got) < len(ts) {
+		n, err := r.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1316 {
+			t.Errorf("got a message of %d bytes; want 1316", n)
+		}
+		got = append(got, b[:n]...)
+	}
+	if !bytes.Equal(got, ts) {
+		t.Errorf("got %d bytes differing from the %d bytes sent", len(got), len(ts))
+	}
+	src.Write(rtpPacket(5, ts[:1316]))
+	if _, err := r.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Errorf("got %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(someTimeout):
+		t.Fatal("UDPToSRT did not return")
+	}
+	want := Stats{Datagrams: 4, Bytes: 4*1316 + 3*12, Rejected: 1, RTP: 3, RTPLost: 2, TSPackets: 28, Chunks: 4}
+	if st := g.Stats(Ingress); st != want {
+		t.Errorf("got %+v; want %+v", st, want)
+	}
+}
+
+func TestSRTToUDP(t *testing.T) {
+	w, r := srtPair(t)
+	defer w.Close()
+	defer r.Close()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	dst, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	g := &Gateway{Packets: 4}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- g.SRTToUDP(ctx, r, pc, dst.LocalAddr()) }()
+
+	ts := tsStream(14)
+	if _, err := w.Write(ts[:1316]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(ts[1316:]); err != nil {
+		t.Fatal(err)
+	}
+	dst.SetReadDeadline(time.Now().Add(someTimeout))
+	b := make([]byte, 1500)
+	var got []byte
+	for len(got) < len(ts) {
+		n, _, err := dst.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 4*TSPacketSize && n != 3*TSPacketSize {
+			t.Errorf("got a datagram of %d bytes; want 4 or 3 TS packets", n)
+		}
+		got = append(got, b[:n]...)
+	}
+	if !bytes.Equal(got, ts) {
+		t.Errorf("got %d bytes differing from the %d bytes sent", len(got), len(ts))
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Errorf("got %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(someTimeout):
+		t.Fatal("SRTToUDP did not return")
+	}
+	want := Stats{Datagrams: 2, Bytes: 2 * 1316, TSPackets: 14, Chunks: 4}
+	if st := g.Stats(Egress); st != want {
+		t.Errorf("got %+v; want %+v", st, want)
+	}
+}
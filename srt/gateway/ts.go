@@ -0,0 +1,141 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package gateway
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+)
+
+const (
+	// TSPacketSize is the size of an MPEG-TS packet.
+	TSPacketSize = 188
+
+	// DefaultPackets is the default number of TS packets per chunk,
+	// which makes the usual SRT payload of 1316 bytes.
+	DefaultPackets = 7
+
+	syncByte = 0x47
+
+	// syncConfirm is the number of sync bytes at the packet distance
+	// which establish the sync, as far as the data reaches.
+	syncConfirm = 3
+)
+
+// findSync returns the offset of the first sync byte in b that is
+// followed by sync bytes at the packet distance, or -1.
+func findSync(b []byte) int {
+	for i := 0; i < len(b); i++ {
+		if b[i] != syncByte {
+			continue
+		}
+		ok := true
+		for k := 1; k < syncConfirm && i+k*TSPacketSize < len(b); k++ {
+			if b[i+k*TSPacketSize] != syncByte {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// An aligner cuts a byte stream into chunks of whole TS packets.
+type aligner struct {
+	packets int    // maximum number of packets per chunk
+	st      *Stats // updated atomically
+	buf     []byte // bytes of an incomplete packet
+	synced  bool
+}
+
+// write appends b to the stream and calls emit with the whole packets
+// it completes, at most a.packets at a time. Bytes between packets are
+// discarded until the sync is found again. The slice passed to emit is
+// only valid during the call.
+func (a *aligner) write(b []byte, emit func([]byte) error) error {
+	a.buf = append(a.buf, b...)
+	buf := a.buf
+	chunk := a.packets * TSPacketSize
+	var p, start int // p is the next packet, start the first unemitted one
+	flush := func() error {
+		if p == start {
+			return nil
+		}
+		atomic.AddInt64(&a.st.TSPackets, int64((p-start)/TSPacketSize))
+		atomic.AddInt64(&a.st.Chunks, 1)
+		err := emit(buf[start:p])
+		start = p
+		return err
+	}
+	for {
+		if !a.synced {
+			i := findSync(buf[p:])
+			if i < 0 {
+				i = len(buf) - p
+			}
+			atomic.AddInt64(&a.st.Discarded, int64(i))
+			p += i
+			start = p
+			if p == len(buf) {
+				break
+			}
+			a.synced = true
+		}
+		for len(buf)-p >= TSPacketSize && buf[p] == syncByte {
+			p += TSPacketSize
+			if p-start == chunk {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if len(buf)-p < TSPacketSize {
+			if len(buf) > p && buf[p] != syncByte {
+				// the start of the next packet is already wrong
+				if err := flush(); err != nil {
+					return err
+				}
+				atomic.AddInt64(&a.st.SyncLosses, 1)
+				a.synced = false
+				continue
+			}
+			break
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		atomic.AddInt64(&a.st.SyncLosses, 1)
+		a.synced = false
+	}
+	err := flush()
+	n := copy(a.buf, buf[p:])
+	a.buf = a.buf[:n]
+	return err
+}
+
+// parseRTP returns the payload of an RTP packet carrying MPEG-TS and
+// its sequence number. ok is false if b is not such a packet.
+func parseRTP(b []byte) (payload []byte, seq uint16, ok bool) {
+	if len(b) < 12 || b[0]>>6 != 2 {
+		return nil, 0, false
+	}
+	n := 12 + 4*int(b[0]&0x0f)
+	if b[0]&0x10 != 0 {
+		if len(b) < n+4 {
+			return nil, 0, false
+		}
+		n += 4 + 4*int(binary.BigEndian.Uint16(b[n+2:]))
+	}
+	end := len(b)
+	if b[0]&0x20 != 0 {
+		end -= int(b[len(b)-1])
+	}
+	if n >= end || b[n] != syncByte {
+		return nil, 0, false
+	}
+	return b[n:end], binary.BigEndian.Uint16(b[2:]), true
+}
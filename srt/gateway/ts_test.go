@@ -0,0 +1,156 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package gateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// tsStream returns n TS packets with a counter in their payload.
+func tsStream(n int) []byte {
+	b := make([]byte, n*TSPacketSize)
+	for i := 0; i < n; i++ {
+		p := b[i*TSPacketSize:]
+		p[0] = syncByte
+		p[1], p[2], p[3] = 0x01, 0x00, 0x10
+		binary.BigEndian.PutUint32(p[4:], uint32(i))
+		for j := 8; j < TSPacketSize; j++ {
+			p[j] = byte(i + j)
+			if p[j] == syncByte {
+				p[j] = 0
+			}
+		}
+	}
+	return b
+}
+
+// align writes the pieces to an aligner and returns its chunks.
+func align(t *testing.T, st *Stats, pieces ...[]byte) [][]byte {
+	t.Helper()
+	a := &aligner{packets: DefaultPackets, st: st}
+	var chunks [][]byte
+	for _, p := range pieces {
+		err := a.write(p, func(b []byte) error {
+			if len(b)%TSPacketSize != 0 || len(b) > DefaultPackets*TSPacketSize {
+				t.Errorf("got chunk of %d bytes", len(b))
+			}
+			chunks = append(chunks, append([]byte(nil), b...))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return chunks
+}
+
+func TestAlignerAligned(t *testing.T) {
+	var st Stats
+	ts := tsStream(14)
+	chunks := align(t, &st, ts[:1316], ts[1316:])
+	if len(chunks) != 2 || !bytes.Equal(bytes.Join(chunks, nil), ts) {
+		t.Fatalf("got %d chunks; want the stream in 2 chunks", len(chunks))
+	}
+	if st.TSPackets != 14 || st.Chunks != 2 || st.SyncLosses != 0 || st.Discarded != 0 {
+		t.Errorf("got %+v", st)
+	}
+}
+
+func TestAlignerSplit(t *testing.T) {
+	var st Stats
+	ts := tsStream(50)
+	var pieces [][]byte
+	for b := ts; len(b) > 0; {
+		n := 1000
+		if n > len(b) {
+			n = len(b)
+		}
+		pieces = append(pieces, b[:n])
+		b = b[n:]
+	}
+	chunks := align(t, &st, pieces...)
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, ts) {
+		t.Fatalf("got %d bytes; want the %d bytes of the stream", len(got), len(ts))
+	}
+	if st.TSPackets != 50 || st.SyncLosses != 0 {
+		t.Errorf("got %+v", st)
+	}
+}
+
+func TestAlignerSyncLoss(t *testing.T) {
+	var st Stats
+	ts := tsStream(21)
+	garbage := bytes.Repeat([]byte{0xff}, 100)
+	in := append(append(append(append([]byte(nil), garbage[:10]...), ts[:5*TSPacketSize]...), garbage...), ts[5*TSPacketSize:]...)
+	chunks := align(t, &st, in[:700], in[700:])
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, ts) {
+		t.Fatalf("got %d bytes; want the %d bytes of the stream without garbage", len(got), len(ts))
+	}
+	if st.SyncLosses != 1 || st.Discarded != 110 || st.TSPackets != 21 {
+		t.Errorf("got %+v; want 1 sync loss and 110 discarded bytes", st)
+	}
+
+	// A packet cut short by the next one is only detected at the
+	// packet after, so the cut packet is forwarded with the start of
+	// the next one.
+	st = Stats{}
+	cut := append(append([]byte(nil), ts[:10*TSPacketSize+50]...), ts[11*TSPacketSize:]...)
+	got := bytes.Join(align(t, &st, cut), nil)
+	if len(got) != 20*TSPacketSize || !bytes.Equal(got[:10*TSPacketSize], ts[:10*TSPacketSize]) || !bytes.Equal(got[11*TSPacketSize:], ts[12*TSPacketSize:]) {
+		t.Errorf("got %d bytes; want packets 0 to 9, the cut packet and packets 12 to 20", len(got))
+	}
+	if st.SyncLosses != 1 || st.Discarded != 50 {
+		t.Errorf("got %+v; want 1 sync loss and 50 discarded bytes", st)
+	}
+
+	errWrite := errors.New("write error")
+	a := &aligner{packets: DefaultPackets, st: &st}
+	if err := a.write(ts, func([]byte) error { return errWrite }); err != errWrite {
+		t.Errorf("got %v; want %v", err, errWrite)
+	}
+}
+
+func TestParseRTP(t *testing.T) {
+	ts := tsStream(7)
+	rtp := func(first byte, ext, pad int) []byte {
+		b := []byte{first, 33, 0x12, 0x34, 0, 0, 0, 1, 0, 0, 0, 2}
+		b = append(b, make([]byte, 4*int(first&0x0f))...)
+		if first&0x10 != 0 {
+			b = append(b, 0xbe, 0xde, 0, byte(ext))
+			b = append(b, make([]byte, 4*ext)...)
+		}
+		b = append(b, ts...)
+		if first&0x20 != 0 {
+			b = append(b, make([]byte, pad-1)...)
+			b = append(b, byte(pad))
+		}
+		return b
+	}
+	for _, b := range [][]byte{
+		rtp(0x80, 0, 0),
+		rtp(0x82, 0, 0),
+		rtp(0x90, 2, 0),
+		rtp(0xa0, 0, 4),
+		rtp(0xb1, 1, 8),
+	} {
+		payload, seq, ok := parseRTP(b)
+		if !ok || seq != 0x1234 || !bytes.Equal(payload, ts) {
+			t.Errorf("%x...: got %d bytes, %#x, %v; want the TS packets", b[:4], len(payload), seq, ok)
+		}
+	}
+	for _, b := range [][]byte{
+		ts,
+		rtp(0x80, 0, 0)[:12],
+		rtp(0x40, 0, 0),
+		rtp(0x8f, 0, 0)[:40],
+		append([]byte{0x80, 33, 0, 1, 0, 0, 0, 1, 0, 0, 0, 2}, 1, 2, 3),
+	} {
+		if _, _, ok := parseRTP(b); ok {
+			t.Errorf("%x...: got RTP", b[:4])
+		}
+	}
+}
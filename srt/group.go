@@ -0,0 +1,334 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/internal/poll"
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// GroupType is the type of a socket group, which defines how the
+// payload is distributed among the member links.
+type GroupType int
+
+// Socket group types
+const (
+	// GroupBroadcast sends every packet over all member links.
+	GroupBroadcast GroupType = srtapi.GroupTypeBroadcast
+
+	// GroupBackup sends over the main link only and switches to a
+	// backup link when the main link becomes unstable.
+	GroupBackup GroupType = srtapi.GroupTypeBackup
+
+	// GroupBalancing splits the payload among the member links.
+	GroupBalancing GroupType = srtapi.GroupTypeBalancing
+)
+
+func (t GroupType) String() string {
+	switch t {
+	case GroupBroadcast:
+		return "broadcast"
+	case GroupBackup:
+		return "backup"
+	case GroupBalancing:
+		return "balancing"
+	}
+	return "undefined"
+}
+
+// MemberState is the state of a member link in a socket group.
+type MemberState int
+
+// Group member states
+const (
+	MemberPending MemberState = srtapi.MemberStatusPending
+	MemberIdle    MemberState = srtapi.MemberStatusIdle
+	MemberRunning MemberState = srtapi.MemberStatusRunning
+	MemberBroken  MemberState = srtapi.MemberStatusBroken
+)
+
+func (s MemberState) String() string {
+	switch s {
+	case MemberPending:
+		return "pending"
+	case MemberIdle:
+		return "idle"
+	case MemberRunning:
+		return "running"
+	case MemberBroken:
+		return "broken"
+	}
+	return "unknown"
+}
+
+// GroupMember describes a member link of a socket group to dial.
+type GroupMember struct {
+	// Address is the remote address of the member link in the
+	// form "host:port".
+	Address string
+
+	// LocalAddr is the local address to use for the member link.
+	// If nil, a local address is automatically chosen.
+	LocalAddr *SRTAddr
+
+	// Weight is the weight of the member link. For backup groups
+	// it is the priority, a link with a higher weight is preferred
+	// as the main link.
+	Weight int
+}
+
+// GroupMemberStatus is the status of a member link of a socket group.
+type GroupMemberStatus struct {
+	// ID is the socket id of the member link.
+	ID int
+
+	// Addr is the remote address of the member link.
+	Addr net.Addr
+
+	// SockState is the socket state, one of srtapi.Status*.
+	SockState int
+
+	// State is the member state in the group.
+	State MemberState
+
+	// Weight is the weight of the member link.
+	Weight int
+}
+
+// A GroupDialer contains options for connecting a socket group.
+type GroupDialer struct {
+	// Type is the type of the socket group. The default is
+	// GroupBroadcast.
+	Type GroupType
+
+	// Timeout is the maximum amount of time a dial will wait for
+	// the first member link to connect.
+	Timeout time.Duration
+
+	// Deadline is the absolute point in time after which dials
+	// will fail.
+	Deadline time.Time
+
+	// Resolver optionally specifies an alternate resolver to use.
+	Resolver *Resolver
+}
+
+func (d *GroupDialer) resolver() *Resolver {
+	if d.Resolver != nil {
+		return d.Resolver
+	}
+	return DefaultResolver
+}
+
+func (d *GroupDialer) groupType() GroupType {
+	if d.Type == 0 {
+		return GroupBroadcast
+	}
+	return d.Type
+}
+
+// Dial connects a socket group to the member addresses on the named
+// network.
+func (d *GroupDialer) Dial(network string, members ...GroupMember) (*SRTGroupConn, error) {
+	return d.DialContext(context.Background(), network, members...)
+}
+
+// DialContext connects a socket group to the member addresses on the
+// named network using the provided context. The options held by ctx
+// are applied to the group and so to all of its members.
+//
+// DialContext returns as soon as one of the member links is
+// connected. The remaining links keep connecting in the background.
+func (d *GroupDialer) DialContext(ctx context.Context, network string, members ...GroupMember) (*SRTGroupConn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	if len(members) == 0 {
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: errMissingAddress}
+	}
+	deadline := d.Deadline
+	if d.Timeout != 0 {
+		deadline = minNonzeroTime(time.Now().Add(d.Timeout), deadline)
+	}
+	if !deadline.IsZero() {
+		subCtx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		ctx = subCtx
+	}
+
+	cfgs, ras, err := d.resolveMembers(ctx, network, members)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	fd, err := groupSocket(ctx, network, d.groupType(), ras[0].family())
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: ras[0], Err: err}
+	}
+	if err := fd.connectGroup(ctx, cfgs); err != nil {
+		fd.Close()
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: ras[0], Err: err}
+	}
+	if err := configure(ctx, fd.pfd.Sysfd, bindPost); err != nil {
+		fd.Close()
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: ras[0], Err: err}
+	}
+	fd.isConnected = true
+	fd.setAddr(nil, ras[0])
+	return newSRTGroupConn(fd), nil
+}
+
+func (d *GroupDialer) resolveMembers(ctx context.Context, network string, members []GroupMember) ([]srtapi.GroupMemberConfig, []*SRTAddr, error) {
+	cfgs := make([]srtapi.GroupMemberConfig, 0, len(members))
+	ras := make([]*SRTAddr, 0, len(members))
+	for _, m := range members {
+		var hint net.Addr
+		if m.LocalAddr != nil {
+			hint = m.LocalAddr
+		}
+		addrs, err := d.resolver().resolveAddrList(ctx, "dial", network, m.Address, hint)
+		if err != nil {
+			return nil, nil, err
+		}
+		ra, ok := addrs.first(isIPv4).(*SRTAddr)
+		if !ok {
+			return nil, nil, &net.AddrError{Err: "unexpected address type", Addr: m.Address}
+		}
+		family := ra.family()
+		rsa, err := ra.sockaddr(family)
+		if err != nil {
+			return nil, nil, err
+		}
+		lsa, err := m.LocalAddr.sockaddr(family)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfgs = append(cfgs, srtapi.GroupMemberConfig{Source: lsa, Peer: rsa, Weight: m.Weight})
+		ras = append(ras, ra)
+	}
+	return cfgs, ras, nil
+}
+
+// groupSocket returns a network file descriptor of a new socket group.
+func groupSocket(ctx context.Context, net string, typ GroupType, family int) (*netFD, error) {
+	if err := validateContext(ctx); err != nil {
+		return nil, err
+	}
+	s, err := srtapi.CreateGroup(int(typ))
+	if err != nil {
+		return nil, os.NewSyscallError("create_group", err)
+	}
+	if err = srtapi.SetNonblock(s, true); err != nil {
+		poll.CloseFunc(s)
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+	if err = configure(ctx, s, bindPre); err != nil {
+		poll.CloseFunc(s)
+		return nil, err
+	}
+	fd, err := newFD(s, family, syscall.SOCK_DGRAM, net)
+	if err != nil {
+		poll.CloseFunc(s)
+		return nil, err
+	}
+	return fd, nil
+}
+
+var errGroupBroken = errors.New("all member links of the group failed")
+
+func (fd *netFD) connectGroup(ctx context.Context, cfgs []srtapi.GroupMemberConfig) error {
+	if _, err := srtapi.ConnectGroup(fd.pfd.Sysfd, cfgs); err != nil {
+		for _, cfg := range cfgs {
+			if cfg.Err != nil {
+				return os.NewSyscallError("connect_group", cfg.Err)
+			}
+		}
+		return os.NewSyscallError("connect_group", err)
+	}
+	return fd.waitConnect(ctx, fd.groupConnectState)
+}
+
+// groupConnectState reports whether any member link of the group fd
+// has been connected.
+func (fd *netFD) groupConnectState() (bool, error) {
+	members, err := srtapi.GroupData(fd.pfd.Sysfd)
+	if err != nil {
+		return false, os.NewSyscallError("group_data", err)
+	}
+	pending := false
+	for _, m := range members {
+		switch m.SockState {
+		case srtapi.StatusConnected:
+			return true, nil
+		case srtapi.StatusInit, srtapi.StatusOpened, srtapi.StatusConnecting:
+			pending = true
+		}
+	}
+	if !pending {
+		return false, errGroupBroken
+	}
+	return false, nil
+}
+
+// SRTGroupConn is a connection over a socket group. Reads and writes
+// go to the group and are distributed over the member links according
+// to the group type.
+type SRTGroupConn struct {
+	SRTConn
+}
+
+func newSRTGroupConn(fd *netFD) *SRTGroupConn {
+	c := &SRTGroupConn{SRTConn{conn: conn{fd}}}
+	trackConn(&c.SRTConn)
+	return c
+}
+
+// Members returns the status of the member links of the group.
+func (c *SRTGroupConn) Members() ([]GroupMemberStatus, error) {
+	if !c.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	data, err := srtapi.GroupData(c.fd.pfd.Sysfd)
+	if err != nil {
+		return nil, &OpError{Op: "members", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("group_data", err)}
+	}
+	members := make([]GroupMemberStatus, 0, len(data))
+	for _, d := range data {
+		members = append(members, GroupMemberStatus{
+			ID:        d.ID,
+			Addr:      sockaddrToSRT(d.Peer),
+			SockState: d.SockState,
+			State:     MemberState(d.MemberState),
+			Weight:    d.Weight,
+		})
+	}
+	return members, nil
+}
+
+// AddMember starts connecting an additional member link to the group.
+// It does not wait for the link to be connected; use Members to
+// follow its state.
+func (c *SRTGroupConn) AddMember(ctx context.Context, m GroupMember) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	var d GroupDialer
+	cfgs, ras, err := d.resolveMembers(ctx, c.fd.net, []GroupMember{m})
+	if err != nil {
+		return &OpError{Op: "dial", Net: c.fd.net, Source: nil, Addr: nil, Err: err}
+	}
+	if _, err := srtapi.ConnectGroup(c.fd.pfd.Sysfd, cfgs); err != nil {
+		if cfgs[0].Err != nil {
+			err = cfgs[0].Err
+		}
+		return &OpError{Op: "dial", Net: c.fd.net, Source: nil, Addr: ras[0], Err: wrapSyscallError("connect_group", err)}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// mustHaveGroups skips the test if the SRT library has no socket
+// groups, like one built without bonding or the srtmock backend.
+func mustHaveGroups(t *testing.T) {
+	fd, err := srtapi.CreateGroup(srtapi.GroupTypeBroadcast)
+	if err != nil {
+		t.Skipf("socket groups not supported: %v", err)
+	}
+	srtapi.Close(fd)
+}
+
+var groupDialTests = []struct {
+	typ     GroupType
+	weights []int
+}{
+	{GroupBroadcast, []int{0, 0}},
+	{GroupBackup, []int{10, 1}},
+}
+
+func TestGroupDial(t *testing.T) {
+	mustHaveGroups(t)
+	ctx := WithOptions(context.Background(), Options("groupconnect", "1"))
+	for i, tt := range groupDialTests {
+		ln, err := newLocalListenerContext(ctx, "srt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := []byte("GROUP DIAL TEST")
+		errc := make(chan error, 1)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				errc <- err
+				return
+			}
+			defer c.Close()
+			if _, ok := c.(*SRTGroupConn); !ok {
+				t.Errorf("#%d: got %T; want *SRTGroupConn", i, c)
+			}
+			c.SetReadDeadline(time.Now().Add(someTimeout))
+			b := make([]byte, 128)
+			n, err := c.Read(b)
+			if err == nil && !bytes.Equal(b[:n], msg) {
+				t.Errorf("#%d: got %q; want %q", i, b[:n], msg)
+			}
+			errc <- err
+		}()
+
+		var members []GroupMember
+		for _, w := range tt.weights {
+			members = append(members, GroupMember{Address: ln.Addr().String(), Weight: w})
+		}
+		d := GroupDialer{Type: tt.typ, Timeout: someTimeout}
+		c, err := d.DialContext(ctx, "srt", members...)
+		if err != nil {
+			ln.Close()
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if _, err := c.Write(msg); err != nil {
+			t.Errorf("#%d: %v", i, err)
+		}
+		if err := <-errc; err != nil {
+			t.Errorf("#%d: %v", i, err)
+		}
+		ms, err := c.Members()
+		if err != nil {
+			t.Errorf("#%d: %v", i, err)
+		}
+		if len(ms) != len(members) {
+			t.Errorf("#%d: got %d members; want %d", i, len(ms), len(members))
+		}
+		c.Close()
+		ln.Close()
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/xmedia-systems/gosrt/internal/poll/runtime"
+)
+
+// InitOptions holds the options of Init.
+type InitOptions struct {
+	// PollShards is the number of epoll shards of the poller.
+	// Zero means the value of SRT_POLLSHARDS, or 1.
+	PollShards int
+}
+
+var library struct {
+	sync.Mutex
+	refs int
+}
+
+// listeners holds the open listeners, which Shutdown closes.
+var listeners struct {
+	sync.Mutex
+	m map[*SRTListener]struct{}
+}
+
+// Init starts the SRT library. Each successful call must be paired
+// with a call to Shutdown; the library keeps running until the last
+// reference is released.
+//
+// Calling Init is optional: the library is started implicitly with the
+// first socket. Init on a running library only takes a reference; it
+// fails if opts asks for another number of poll shards than the
+// library runs with. Init after the library was shut down starts it
+// again.
+func Init(opts *InitOptions) error {
+	var shards int
+	if opts != nil {
+		shards = opts.PollShards
+	}
+	if shards < 0 {
+		return &OpError{Op: "init", Net: "srt", Err: errors.New("invalid number of poll shards")}
+	}
+	library.Lock()
+	defer library.Unlock()
+	if err := runtime.PollServerInit(shards); err != nil {
+		return &OpError{Op: "init", Net: "srt", Err: err}
+	}
+	library.refs++
+	return nil
+}
+
+// Shutdown releases a reference taken by Init. If it is the last
+// reference, or Init was never called, it stops the library:
+//
+// All listeners are closed. Then Shutdown waits until the connections
+// are closed by their users or ctx is done. The remaining sockets are
+// closed, their pending I/O fails, and the library is cleaned up.
+//
+// Shutdown returns ctx.Err() if connections had to be closed forcibly.
+// It is a no-op if the library is not running.
+func Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		panic("nil context")
+	}
+	library.Lock()
+	defer library.Unlock()
+	if library.refs > 1 {
+		library.refs--
+		return nil
+	}
+	library.refs = 0
+	if !runtime.PollServerRunning() {
+		return nil
+	}
+	listeners.Lock()
+	lns := make([]*SRTListener, 0, len(listeners.m))
+	for ln := range listeners.m {
+		lns = append(lns, ln)
+	}
+	listeners.Unlock()
+	for _, ln := range lns {
+		ln.Close()
+	}
+	var err error
+	if !runtime.PollServerDrain(ctx.Done()) {
+		err = ctx.Err()
+	}
+	runtime.PollServerShutdown()
+	return err
+}
+
+func addListener(ln *SRTListener) {
+	listeners.Lock()
+	defer listeners.Unlock()
+	if listeners.m == nil {
+		listeners.m = make(map[*SRTListener]struct{})
+	}
+	listeners.m[ln] = struct{}{}
+}
+
+func removeListener(ln *SRTListener) {
+	listeners.Lock()
+	defer listeners.Unlock()
+	delete(listeners.m, ln)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/internal/poll"
+)
+
+func echoOnce(t *testing.T) {
+	t.Helper()
+	ls, err := newLocalServer("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.teardown()
+	ch := make(chan error, 1)
+	if err := ls.buildup(func(ls *localServer, ln net.Listener) { transponder(ln, ch) }); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Dial(ls.Listener.Addr().Network(), ls.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tch := make(chan error, 1)
+	transceiver(c, []byte("LIFECYCLE TEST"), tch)
+	for err := range tch {
+		t.Error(err)
+	}
+	for err := range ch {
+		t.Error(err)
+	}
+}
+
+func TestInitShutdown(t *testing.T) {
+	// Stop the library started implicitly by earlier tests, so that
+	// Init chooses the shards.
+	ctx, cancel := context.WithTimeout(context.Background(), someTimeout)
+	Shutdown(ctx)
+	cancel()
+
+	for i := 0; i < 3; i++ {
+		if err := Init(&InitOptions{PollShards: 2}); err != nil {
+			t.Fatal(err)
+		}
+		if err := Init(nil); err != nil {
+			t.Fatal(err)
+		}
+		// The running library cannot change its shards.
+		if err := Init(&InitOptions{PollShards: 3}); err == nil {
+			Shutdown(context.Background())
+			t.Error("Init with another number of shards should fail")
+		}
+		echoOnce(t)
+		// The first Shutdown only releases a reference.
+		if err := Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		echoOnce(t)
+		if err := Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if fd := poll.Descriptor(); fd != -1 {
+			t.Errorf("got poller descriptor %d after the shutdown; want -1", fd)
+		}
+		// Shutdown of a stopped library is a no-op.
+		if err := Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The library is started implicitly again.
+	echoOnce(t)
+}
+
+func TestInitInvalidOptions(t *testing.T) {
+	if err := Init(&InitOptions{PollShards: -1}); err == nil {
+		Shutdown(context.Background())
+		t.Fatal("should fail")
+	}
+}
+
+func TestShutdownDrain(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptErr := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			// Block in Read until Shutdown closes the socket.
+			_, err = c.Read(make([]byte, 128))
+		}
+		acceptErr <- err
+	}()
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := c.Read(make([]byte, 128))
+		readErr <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v; want %v", err, context.DeadlineExceeded)
+	}
+	for _, ch := range []chan error{acceptErr, readErr} {
+		select {
+		case err := <-ch:
+			if err == nil {
+				t.Error("Read should fail after Shutdown")
+			}
+		case <-time.After(someTimeout):
+			t.Fatal("Read is blocked after Shutdown")
+		}
+	}
+}
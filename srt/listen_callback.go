@@ -5,19 +5,74 @@ package srt
 
 import (
 	"context"
+	"errors"
+	"runtime/debug"
+	"syscall"
 
+	"github.com/xmedia-systems/gosrt/logging"
+	"github.com/xmedia-systems/gosrt/srt/streamid"
 	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
+// ListenCallbackFunc is called by a listener for every incoming
+// connection before it is accepted. ns is the socket of the pending
+// connection, hsversion the handshake version, peeraddr the address
+// of the caller and streamID the stream ID sent by the caller.
+//
+// Returning nil accepts the connection. Returning a non-nil error
+// rejects it. If the error is or wraps a RejectReason, the caller is
+// told that reason; otherwise it is told RejectFallback.
+type ListenCallbackFunc func(ns int, hsversion int, peeraddr syscall.Sockaddr, streamID string) error
+
+// StreamIDCallbackFunc is like ListenCallbackFunc, but gets the
+// parsed stream ID of the caller.
+type StreamIDCallbackFunc func(ns int, hsversion int, peeraddr syscall.Sockaddr, id *streamid.StreamID) error
+
+// ParseStreamID returns a ListenCallbackFunc that parses the stream ID
+// of the caller and calls callback with it. Connections with a
+// malformed stream ID are rejected with RejectBadRequest.
+func ParseStreamID(callback StreamIDCallbackFunc) ListenCallbackFunc {
+	return func(ns int, hsversion int, peeraddr syscall.Sockaddr, streamID string) error {
+		id, err := streamid.Parse(streamID)
+		if err != nil {
+			return RejectBadRequest
+		}
+		return callback(ns, hsversion, peeraddr, id)
+	}
+}
+
 // listenCallbackContextKey is the type of contextKeys used for listenCallback.
 type listenCallbackContextKey struct{}
 
 // WithListenCallback returns a new context.Context with the listenCallback.
-func WithListenCallback(ctx context.Context, callback srtapi.SrtListenCallbackFunc) context.Context {
+func WithListenCallback(ctx context.Context, callback ListenCallbackFunc) context.Context {
 	return context.WithValue(ctx, listenCallbackContextKey{}, callback)
 }
 
 func listenCallbackValue(ctx context.Context) srtapi.SrtListenCallbackFunc {
-	callback, _ := ctx.Value(listenCallbackContextKey{}).(srtapi.SrtListenCallbackFunc)
-	return callback
+	callback, _ := ctx.Value(listenCallbackContextKey{}).(ListenCallbackFunc)
+	if callback == nil {
+		return nil
+	}
+	return func(ns int, hsversion int, peeraddr syscall.Sockaddr, streamID string) (ret int) {
+		// The callback runs on a thread of the SRT library; a panic
+		// must not unwind through the C stack.
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Logf(logging.LevelError, "listen callback: panic: %v\n%s", r, debug.Stack())
+				srtapi.SetRejectReason(ns, int(RejectFallback))
+				ret = -1
+			}
+		}()
+		err := callback(ns, hsversion, peeraddr, streamID)
+		if err == nil {
+			return 0
+		}
+		var reason RejectReason
+		if !errors.As(err, &reason) {
+			reason = RejectFallback
+		}
+		srtapi.SetRejectReason(ns, int(reason))
+		return -1
+	}
 }
@@ -0,0 +1,184 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/xmedia-systems/gosrt/srt/streamid"
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+var listenCallbackRejectTests = []struct {
+	streamID string
+	err      error
+	reason   RejectReason // zero means accepted
+}{
+	{"user", nil, 0},
+	{"stranger", RejectUnauthorized, RejectUnauthorized},
+	{"missing", RejectNotFound, RejectNotFound},
+	{"custom", RejectUserDefined + 7, RejectUserDefined + 7},
+	{"broken", errors.New("callback failed"), RejectFallback},
+	{"wrapped", fmt.Errorf("no such user: %w", RejectUnauthorized), RejectUnauthorized},
+}
+
+// acceptOne accepts a connection on ln and keeps it open until the
+// returned func is called, so that the caller sees it established.
+func acceptOne(ln net.Listener) (release func()) {
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	return func() {
+		ln.Close()
+		if c := <-accepted; c != nil {
+			c.Close()
+		}
+	}
+}
+
+func TestListenCallbackReject(t *testing.T) {
+	for i, tt := range listenCallbackRejectTests {
+		ctx := WithListenCallback(context.Background(), func(ns int, hsversion int, peeraddr syscall.Sockaddr, streamID string) error {
+			if streamID != tt.streamID {
+				return RejectBadRequest
+			}
+			return tt.err
+		})
+		ln, err := newLocalListenerContext(ctx, "srt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release := acceptOne(ln)
+
+		var d Dialer
+		dctx := WithOptions(context.Background(), Options("streamid", tt.streamID))
+		c, err := d.DialContext(dctx, ln.Addr().Network(), ln.Addr().String())
+		release()
+		if tt.reason == 0 {
+			if err != nil {
+				t.Errorf("#%d: %v", i, err)
+				continue
+			}
+			c.Close()
+			continue
+		}
+		if err == nil {
+			c.Close()
+			t.Errorf("#%d: should fail", i)
+			continue
+		}
+		if perr := parseDialError(err); perr != nil {
+			t.Errorf("#%d: %v", i, perr)
+		}
+		rerr, ok := err.(*OpError).Err.(*RejectError)
+		if !ok {
+			t.Errorf("#%d: got %v; want *RejectError", i, err)
+			continue
+		}
+		if rerr.Reason != tt.reason {
+			t.Errorf("#%d: got reason %v; want %v", i, rerr.Reason, tt.reason)
+		}
+	}
+}
+
+// A peer closing the connection before the caller sees it established
+// makes the dial fail with a connection error.
+func TestDialPeerClosed(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+		if err == nil {
+			c.Close()
+			continue
+		}
+		serr, ok := err.(*OpError).Err.(*os.SyscallError)
+		if !ok || serr.Err != srtapi.ECONNLOST && serr.Err != srtapi.ENOCONN {
+			t.Errorf("#%d: got %v; want a lost or missing connection", i, err)
+		}
+	}
+}
+
+func TestRejectReasonString(t *testing.T) {
+	for _, tt := range []struct {
+		reason RejectReason
+		want   string
+	}{
+		{RejectForbidden, "forbidden"},
+		{RejectPredefined + 42, "predefined reason 42"},
+		{RejectUserDefined + 3, "user defined reason 3"},
+	} {
+		if s := tt.reason.String(); s != tt.want {
+			t.Errorf("got %q; want %q", s, tt.want)
+		}
+	}
+}
+
+func TestParseStreamID(t *testing.T) {
+	got := make(chan *streamid.StreamID, 1)
+	ctx := WithListenCallback(context.Background(), ParseStreamID(func(ns int, hsversion int, peeraddr syscall.Sockaddr, id *streamid.StreamID) error {
+		got <- id
+		if id.User != "admin" {
+			return RejectUnauthorized
+		}
+		return nil
+	}))
+	ln, err := newLocalListenerContext(ctx, "srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// the accepted connection is kept open until the caller is done
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			accepted <- c
+		}
+	}()
+
+	want := &streamid.StreamID{Resource: "live/stream1", User: "admin", Mode: streamid.ModePublish}
+	d := Dialer{StreamID: want}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	(<-accepted).Close()
+	if id := <-got; !reflect.DeepEqual(id, want) {
+		t.Errorf("got %+v; want %+v", id, want)
+	}
+
+	// a malformed stream ID is rejected before the callback
+	dctx := WithOptions(context.Background(), Options("streamid", "#!::r"))
+	d.StreamID = nil
+	_, err = d.DialContext(dctx, ln.Addr().Network(), ln.Addr().String())
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	rerr, ok := err.(*OpError).Err.(*RejectError)
+	if !ok || rerr.Reason != RejectBadRequest {
+		t.Errorf("got %v; want %v", err, RejectBadRequest)
+	}
+}
@@ -157,6 +157,7 @@ var dualStackSRTListenerTests = []struct {
 // On DragonFly BSD, we expect the kernel version of node under test
 // to be greater than or equal to 4.4.
 func TestDualStackSRTListener(t *testing.T) {
+	mustHaveDualStack(t)
 	switch runtime.GOOS {
 	case "nacl", "plan9":
 		t.Skipf("not supported on %s", runtime.GOOS)
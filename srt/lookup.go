@@ -27,7 +27,7 @@ type Resolver struct {
 // LookupIPAddr looks up host using the local resolver.
 // It returns a slice of that host's IPv4 and IPv6 addresses.
 func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
-	return r.nr.LookupIPAddr(ctx, host)
+	return testHookLookupIP(ctx, r.nr.LookupIPAddr, host)
 }
 
 // LookupPort looks up the port for the given network and service.
@@ -10,6 +10,7 @@ package srt
 
 import (
 	"github.com/xmedia-systems/gosrt/internal/poll"
+	"github.com/xmedia-systems/gosrt/internal/poll/runtime"
 )
 
 var (
@@ -20,6 +21,7 @@ var (
 	origListen        = listenFunc
 	origAccept        = poll.AcceptFunc
 	origGetsockoptInt = getsockoptIntFunc
+	origEpollUwait    = runtime.EpollUwaitFunc
 
 	extraTestHookInstallers   []func()
 	extraTestHookUninstallers []func()
@@ -32,6 +34,7 @@ func installTestHooks() {
 	listenFunc = sw.Listen
 	poll.AcceptFunc = sw.Accept
 	getsockoptIntFunc = sw.GetsockoptInt
+	runtime.EpollUwaitFunc = sw.EpollUwait
 
 	for _, fn := range extraTestHookInstallers {
 		fn()
@@ -45,6 +48,7 @@ func uninstallTestHooks() {
 	listenFunc = origListen
 	poll.AcceptFunc = origAccept
 	getsockoptIntFunc = origGetsockoptInt
+	runtime.EpollUwaitFunc = origEpollUwait
 
 	for _, fn := range extraTestHookUninstallers {
 		fn()
@@ -9,6 +9,7 @@
 package srt
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -41,7 +42,7 @@ func TestMain(m *testing.M) {
 		printSocketStats()
 	}
 	forceCloseSockets()
-	Shutdown()
+	Shutdown(context.Background())
 	os.Exit(st)
 }
 
@@ -0,0 +1,260 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package metrics exports the statistics of live SRT connections in
+// the OpenMetrics text format, as scraped by Prometheus.
+//
+// Importing the package registers DefaultRegistry with the srt
+// package, so that all connections and listeners opened afterwards
+// are tracked until they are closed. Serve the metrics with
+//
+//	http.Handle("/metrics", metrics.Handler())
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+// ContentType is the content type of the OpenMetrics text format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Registry tracks live SRT connections and listeners and writes their
+// statistics in the OpenMetrics text format. It implements
+// srt.ConnTracker and http.Handler.
+type Registry struct {
+	mu        sync.Mutex
+	conns     map[*srt.SRTConn]struct{}
+	listeners map[*srt.SRTListener]struct{}
+}
+
+// NewRegistry returns an empty Registry. Register it with
+// srt.AddConnTracker to track connections automatically.
+func NewRegistry() *Registry {
+	return &Registry{
+		conns:     make(map[*srt.SRTConn]struct{}),
+		listeners: make(map[*srt.SRTListener]struct{}),
+	}
+}
+
+// DefaultRegistry is the Registry used by Handler. It is registered
+// with the srt package when the package is imported.
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	srt.AddConnTracker(DefaultRegistry)
+}
+
+// Handler returns an http.Handler serving the metrics of
+// DefaultRegistry.
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+// ConnOpened implements the srt.ConnTracker ConnOpened method.
+func (r *Registry) ConnOpened(c *srt.SRTConn) {
+	r.mu.Lock()
+	r.conns[c] = struct{}{}
+	r.mu.Unlock()
+}
+
+// ConnClosed implements the srt.ConnTracker ConnClosed method.
+func (r *Registry) ConnClosed(c *srt.SRTConn) {
+	r.mu.Lock()
+	delete(r.conns, c)
+	r.mu.Unlock()
+}
+
+// ListenerOpened implements the srt.ConnTracker ListenerOpened method.
+func (r *Registry) ListenerOpened(l *srt.SRTListener) {
+	r.mu.Lock()
+	r.listeners[l] = struct{}{}
+	r.mu.Unlock()
+}
+
+// ListenerClosed implements the srt.ConnTracker ListenerClosed method.
+func (r *Registry) ListenerClosed(l *srt.SRTListener) {
+	r.mu.Lock()
+	delete(r.listeners, l)
+	r.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics of r to w.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.WriteTo(w)
+}
+
+type sample struct {
+	labels string
+	value  float64
+}
+
+type family struct {
+	name, typ, unit, help string
+	samples               []sample
+}
+
+// metric families in the order of the exposition
+var families = []family{
+	{"srt_listener", "info", "", "Open SRT listeners.", nil},
+	{"srt_connection", "info", "", "Open SRT connections.", nil},
+	{"srt_rtt_seconds", "gauge", "seconds", "Round trip time.", nil},
+	{"srt_bandwidth_bits_per_second", "gauge", "", "Estimated link bandwidth.", nil},
+	{"srt_max_bandwidth_bits_per_second", "gauge", "", "Transmission bandwidth limit.", nil},
+	{"srt_rate_bits_per_second", "gauge", "", "Sending or receiving rate.", nil},
+	{"srt_flow_window_packets", "gauge", "", "Flow window size.", nil},
+	{"srt_congestion_window_packets", "gauge", "", "Congestion window size.", nil},
+	{"srt_flight_packets", "gauge", "", "Packets on flight.", nil},
+	{"srt_packets", "counter", "", "Packets sent or received.", nil},
+	{"srt_bytes", "counter", "bytes", "Bytes sent or received.", nil},
+	{"srt_packets_lost", "counter", "", "Packets lost.", nil},
+	{"srt_packets_retransmitted", "counter", "", "Packets retransmitted.", nil},
+	{"srt_packets_dropped", "counter", "", "Packets dropped because they were too late.", nil},
+	{"srt_buffer_packets", "gauge", "", "Packets in the send or receive buffer.", nil},
+	{"srt_buffer_bytes", "gauge", "bytes", "Bytes in the send or receive buffer.", nil},
+	{"srt_buffer_seconds", "gauge", "seconds", "Timespan of the data in the send or receive buffer.", nil},
+}
+
+// WriteTo writes the metrics of r to w in the OpenMetrics text
+// format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	conns := make([]*srt.SRTConn, 0, len(r.conns))
+	for c := range r.conns {
+		conns = append(conns, c)
+	}
+	listeners := make([]*srt.SRTListener, 0, len(r.listeners))
+	for l := range r.listeners {
+		listeners = append(listeners, l)
+	}
+	r.mu.Unlock()
+
+	fams := make([]family, len(families))
+	byName := make(map[string]*family, len(families))
+	for i := range families {
+		fams[i] = families[i]
+		byName[fams[i].name] = &fams[i]
+	}
+	add := func(name, labels string, value float64) {
+		f := byName[name]
+		f.samples = append(f.samples, sample{labels, value})
+	}
+
+	for _, l := range listeners {
+		add("srt_listener", labels("local_addr", l.Addr().String()), 1)
+	}
+	for _, c := range conns {
+		st, err := c.Stats(false)
+		if err != nil {
+			// closed concurrently
+			continue
+		}
+		streamID, _ := c.StreamID()
+		base := labels("socket", strconv.Itoa(st.SocketID), "stream_id", streamID, "peer", c.RemoteAddr().String())
+		send := base + "," + labels("direction", "send")
+		recv := base + "," + labels("direction", "recv")
+
+		add("srt_connection", base+","+labels("local_addr", c.LocalAddr().String()), 1)
+		add("srt_rtt_seconds", base, st.Link.RTT/1e3)
+		add("srt_bandwidth_bits_per_second", base, st.Link.Bandwidth*1e6)
+		add("srt_max_bandwidth_bits_per_second", base, st.Link.MaxBandwidth*1e6)
+		add("srt_flow_window_packets", base, float64(st.Window.Flow))
+		add("srt_congestion_window_packets", base, float64(st.Window.Congestion))
+		add("srt_flight_packets", base, float64(st.Window.Flight))
+
+		add("srt_rate_bits_per_second", send, st.Send.MbitRate*1e6)
+		add("srt_packets", send, float64(st.Send.Total.Packets))
+		add("srt_bytes", send, float64(st.Send.Total.Bytes))
+		add("srt_packets_lost", send, float64(st.Send.Total.PacketsLost))
+		add("srt_packets_retransmitted", send, float64(st.Send.Total.PacketsRetransmitted))
+		add("srt_packets_dropped", send, float64(st.Send.Total.PacketsDropped))
+		add("srt_buffer_packets", send, float64(st.Send.BufferPackets))
+		add("srt_buffer_bytes", send, float64(st.Send.BufferBytes))
+		add("srt_buffer_seconds", send, float64(st.Send.BufferMs)/1e3)
+
+		add("srt_rate_bits_per_second", recv, st.Recv.MbitRate*1e6)
+		add("srt_packets", recv, float64(st.Recv.Total.Packets))
+		add("srt_bytes", recv, float64(st.Recv.Total.Bytes))
+		add("srt_packets_lost", recv, float64(st.Recv.Total.PacketsLost))
+		// libsrt counts the received retransmissions only per
+		// interval, which other users of the statistics may clear at
+		// any time, so they are not exported
+		add("srt_packets_dropped", recv, float64(st.Recv.Total.PacketsDropped))
+		add("srt_buffer_packets", recv, float64(st.Recv.BufferPackets))
+		add("srt_buffer_bytes", recv, float64(st.Recv.BufferBytes))
+		add("srt_buffer_seconds", recv, float64(st.Recv.BufferMs)/1e3)
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countWriter{w: bw}
+	for i := range fams {
+		fams[i].write(cw)
+	}
+	fmt.Fprint(cw, "# EOF\n")
+	if cw.err == nil {
+		cw.err = bw.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func (f *family) write(w io.Writer) {
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+	if f.unit != "" {
+		fmt.Fprintf(w, "# UNIT %s %s\n", f.name, f.unit)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+	name := f.name
+	switch f.typ {
+	case "counter":
+		name += "_total"
+	case "info":
+		name += "_info"
+	}
+	sort.SliceStable(f.samples, func(i, j int) bool { return f.samples[i].labels < f.samples[j].labels })
+	for _, s := range f.samples {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, s.labels, strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats the name value pairs kv as a label set without the
+// enclosing braces.
+func labels(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(kv[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
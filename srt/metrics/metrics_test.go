@@ -0,0 +1,118 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+)
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("got content type %q; want %q", ct, ContentType)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestHandler(t *testing.T) {
+	ts := httptest.NewServer(Handler())
+	defer ts.Close()
+
+	ctx := srt.WithOptions(context.Background(), srt.Options("streamid", "#!::r=live/test"))
+	ln, err := srt.ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+			close(accepted)
+			return
+		}
+		accepted <- c
+	}()
+	var d srt.Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc, ok := <-accepted
+	if !ok {
+		t.FailNow()
+	}
+
+	c.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.Write(make([]byte, 1316)); err != nil {
+		t.Fatal(err)
+	}
+	sc.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := sc.Read(make([]byte, 1316)); err != nil {
+		t.Fatal(err)
+	}
+
+	out := scrape(t, ts.URL)
+	if !strings.HasSuffix(out, "# EOF\n") {
+		t.Errorf("missing EOF marker in\n%s", out)
+	}
+	for _, want := range []string{
+		"# TYPE srt_packets counter\n",
+		"# UNIT srt_rtt_seconds seconds\n",
+		`srt_listener_info{local_addr="` + ln.Addr().String() + `"} 1`,
+		`srt_connection_info{`,
+		`stream_id="#!::r=live/test"`,
+		`peer="` + c.RemoteAddr().String() + `"`,
+		`direction="send"`,
+		`direction="recv"`,
+		"srt_rtt_seconds{",
+		"srt_buffer_bytes{",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "srt_packets_retransmitted_total{") && strings.Contains(line, `direction="recv"`) || strings.HasPrefix(line, "srt_recv_retransmitted") {
+			t.Errorf("interval value exported: %s", line)
+		}
+	}
+
+	sc.Close()
+	c.Close()
+	ln.Close()
+	out = scrape(t, ts.URL)
+	if strings.Contains(out, "srt_connection_info{") || strings.Contains(out, "srt_listener_info{") {
+		t.Errorf("closed connections still exported in\n%s", out)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	got := labels("a", "x", "b", "q\"\\\nz")
+	want := `a="x",b="q\"\\\nz"`
+	if got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build srtmock
+
+package srt
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+func TestMockClockDelay(t *testing.T) {
+	clock := srtapi.NewMockClock(time.Unix(1000, 0))
+	srtapi.SetMockNetwork(&srtapi.MockNetwork{Clock: clock})
+	defer srtapi.SetMockNetwork(nil)
+
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			accepted <- c
+		}
+	}()
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := <-accepted
+	defer sc.Close()
+
+	srtapi.SetMockNetwork(&srtapi.MockNetwork{Clock: clock, Delay: time.Second})
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 16)
+	sc.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := sc.Read(b); err == nil || !err.(net.Error).Timeout() {
+		t.Fatalf("got %v before the delay; want a timeout", err)
+	}
+	sc.SetReadDeadline(time.Time{})
+	clock.Advance(time.Second)
+	n, err := sc.Read(b)
+	if err != nil || string(b[:n]) != "hello" {
+		t.Fatalf("got %q, %v; want hello", b[:n], err)
+	}
+	st, err := sc.(*SRTConn).Stats(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Time != time.Second {
+		t.Errorf("got connection time %v; want 1s", st.Time)
+	}
+}
+
+func TestMockClockConnTimeout(t *testing.T) {
+	clock := srtapi.NewMockClock(time.Unix(1000, 0))
+	srtapi.SetMockNetwork(&srtapi.MockNetwork{Clock: clock})
+	defer srtapi.SetMockNetwork(nil)
+
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// Nobody answers; the default connection timeout of 3s passes on
+	// the clock of the network.
+	done := make(chan error, 1)
+	go func() {
+		c, err := Dial("srt", addr)
+		if err == nil {
+			c.Close()
+		}
+		done <- err
+	}()
+	start := time.Now()
+	for {
+		select {
+		case err := <-done:
+			rerr, ok := err.(*OpError).Err.(*RejectError)
+			if !ok || rerr.Reason != RejectTimeout {
+				t.Fatalf("got %v; want %v", err, RejectTimeout)
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("took %v of real time", d)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			clock.Advance(time.Second)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// MsgCtrl holds the message control information which is sent or
+// received along with a single SRT message.
+type MsgCtrl struct {
+	// TTL is the time to live of a sent message. A message which
+	// could not be delivered within TTL is dropped by the sender.
+	// Zero or negative means infinite.
+	TTL time.Duration
+
+	// InOrder requests in-order delivery of the message. It is only
+	// used in message mode.
+	InOrder bool
+
+	// Boundary is the packet boundary flag of a message in file
+	// mode.
+	Boundary int
+
+	// SrcTime is the source time of the message in microseconds of
+	// the SRT internal clock (see SRTNow). Zero on write means that
+	// the current time is used. On read it is the sender's time
+	// restored by TSBPD.
+	SrcTime int64
+
+	// PktSeq is the sequence number of the first packet of the
+	// received message.
+	PktSeq int32
+
+	// MsgNo is the message number assigned by the sender.
+	MsgNo int32
+}
+
+func (m *MsgCtrl) toAPI() *srtapi.MsgCtrl {
+	mc := &srtapi.MsgCtrl{MsgTTL: -1, PktSeq: -1, MsgNo: -1}
+	if m == nil {
+		return mc
+	}
+	if m.TTL > 0 {
+		mc.MsgTTL = int(m.TTL / time.Millisecond)
+		if mc.MsgTTL == 0 {
+			mc.MsgTTL = 1
+		}
+	}
+	mc.InOrder = m.InOrder
+	mc.Boundary = m.Boundary
+	mc.SrcTime = m.SrcTime
+	return mc
+}
+
+func (m *MsgCtrl) fromAPI(mc *srtapi.MsgCtrl) {
+	if m == nil {
+		return
+	}
+	m.TTL = 0
+	if mc.MsgTTL > 0 {
+		m.TTL = time.Duration(mc.MsgTTL) * time.Millisecond
+	}
+	m.InOrder = mc.InOrder
+	m.Boundary = mc.Boundary
+	m.SrcTime = mc.SrcTime
+	m.PktSeq = mc.PktSeq
+	m.MsgNo = mc.MsgNo
+}
+
+// SRTNow returns the current time of the SRT internal clock in
+// microseconds. It is the time base of MsgCtrl.SrcTime.
+func SRTNow() int64 {
+	return srtapi.TimeNow()
+}
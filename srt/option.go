@@ -0,0 +1,212 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// KMState is the state of the key material exchange of an encrypted
+// connection.
+type KMState int
+
+// Key material states
+const (
+	KMUnsecured KMState = srtapi.KmStateUnsecured
+	KMSecuring  KMState = srtapi.KmStateSecuring
+	KMSecured   KMState = srtapi.KmStateSecured
+	KMNoSecret  KMState = srtapi.KmStateNosecret
+	KMBadSecret KMState = srtapi.KmStateBadsecret
+)
+
+func (s KMState) String() string {
+	switch s {
+	case KMUnsecured:
+		return "unsecured"
+	case KMSecuring:
+		return "securing"
+	case KMSecured:
+		return "secured"
+	case KMNoSecret:
+		return "no secret"
+	case KMBadSecret:
+		return "bad secret"
+	}
+	return "KMState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// GetOption returns the current value of the socket option name in
+// the string form used by Options. Besides the options that can be
+// set, the read only options "version", "peerversion", "kmstate",
+// "sndkmstate", "rcvkmstate", "snddata" and "rcvdata" are available.
+func (c *SRTConn) GetOption(name string) (string, error) {
+	if !c.ok() {
+		return "", srtapi.EINVPARAM
+	}
+	o := lookupOption(name)
+	if o == nil {
+		return "", &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: &ConfigError{Field: name, Value: "", Err: errUnknownOption}}
+	}
+	v, err := o.get(c.fd.pfd.Sysfd)
+	if err != nil {
+		return "", &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockflag", err)}
+	}
+	return v, nil
+}
+
+// SetOption sets the socket option name to value, given in the string
+// form used by Options. Only options that the library allows to change
+// on a connected socket, such as "maxbw", "inputbw" or "oheadbw", can
+// be set.
+func (c *SRTConn) SetOption(name, value string) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	o := lookupOption(name)
+	if o == nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: &ConfigError{Field: name, Value: value, Err: errUnknownOption}}
+	}
+	if o.binding == bindNone {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: &ConfigError{Field: name, Value: value, Err: errReadOnlyOption}}
+	}
+	if err := o.apply(c.fd.pfd.Sysfd, value); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+func (c *SRTConn) getInt(opt int) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	v, err := srtapi.GetsockflagInt(c.fd.pfd.Sysfd, opt)
+	if err != nil {
+		return 0, &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockflag", err)}
+	}
+	return v, nil
+}
+
+func (c *SRTConn) getInt64(opt int) (int64, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	v, err := srtapi.GetsockflagInt64(c.fd.pfd.Sysfd, opt)
+	if err != nil {
+		return 0, &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockflag", err)}
+	}
+	return v, nil
+}
+
+func (c *SRTConn) getString(opt int) (string, error) {
+	if !c.ok() {
+		return "", srtapi.EINVPARAM
+	}
+	v, err := srtapi.GetsockflagString(c.fd.pfd.Sysfd, opt)
+	if err != nil {
+		return "", &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockflag", err)}
+	}
+	return v, nil
+}
+
+func (c *SRTConn) getDuration(opt int) (time.Duration, error) {
+	ms, err := c.getInt(opt)
+	return time.Duration(ms) * time.Millisecond, err
+}
+
+func (c *SRTConn) setInt(opt int, v int) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if err := srtapi.SetsockflagInt(c.fd.pfd.Sysfd, opt, v); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("setsockflag", err)}
+	}
+	return nil
+}
+
+func (c *SRTConn) setInt64(opt int, v int64) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if err := srtapi.SetsockflagInt64(c.fd.pfd.Sysfd, opt, v); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("setsockflag", err)}
+	}
+	return nil
+}
+
+// Latency returns the negotiated receiver latency of the connection.
+func (c *SRTConn) Latency() (time.Duration, error) {
+	return c.getDuration(srtapi.OptionRcvlatency)
+}
+
+// PeerLatency returns the negotiated latency of the peer, i.e. the
+// sender latency of the connection.
+func (c *SRTConn) PeerLatency() (time.Duration, error) {
+	return c.getDuration(srtapi.OptionPeerlatency)
+}
+
+// PeerVersion returns the SRT version of the peer in the form
+// "major.minor.patch".
+func (c *SRTConn) PeerVersion() (string, error) {
+	v, err := c.getInt(srtapi.OptionPeerversion)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(v>>16&0xff) + "." + strconv.Itoa(v>>8&0xff) + "." + strconv.Itoa(v&0xff), nil
+}
+
+// KMState returns the state of the key material exchange of the
+// connection.
+func (c *SRTConn) KMState() (KMState, error) {
+	v, err := c.getInt(srtapi.OptionKmstate)
+	return KMState(v), err
+}
+
+// PayloadSize returns the maximum payload size of a packet.
+func (c *SRTConn) PayloadSize() (int, error) {
+	return c.getInt(srtapi.OptionPayloadsize)
+}
+
+// Congestion returns the name of the congestion controller.
+func (c *SRTConn) Congestion() (string, error) {
+	return c.getString(srtapi.OptionCongestion)
+}
+
+// MaxBW returns the maximum sending bandwidth in bytes per second.
+// -1 means unlimited, 0 means relative to the input bandwidth.
+func (c *SRTConn) MaxBW() (int64, error) {
+	return c.getInt64(srtapi.OptionMaxbw)
+}
+
+// SetMaxBW changes the maximum sending bandwidth of the connection in
+// bytes per second. -1 means unlimited, 0 means relative to the input
+// bandwidth (see SetInputBW and SetOheadBW).
+func (c *SRTConn) SetMaxBW(bw int64) error {
+	return c.setInt64(srtapi.OptionMaxbw, bw)
+}
+
+// InputBW returns the input rate of the sender in bytes per second.
+func (c *SRTConn) InputBW() (int64, error) {
+	return c.getInt64(srtapi.OptionInputbw)
+}
+
+// SetInputBW changes the input rate of the sender in bytes per second
+// used when the maximum bandwidth is 0. 0 means the input rate is
+// estimated.
+func (c *SRTConn) SetInputBW(bw int64) error {
+	return c.setInt64(srtapi.OptionInputbw, bw)
+}
+
+// OheadBW returns the recovery bandwidth overhead in percent.
+func (c *SRTConn) OheadBW() (int, error) {
+	return c.getInt(srtapi.OptionOheadbw)
+}
+
+// SetOheadBW changes the recovery bandwidth overhead above the input
+// rate in percent, used when the maximum bandwidth is 0.
+func (c *SRTConn) SetOheadBW(percent int) error {
+	return c.setInt(srtapi.OptionOheadbw, percent)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConnGetOption(t *testing.T) {
+	peer := func(c *SRTConn) error {
+		if _, err := c.Latency(); err != nil {
+			return err
+		}
+		v, err := c.PeerVersion()
+		if err != nil {
+			return err
+		}
+		if strings.Count(v, ".") != 2 || v == "0.0.0" {
+			return fmt.Errorf("got peer version %q", v)
+		}
+		state, err := c.KMState()
+		if err != nil {
+			return err
+		}
+		if state != KMUnsecured {
+			return fmt.Errorf("got key material state %v; want %v", state, KMUnsecured)
+		}
+		if cc, err := c.Congestion(); err != nil || cc != "live" {
+			return fmt.Errorf("got congestion %q, %v; want live", cc, err)
+		}
+		if _, err := c.GetOption("peerversion"); err != nil {
+			return err
+		}
+		if _, err := c.GetOption("nonexistent"); err == nil {
+			return fmt.Errorf("got no error for an unknown option")
+		}
+		return nil
+	}
+	withSRTConnPair(t, peer, peer)
+}
+
+func TestConnSetOption(t *testing.T) {
+	server := func(c *SRTConn) error {
+		return nil
+	}
+	client := func(c *SRTConn) error {
+		if err := c.SetMaxBW(1000000); err != nil {
+			return err
+		}
+		if bw, err := c.MaxBW(); err != nil || bw != 1000000 {
+			return fmt.Errorf("got maxbw %d, %v; want 1000000", bw, err)
+		}
+		if err := c.SetOption("maxbw", "-1"); err != nil {
+			return err
+		}
+		if v, err := c.GetOption("maxbw"); err != nil || v != "-1" {
+			return fmt.Errorf("got maxbw %q, %v; want -1", v, err)
+		}
+		if err := c.SetOheadBW(50); err != nil {
+			return err
+		}
+		if err := c.SetOption("peerversion", "1"); err == nil {
+			return fmt.Errorf("got no error for a read only option")
+		}
+		err := c.SetOption("inputbw", "fast")
+		if oe, ok := err.(*OpError); !ok {
+			return fmt.Errorf("got %v; want *OpError", err)
+		} else if _, ok := oe.Err.(*ConfigError); !ok {
+			return fmt.Errorf("got %v; want *ConfigError", oe.Err)
+		} else if oe.Source != c.LocalAddr() || oe.Addr != c.RemoteAddr() {
+			return fmt.Errorf("got %v->%v; want %v->%v", oe.Source, oe.Addr, c.LocalAddr(), c.RemoteAddr())
+		}
+		return nil
+	}
+	withSRTConnPair(t, server, client)
+}
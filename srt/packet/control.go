@@ -0,0 +1,376 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ControlType is the type of a control packet.
+type ControlType uint16
+
+// Control packet types
+const (
+	TypeHandshake         ControlType = 0
+	TypeKeepalive         ControlType = 1
+	TypeACK               ControlType = 2
+	TypeNAK               ControlType = 3
+	TypeCongestionWarning ControlType = 4
+	TypeShutdown          ControlType = 5
+	TypeACKACK            ControlType = 6
+	TypeDropReq           ControlType = 7
+	TypePeerError         ControlType = 8
+	TypeUserDefined       ControlType = 0x7fff
+)
+
+var controlTypeNames = map[ControlType]string{
+	TypeHandshake:         "HANDSHAKE",
+	TypeKeepalive:         "KEEPALIVE",
+	TypeACK:               "ACK",
+	TypeNAK:               "NAK",
+	TypeCongestionWarning: "CGWARNING",
+	TypeShutdown:          "SHUTDOWN",
+	TypeACKACK:            "ACKACK",
+	TypeDropReq:           "DROPREQ",
+	TypePeerError:         "PEERERROR",
+	TypeUserDefined:       "USERDEFINED",
+}
+
+func (t ControlType) String() string {
+	if s, ok := controlTypeNames[t]; ok {
+		return s
+	}
+	return "ControlType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Control is a control packet of a type this package has no own type
+// for, e.g. the HSv4 handshake extensions sent as TypeUserDefined.
+type Control struct {
+	Header
+
+	Type    ControlType
+	Subtype uint16
+
+	// Info is the type-specific information of the header.
+	Info uint32
+
+	// CIF is the control information field following the header.
+	CIF []byte
+}
+
+// MarshalBinary encodes c into the wire format.
+func (c *Control) MarshalBinary() ([]byte, error) {
+	if c.Type > 0x7fff {
+		return nil, errors.New("packet: control type out of range")
+	}
+	return marshalControl(&c.Header, c.Type, c.Subtype, c.Info, c.CIF), nil
+}
+
+func (c *Control) String() string {
+	return fmt.Sprintf("%v subtype=%d info=%d len=%d %v", c.Type, c.Subtype, c.Info, len(c.CIF), &c.Header)
+}
+
+// pad is the control information field libsrt sends with the control
+// packets that have none.
+var pad = []byte{0, 0, 0, 0}
+
+func marshalControl(h *Header, typ ControlType, subtype uint16, info uint32, cif []byte) []byte {
+	b := make([]byte, HeaderLen+len(cif))
+	binary.BigEndian.PutUint32(b, controlFlag|uint32(typ)<<16|uint32(subtype))
+	binary.BigEndian.PutUint32(b[4:], info)
+	binary.BigEndian.PutUint32(b[8:], h.Timestamp)
+	binary.BigEndian.PutUint32(b[12:], h.DestSocketID)
+	copy(b[HeaderLen:], cif)
+	return b
+}
+
+func parseControl(b []byte) (Packet, error) {
+	w0 := binary.BigEndian.Uint32(b)
+	c := &Control{
+		Header: Header{
+			Timestamp:    binary.BigEndian.Uint32(b[8:]),
+			DestSocketID: binary.BigEndian.Uint32(b[12:]),
+		},
+		Type:    ControlType(w0 >> 16 & 0x7fff),
+		Subtype: uint16(w0),
+		Info:    binary.BigEndian.Uint32(b[4:]),
+		CIF:     b[HeaderLen:],
+	}
+	switch c.Type {
+	case TypeHandshake:
+		return parseHandshake(c)
+	case TypeKeepalive:
+		return &Keepalive{Header: c.Header}, nil
+	case TypeACK:
+		return parseACK(c)
+	case TypeNAK:
+		return parseNAK(c)
+	case TypeCongestionWarning:
+		return &CongestionWarning{Header: c.Header}, nil
+	case TypeShutdown:
+		return &Shutdown{Header: c.Header}, nil
+	case TypeACKACK:
+		return &ACKACK{Header: c.Header, AckNo: c.Info}, nil
+	case TypeDropReq:
+		return parseDropReq(c)
+	case TypePeerError:
+		return &PeerError{Header: c.Header, Code: c.Info}, nil
+	}
+	return c, nil
+}
+
+// cifWords returns the control information field of c as 32 bit words.
+func cifWords(c *Control) []uint32 {
+	w := make([]uint32, len(c.CIF)/4)
+	for i := range w {
+		w[i] = binary.BigEndian.Uint32(c.CIF[4*i:])
+	}
+	return w
+}
+
+func putWords(w []uint32) []byte {
+	b := make([]byte, 4*len(w))
+	for i, v := range w {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// Keepalive is sent when there was no other packet for a while.
+type Keepalive struct {
+	Header
+}
+
+// MarshalBinary encodes k into the wire format.
+func (k *Keepalive) MarshalBinary() ([]byte, error) {
+	return marshalControl(&k.Header, TypeKeepalive, 0, 0, pad), nil
+}
+
+func (k *Keepalive) String() string { return "KEEPALIVE " + k.Header.String() }
+
+// Shutdown closes the connection.
+type Shutdown struct {
+	Header
+}
+
+// MarshalBinary encodes s into the wire format.
+func (s *Shutdown) MarshalBinary() ([]byte, error) {
+	return marshalControl(&s.Header, TypeShutdown, 0, 0, pad), nil
+}
+
+func (s *Shutdown) String() string { return "SHUTDOWN " + s.Header.String() }
+
+// CongestionWarning asks the sender to slow down.
+type CongestionWarning struct {
+	Header
+}
+
+// MarshalBinary encodes w into the wire format.
+func (w *CongestionWarning) MarshalBinary() ([]byte, error) {
+	return marshalControl(&w.Header, TypeCongestionWarning, 0, 0, pad), nil
+}
+
+func (w *CongestionWarning) String() string { return "CGWARNING " + w.Header.String() }
+
+// PeerError reports an error of the peer, e.g. of a file write.
+type PeerError struct {
+	Header
+	Code uint32
+}
+
+// MarshalBinary encodes e into the wire format.
+func (e *PeerError) MarshalBinary() ([]byte, error) {
+	return marshalControl(&e.Header, TypePeerError, 0, e.Code, pad), nil
+}
+
+func (e *PeerError) String() string {
+	return fmt.Sprintf("PEERERROR code=%d %v", e.Code, &e.Header)
+}
+
+// ACK acknowledges the packets received so far.
+type ACK struct {
+	Header
+
+	// AckNo numbers the full ACKs, so that the ACKACK answering
+	// one can be matched.
+	AckNo uint32
+
+	// LastAckSeq is the sequence number of the packet following the
+	// last acknowledged one.
+	LastAckSeq uint32
+
+	// Light is set for a light ACK, which has no other fields.
+	Light bool
+
+	// RTT and RTTVar are the round trip time and its variance, in
+	// microseconds.
+	RTT    uint32
+	RTTVar uint32
+
+	// AvailBuf is the free space of the receiver buffer, in packets.
+	AvailBuf uint32
+
+	// PacketRate is the receiving rate in packets per second,
+	// LinkCapacity the estimated link capacity in packets per
+	// second, and ByteRate the receiving rate in bytes per second.
+	PacketRate   uint32
+	LinkCapacity uint32
+	ByteRate     uint32
+}
+
+func parseACK(c *Control) (*ACK, error) {
+	w := cifWords(c)
+	if len(w) < 1 {
+		return nil, errTruncated
+	}
+	a := &ACK{Header: c.Header, AckNo: c.Info, LastAckSeq: w[0]}
+	if len(w) == 1 {
+		a.Light = true
+		return a, nil
+	}
+	if len(w) < 4 {
+		return nil, errTruncated
+	}
+	a.RTT, a.RTTVar, a.AvailBuf = w[1], w[2], w[3]
+	if len(w) >= 7 {
+		a.PacketRate, a.LinkCapacity, a.ByteRate = w[4], w[5], w[6]
+	}
+	return a, nil
+}
+
+// MarshalBinary encodes a into the wire format. A light ACK has only
+// LastAckSeq, all other ACKs have every field.
+func (a *ACK) MarshalBinary() ([]byte, error) {
+	w := []uint32{a.LastAckSeq}
+	if !a.Light {
+		w = append(w, a.RTT, a.RTTVar, a.AvailBuf, a.PacketRate, a.LinkCapacity, a.ByteRate)
+	}
+	return marshalControl(&a.Header, TypeACK, 0, a.AckNo, putWords(w)), nil
+}
+
+func (a *ACK) String() string {
+	if a.Light {
+		return fmt.Sprintf("ACK light seq=%d %v", a.LastAckSeq, &a.Header)
+	}
+	return fmt.Sprintf("ACK no=%d seq=%d rtt=%d/%d buf=%d rate=%d/%d bw=%d %v",
+		a.AckNo, a.LastAckSeq, a.RTT, a.RTTVar, a.AvailBuf, a.PacketRate, a.ByteRate, a.LinkCapacity, &a.Header)
+}
+
+// ACKACK answers a full ACK.
+type ACKACK struct {
+	Header
+	AckNo uint32
+}
+
+// MarshalBinary encodes a into the wire format.
+func (a *ACKACK) MarshalBinary() ([]byte, error) {
+	return marshalControl(&a.Header, TypeACKACK, 0, a.AckNo, pad), nil
+}
+
+func (a *ACKACK) String() string {
+	return fmt.Sprintf("ACKACK no=%d %v", a.AckNo, &a.Header)
+}
+
+// SeqRange is a range of sequence numbers from First to Last,
+// inclusive.
+type SeqRange struct {
+	First, Last uint32
+}
+
+func (r SeqRange) String() string {
+	if r.First == r.Last {
+		return strconv.FormatUint(uint64(r.First), 10)
+	}
+	return strconv.FormatUint(uint64(r.First), 10) + "-" + strconv.FormatUint(uint64(r.Last), 10)
+}
+
+// Len returns the number of sequence numbers in r.
+func (r SeqRange) Len() int {
+	return int(SeqDiff(r.First, r.Last)) + 1
+}
+
+// NAK reports lost packets.
+type NAK struct {
+	Header
+	Loss []SeqRange
+}
+
+func parseNAK(c *Control) (*NAK, error) {
+	w := cifWords(c)
+	n := &NAK{Header: c.Header}
+	for i := 0; i < len(w); i++ {
+		if w[i]&controlFlag == 0 {
+			n.Loss = append(n.Loss, SeqRange{w[i], w[i]})
+			continue
+		}
+		if i+1 == len(w) {
+			return nil, errTruncated
+		}
+		n.Loss = append(n.Loss, SeqRange{w[i] & seqMask, w[i+1] & seqMask})
+		i++
+	}
+	return n, nil
+}
+
+// MarshalBinary encodes n into the wire format.
+func (n *NAK) MarshalBinary() ([]byte, error) {
+	var w []uint32
+	for _, r := range n.Loss {
+		if r.First > seqMask || r.Last > seqMask {
+			return nil, errors.New("packet: sequence number out of range")
+		}
+		if r.First == r.Last {
+			w = append(w, r.First)
+		} else {
+			w = append(w, r.First|controlFlag, r.Last)
+		}
+	}
+	return marshalControl(&n.Header, TypeNAK, 0, 0, putWords(w)), nil
+}
+
+// Lost returns the number of packets reported lost by n.
+func (n *NAK) Lost() int {
+	lost := 0
+	for _, r := range n.Loss {
+		lost += r.Len()
+	}
+	return lost
+}
+
+func (n *NAK) String() string {
+	loss := make([]string, len(n.Loss))
+	for i, r := range n.Loss {
+		loss[i] = r.String()
+	}
+	return fmt.Sprintf("NAK loss=[%s] %v", strings.Join(loss, " "), &n.Header)
+}
+
+// DropReq asks the receiver to stop waiting for the packets of a
+// message, which the sender dropped.
+type DropReq struct {
+	Header
+	MsgNo uint32
+	Range SeqRange
+}
+
+func parseDropReq(c *Control) (*DropReq, error) {
+	w := cifWords(c)
+	if len(w) < 2 {
+		return nil, errTruncated
+	}
+	return &DropReq{Header: c.Header, MsgNo: c.Info, Range: SeqRange{w[0], w[1]}}, nil
+}
+
+// MarshalBinary encodes d into the wire format.
+func (d *DropReq) MarshalBinary() ([]byte, error) {
+	return marshalControl(&d.Header, TypeDropReq, 0, d.MsgNo, putWords([]uint32{d.Range.First, d.Range.Last})), nil
+}
+
+func (d *DropReq) String() string {
+	return fmt.Sprintf("DROPREQ msg=%d seq=%v %v", d.MsgNo, d.Range, &d.Header)
+}
@@ -0,0 +1,604 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// handshakeLen is the length of the handshake control information
+// without extensions.
+const handshakeLen = 48
+
+// HandshakeType is the type of a handshake packet. Values from
+// HandshakeReject up are rejections, with the reject reason added.
+type HandshakeType uint32
+
+// Handshake types
+const (
+	HandshakeWaveahand  HandshakeType = 0
+	HandshakeInduction  HandshakeType = 1
+	HandshakeDone       HandshakeType = 0xfffffffd
+	HandshakeAgreement  HandshakeType = 0xfffffffe
+	HandshakeConclusion HandshakeType = 0xffffffff
+	HandshakeReject     HandshakeType = 1000
+)
+
+// IsReject reports whether t rejects the connection.
+func (t HandshakeType) IsReject() bool {
+	return t >= HandshakeReject && t < HandshakeDone
+}
+
+// RejectReason returns the reject reason of a rejection, as in
+// srtapi.RejectReasonString.
+func (t HandshakeType) RejectReason() int {
+	if !t.IsReject() {
+		return 0
+	}
+	return int(t - HandshakeReject)
+}
+
+func (t HandshakeType) String() string {
+	switch t {
+	case HandshakeWaveahand:
+		return "waveahand"
+	case HandshakeInduction:
+		return "induction"
+	case HandshakeDone:
+		return "done"
+	case HandshakeAgreement:
+		return "agreement"
+	case HandshakeConclusion:
+		return "conclusion"
+	}
+	if t.IsReject() {
+		return "reject(" + strconv.Itoa(t.RejectReason()) + ")"
+	}
+	return "HandshakeType(" + strconv.FormatUint(uint64(t), 10) + ")"
+}
+
+// HSMagic is the extension field of the induction response of an HSv5
+// listener.
+const HSMagic = 0x4a17
+
+// ExtField flags of an HSv5 conclusion handshake
+const (
+	HSExtHSREQ  = 0x1
+	HSExtKMREQ  = 0x2
+	HSExtConfig = 0x4
+)
+
+// Handshake is a handshake packet.
+type Handshake struct {
+	Header
+
+	// Version is the handshake version, 4 or 5.
+	Version uint32
+
+	// Encryption is the key length of the cipher divided by 8, or 0
+	// without encryption.
+	Encryption uint16
+
+	// ExtField is HSMagic or a combination of the HSExt flags.
+	ExtField uint16
+
+	// ISN is the initial packet sequence number.
+	ISN uint32
+
+	MTU        uint32
+	FlowWindow uint32
+
+	Type HandshakeType
+
+	// SocketID is the SRT socket id of the sender.
+	SocketID uint32
+
+	Cookie uint32
+
+	// PeerIP is the address of the receiver as seen by the sender.
+	PeerIP net.IP
+
+	// Extensions are the HSv5 handshake extensions.
+	Extensions []Extension
+}
+
+// peerIP decodes the peer address of a handshake. libsrt writes each
+// 32 bit word of the address in little endian byte order.
+func peerIP(b []byte) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	for i := 0; i < net.IPv6len; i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	for _, c := range ip[4:] {
+		if c != 0 {
+			return ip
+		}
+	}
+	return net.IPv4(ip[0], ip[1], ip[2], ip[3])
+}
+
+func putPeerIP(b []byte, ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = make(net.IP, net.IPv6len)
+		copy(ip, ip4)
+	} else if len(ip) != net.IPv6len {
+		return
+	}
+	for i := 0; i < net.IPv6len; i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+}
+
+func parseHandshake(c *Control) (*Handshake, error) {
+	b := c.CIF
+	if len(b) < handshakeLen {
+		return nil, errTruncated
+	}
+	h := &Handshake{
+		Header:     c.Header,
+		Version:    binary.BigEndian.Uint32(b),
+		Encryption: binary.BigEndian.Uint16(b[4:]),
+		ExtField:   binary.BigEndian.Uint16(b[6:]),
+		ISN:        binary.BigEndian.Uint32(b[8:]),
+		MTU:        binary.BigEndian.Uint32(b[12:]),
+		FlowWindow: binary.BigEndian.Uint32(b[16:]),
+		Type:       HandshakeType(binary.BigEndian.Uint32(b[20:])),
+		SocketID:   binary.BigEndian.Uint32(b[24:]),
+		Cookie:     binary.BigEndian.Uint32(b[28:]),
+		PeerIP:     peerIP(b[32:48]),
+	}
+	if h.Version < 5 {
+		return h, nil
+	}
+	for b = b[handshakeLen:]; len(b) > 0; {
+		if len(b) < 4 {
+			return nil, errTruncated
+		}
+		typ := ExtType(binary.BigEndian.Uint16(b))
+		n := 4 + 4*int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < n {
+			return nil, errTruncated
+		}
+		ext, err := parseExtension(typ, b[4:n])
+		if err != nil {
+			return nil, err
+		}
+		h.Extensions = append(h.Extensions, ext)
+		b = b[n:]
+	}
+	return h, nil
+}
+
+// MarshalBinary encodes h into the wire format.
+func (h *Handshake) MarshalBinary() ([]byte, error) {
+	b := make([]byte, handshakeLen)
+	binary.BigEndian.PutUint32(b, h.Version)
+	binary.BigEndian.PutUint16(b[4:], h.Encryption)
+	binary.BigEndian.PutUint16(b[6:], h.ExtField)
+	binary.BigEndian.PutUint32(b[8:], h.ISN)
+	binary.BigEndian.PutUint32(b[12:], h.MTU)
+	binary.BigEndian.PutUint32(b[16:], h.FlowWindow)
+	binary.BigEndian.PutUint32(b[20:], uint32(h.Type))
+	binary.BigEndian.PutUint32(b[24:], h.SocketID)
+	binary.BigEndian.PutUint32(b[28:], h.Cookie)
+	putPeerIP(b[32:], h.PeerIP)
+	for _, ext := range h.Extensions {
+		data, err := ext.marshal()
+		if err != nil {
+			return nil, err
+		}
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+		if len(data)/4 > 0xffff {
+			return nil, errors.New("packet: handshake extension too long")
+		}
+		var eh [4]byte
+		binary.BigEndian.PutUint16(eh[:], uint16(ext.Type()))
+		binary.BigEndian.PutUint16(eh[2:], uint16(len(data)/4))
+		b = append(b, eh[:]...)
+		b = append(b, data...)
+	}
+	return marshalControl(&h.Header, TypeHandshake, 0, 0, b), nil
+}
+
+// Extension returns the first extension of type t, or nil.
+func (h *Handshake) Extension(t ExtType) Extension {
+	for _, ext := range h.Extensions {
+		if ext.Type() == t {
+			return ext
+		}
+	}
+	return nil
+}
+
+// StreamID returns the stream ID sent with h, or "".
+func (h *Handshake) StreamID() string {
+	if s, ok := h.Extension(ExtSID).(*StringExt); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func (h *Handshake) String() string {
+	s := fmt.Sprintf("HANDSHAKE v%d %v sock=%#x cookie=%#x isn=%d mtu=%d fw=%d peer=%v",
+		h.Version, h.Type, h.SocketID, h.Cookie, h.ISN, h.MTU, h.FlowWindow, h.PeerIP)
+	if h.Encryption != 0 {
+		s += " aes-" + strconv.Itoa(int(h.Encryption)*64)
+	}
+	if h.ExtField == HSMagic {
+		s += " magic"
+	} else if h.ExtField != 0 {
+		s += fmt.Sprintf(" ext=%#x", h.ExtField)
+	}
+	s += " " + h.Header.String()
+	for _, ext := range h.Extensions {
+		s += " [" + ext.String() + "]"
+	}
+	return s
+}
+
+// ExtType is the type of a handshake extension.
+type ExtType uint16
+
+// Handshake extension types
+const (
+	ExtHSREQ      ExtType = 1
+	ExtHSRSP      ExtType = 2
+	ExtKMREQ      ExtType = 3
+	ExtKMRSP      ExtType = 4
+	ExtSID        ExtType = 5
+	ExtCongestion ExtType = 6
+	ExtFilter     ExtType = 7
+	ExtGroup      ExtType = 8
+)
+
+var extTypeNames = map[ExtType]string{
+	ExtHSREQ:      "HSREQ",
+	ExtHSRSP:      "HSRSP",
+	ExtKMREQ:      "KMREQ",
+	ExtKMRSP:      "KMRSP",
+	ExtSID:        "SID",
+	ExtCongestion: "CONGESTION",
+	ExtFilter:     "FILTER",
+	ExtGroup:      "GROUP",
+}
+
+func (t ExtType) String() string {
+	if s, ok := extTypeNames[t]; ok {
+		return s
+	}
+	return "ExtType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// An Extension is an HSv5 handshake extension. It is one of
+// *HSExt, *KMExt, *StringExt, *GroupExt and *RawExt.
+type Extension interface {
+	Type() ExtType
+	String() string
+
+	// marshal returns the contents of the extension.
+	marshal() ([]byte, error)
+}
+
+func parseExtension(t ExtType, b []byte) (Extension, error) {
+	switch t {
+	case ExtHSREQ, ExtHSRSP:
+		if len(b) < 12 {
+			return nil, errTruncated
+		}
+		return &HSExt{
+			Cmd:       t,
+			Version:   binary.BigEndian.Uint32(b),
+			Flags:     HSFlags(binary.BigEndian.Uint32(b[4:])),
+			RecvDelay: binary.BigEndian.Uint16(b[8:]),
+			SendDelay: binary.BigEndian.Uint16(b[10:]),
+		}, nil
+	case ExtKMREQ, ExtKMRSP:
+		if t == ExtKMRSP && len(b) == 4 {
+			return &KMExt{Cmd: t, State: binary.BigEndian.Uint32(b)}, nil
+		}
+		km, err := parseKeyMaterial(b)
+		if err != nil {
+			return nil, err
+		}
+		return &KMExt{Cmd: t, KM: km}, nil
+	case ExtSID, ExtCongestion, ExtFilter:
+		return &StringExt{Cmd: t, Value: wordString(b)}, nil
+	case ExtGroup:
+		if len(b) < 8 {
+			return nil, errTruncated
+		}
+		return &GroupExt{
+			ID:        binary.BigEndian.Uint32(b),
+			GroupType: b[4],
+			Flags:     b[5],
+			Weight:    binary.BigEndian.Uint16(b[6:]),
+		}, nil
+	}
+	return &RawExt{Cmd: t, Data: b}, nil
+}
+
+// HSFlags are the SRT flags of an HSREQ or HSRSP extension.
+type HSFlags uint32
+
+// SRT flags
+const (
+	FlagTSBPDSnd     HSFlags = 0x01
+	FlagTSBPDRcv     HSFlags = 0x02
+	FlagCrypt        HSFlags = 0x04
+	FlagTLPktDrop    HSFlags = 0x08
+	FlagPeriodicNAK  HSFlags = 0x10
+	FlagRexmitFlag   HSFlags = 0x20
+	FlagStream       HSFlags = 0x40
+	FlagPacketFilter HSFlags = 0x80
+)
+
+var hsFlagNames = []string{"tsbpdsnd", "tsbpdrcv", "crypt", "tlpktdrop", "periodicnak", "rexmitflg", "stream", "filter"}
+
+func (f HSFlags) String() string {
+	var s []string
+	for i, name := range hsFlagNames {
+		if f&(1<<uint(i)) != 0 {
+			s = append(s, name)
+		}
+	}
+	if rest := f &^ (1<<uint(len(hsFlagNames)) - 1); rest != 0 {
+		s = append(s, fmt.Sprintf("%#x", uint32(rest)))
+	}
+	return strings.Join(s, "|")
+}
+
+// HSExt is an HSREQ or HSRSP extension, which exchanges the SRT
+// version, flags and latencies.
+type HSExt struct {
+	// Cmd is ExtHSREQ or ExtHSRSP.
+	Cmd ExtType
+
+	// Version is the SRT library version, e.g. 0x010403 for 1.4.3.
+	Version uint32
+
+	Flags HSFlags
+
+	// RecvDelay and SendDelay are the TSBPD latencies of the
+	// receiver and the sender, in milliseconds.
+	RecvDelay uint16
+	SendDelay uint16
+}
+
+// Type returns e.Cmd.
+func (e *HSExt) Type() ExtType { return e.Cmd }
+
+func (e *HSExt) marshal() ([]byte, error) {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint32(b, e.Version)
+	binary.BigEndian.PutUint32(b[4:], uint32(e.Flags))
+	binary.BigEndian.PutUint16(b[8:], e.RecvDelay)
+	binary.BigEndian.PutUint16(b[10:], e.SendDelay)
+	return b, nil
+}
+
+func (e *HSExt) String() string {
+	return fmt.Sprintf("%v version=%d.%d.%d flags=%v latency=%d/%d", e.Cmd,
+		e.Version>>16, e.Version>>8&0xff, e.Version&0xff, e.Flags, e.RecvDelay, e.SendDelay)
+}
+
+// StringExt is an extension holding a string: the stream ID, the
+// congestion controller or the packet filter configuration.
+type StringExt struct {
+	// Cmd is ExtSID, ExtCongestion or ExtFilter.
+	Cmd   ExtType
+	Value string
+}
+
+// Type returns e.Cmd.
+func (e *StringExt) Type() ExtType { return e.Cmd }
+
+func (e *StringExt) marshal() ([]byte, error) {
+	return putWordString(e.Value), nil
+}
+
+func (e *StringExt) String() string {
+	return fmt.Sprintf("%v %q", e.Cmd, e.Value)
+}
+
+// wordString decodes a string extension. libsrt writes each 32 bit
+// word of it in little endian byte order and pads it with zeros.
+func wordString(b []byte) string {
+	s := make([]byte, 0, len(b))
+	for i := 0; i+4 <= len(b); i += 4 {
+		s = append(s, b[i+3], b[i+2], b[i+1], b[i])
+	}
+	for len(s) > 0 && s[len(s)-1] == 0 {
+		s = s[:len(s)-1]
+	}
+	return string(s)
+}
+
+func putWordString(s string) []byte {
+	b := make([]byte, (len(s)+3)/4*4)
+	copy(b, s)
+	for i := 0; i < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return b
+}
+
+// GroupExt is the extension of a member connection of a socket group.
+type GroupExt struct {
+	// ID is the SRT socket id of the group.
+	ID        uint32
+	GroupType uint8
+	Flags     uint8
+	Weight    uint16
+}
+
+// Type returns ExtGroup.
+func (e *GroupExt) Type() ExtType { return ExtGroup }
+
+func (e *GroupExt) marshal() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, e.ID)
+	b[4], b[5] = e.GroupType, e.Flags
+	binary.BigEndian.PutUint16(b[6:], e.Weight)
+	return b, nil
+}
+
+func (e *GroupExt) String() string {
+	return fmt.Sprintf("GROUP id=%#x type=%d flags=%#x weight=%d", e.ID, e.GroupType, e.Flags, e.Weight)
+}
+
+// RawExt is an extension of a type this package doesn't decode.
+type RawExt struct {
+	Cmd  ExtType
+	Data []byte
+}
+
+// Type returns e.Cmd.
+func (e *RawExt) Type() ExtType { return e.Cmd }
+
+func (e *RawExt) marshal() ([]byte, error) { return e.Data, nil }
+
+func (e *RawExt) String() string {
+	return fmt.Sprintf("%v len=%d", e.Cmd, len(e.Data))
+}
+
+// KMExt is a KMREQ or KMRSP extension, which carries the key material
+// of an encrypted connection.
+type KMExt struct {
+	// Cmd is ExtKMREQ or ExtKMRSP.
+	Cmd ExtType
+
+	// KM is the key material. It is nil in a KMRSP which reports an
+	// error in State instead.
+	KM *KeyMaterial
+
+	// State is the KM state of a failed KMRSP, like
+	// srtapi.KmStateBadsecret.
+	State uint32
+}
+
+// Type returns e.Cmd.
+func (e *KMExt) Type() ExtType { return e.Cmd }
+
+func (e *KMExt) marshal() ([]byte, error) {
+	if e.KM == nil {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, e.State)
+		return b, nil
+	}
+	return e.KM.MarshalBinary()
+}
+
+func (e *KMExt) String() string {
+	if e.KM == nil {
+		return fmt.Sprintf("%v state=%d", e.Cmd, e.State)
+	}
+	return e.Cmd.String() + " " + e.KM.String()
+}
+
+// Key material message constants
+const (
+	kmVersion    = 1
+	kmPacketType = 2
+	kmSign       = 0x2029
+	kmHeaderLen  = 16
+	kmWrapLen    = 8
+)
+
+// Ciphers of the key material
+const (
+	CipherNone   = 0
+	CipherAESECB = 1
+	CipherAESCTR = 2
+	CipherAESCBC = 3
+	CipherAESGCM = 4
+)
+
+// KeyMaterial is a key material message, which carries the stream
+// encryption keys wrapped with the key derived from the passphrase.
+type KeyMaterial struct {
+	// Key tells which keys are carried, KeyEven, KeyOdd or KeyBoth.
+	Key KeyFlag
+
+	// KEKI is the key encryption key index, 0 for the default key.
+	KEKI uint32
+
+	Cipher uint8
+	Auth   uint8
+
+	// SE is the stream encapsulation, 2 for SRT.
+	SE uint8
+
+	Salt []byte
+
+	// KeyLen is the length of a stream encryption key.
+	KeyLen int
+
+	// Wrap holds the wrapped keys, with the 8 byte integrity check
+	// value first.
+	Wrap []byte
+}
+
+func parseKeyMaterial(b []byte) (*KeyMaterial, error) {
+	if len(b) < kmHeaderLen {
+		return nil, errTruncated
+	}
+	if b[0]>>4&7 != kmVersion || b[0]&0xf != kmPacketType || binary.BigEndian.Uint16(b[1:]) != kmSign {
+		return nil, errors.New("packet: not a key material message")
+	}
+	km := &KeyMaterial{
+		Key:    KeyFlag(b[3] & 3),
+		KEKI:   binary.BigEndian.Uint32(b[4:]),
+		Cipher: b[8],
+		Auth:   b[9],
+		SE:     b[10],
+		KeyLen: 4 * int(b[15]),
+	}
+	slen := 4 * int(b[14])
+	n := kmHeaderLen + slen + kmWrapLen + km.keys()*km.KeyLen
+	if len(b) < n {
+		return nil, errTruncated
+	}
+	km.Salt = b[kmHeaderLen : kmHeaderLen+slen]
+	km.Wrap = b[kmHeaderLen+slen : n]
+	return km, nil
+}
+
+// keys returns the number of keys in km.
+func (km *KeyMaterial) keys() int {
+	if km.Key == KeyBoth {
+		return 2
+	}
+	return 1
+}
+
+// MarshalBinary encodes km into the wire format.
+func (km *KeyMaterial) MarshalBinary() ([]byte, error) {
+	if len(km.Salt)%4 != 0 || len(km.Salt) > 4*0xff || km.KeyLen%4 != 0 || km.KeyLen > 4*0xff {
+		return nil, errors.New("packet: invalid salt or key length")
+	}
+	if len(km.Wrap) != kmWrapLen+km.keys()*km.KeyLen {
+		return nil, errors.New("packet: wrapped keys do not match the key length")
+	}
+	b := make([]byte, kmHeaderLen, kmHeaderLen+len(km.Salt)+len(km.Wrap))
+	b[0] = kmVersion<<4 | kmPacketType
+	binary.BigEndian.PutUint16(b[1:], kmSign)
+	b[3] = byte(km.Key & 3)
+	binary.BigEndian.PutUint32(b[4:], km.KEKI)
+	b[8], b[9], b[10] = km.Cipher, km.Auth, km.SE
+	b[14] = byte(len(km.Salt) / 4)
+	b[15] = byte(km.KeyLen / 4)
+	b = append(b, km.Salt...)
+	return append(b, km.Wrap...), nil
+}
+
+func (km *KeyMaterial) String() string {
+	return fmt.Sprintf("key=%v keki=%d cipher=%d keylen=%d salt=%x", km.Key, km.KEKI, km.Cipher, km.KeyLen, km.Salt)
+}
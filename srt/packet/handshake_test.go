@@ -0,0 +1,171 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package packet
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// conclusionWire is the conclusion request of an HSv5 caller with
+// encryption, a stream ID and a group membership.
+const conclusionWire = `
+80000000 00000000 00000100 00000000
+00000005 00020007 12345678 000005dc 00002000 ffffffff 0badcafe 7a69a3b1
+0100007f 00000000 00000000 00000000
+0001 0003 00010403 000000bf 00780050
+0003 000e 12202901 00000000 02000200 00000404
+          00010203 04050607 08090a0b 0c0d0e0f
+          a0a1a2a3 a4a5a6a7 a8a9aaab acadaeaf b0b1b2b3 b4b5b6b7
+0005 0003 3a3a2123 62613d72 00000063
+0008 0002 40000001 01000001
+`
+
+func TestHandshakeConclusion(t *testing.T) {
+	b := mustHex(t, conclusionWire)
+	p, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, ok := p.(*Handshake)
+	if !ok {
+		t.Fatalf("got %T; want *Handshake", p)
+	}
+	want := &Handshake{
+		Header:     Header{Timestamp: 0x100},
+		Version:    5,
+		Encryption: 2,
+		ExtField:   HSExtHSREQ | HSExtKMREQ | HSExtConfig,
+		ISN:        0x12345678,
+		MTU:        1500,
+		FlowWindow: 8192,
+		Type:       HandshakeConclusion,
+		SocketID:   0x0badcafe,
+		Cookie:     0x7a69a3b1,
+		PeerIP:     net.IPv4(127, 0, 0, 1),
+		Extensions: []Extension{
+			&HSExt{
+				Cmd:       ExtHSREQ,
+				Version:   0x010403,
+				Flags:     FlagTSBPDSnd | FlagTSBPDRcv | FlagCrypt | FlagTLPktDrop | FlagPeriodicNAK | FlagRexmitFlag | FlagPacketFilter,
+				RecvDelay: 120,
+				SendDelay: 80,
+			},
+			&KMExt{
+				Cmd: ExtKMREQ,
+				KM: &KeyMaterial{
+					Key:    KeyEven,
+					Cipher: CipherAESCTR,
+					SE:     2,
+					Salt:   mustHex(t, "000102030405060708090a0b0c0d0e0f"),
+					KeyLen: 16,
+					Wrap:   mustHex(t, "a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7"),
+				},
+			},
+			&StringExt{Cmd: ExtSID, Value: "#!::r=abc"},
+			&GroupExt{ID: 0x40000001, GroupType: 1, Weight: 1},
+		},
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("got %v; want %v", h, want)
+	}
+	if got := h.StreamID(); got != "#!::r=abc" {
+		t.Errorf("got stream ID %q; want %q", got, "#!::r=abc")
+	}
+	if h.Extension(ExtFilter) != nil {
+		t.Error("got a filter extension; want none")
+	}
+	s := h.String()
+	for _, sub := range []string{"v5 conclusion", "peer=127.0.0.1", "aes-128", "HSREQ version=1.4.3", "latency=120/80", `SID "#!::r=abc"`} {
+		if !strings.Contains(s, sub) {
+			t.Errorf("missing %q in %s", sub, s)
+		}
+	}
+
+	out, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, b) {
+		t.Errorf("got  %x\nwant %x", out, b)
+	}
+}
+
+func TestHandshakeInduction(t *testing.T) {
+	h := &Handshake{
+		Version:    4,
+		ExtField:   2,
+		ISN:        1,
+		MTU:        1500,
+		FlowWindow: 8192,
+		Type:       HandshakeInduction,
+		SocketID:   0x1234,
+		PeerIP:     net.ParseIP("2001:db8::1"),
+	}
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b[HeaderLen+32 : HeaderLen+36]; !bytes.Equal(got, []byte{0xb8, 0x0d, 0x01, 0x20}) {
+		t.Errorf("got peer address word %x; want b80d0120", got)
+	}
+	p, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, h) {
+		t.Errorf("got %v; want %v", p, h)
+	}
+}
+
+func TestHandshakeReject(t *testing.T) {
+	typ := HandshakeReject + 5
+	if !typ.IsReject() || typ.RejectReason() != 5 || typ.String() != "reject(5)" {
+		t.Errorf("got %v, %v, %d; want a rejection for reason 5", typ, typ.IsReject(), typ.RejectReason())
+	}
+	for _, typ := range []HandshakeType{HandshakeInduction, HandshakeConclusion, HandshakeDone} {
+		if typ.IsReject() {
+			t.Errorf("%v: got a rejection", typ)
+		}
+	}
+
+	h := &Handshake{
+		Version:    5,
+		Type:       HandshakeConclusion,
+		Extensions: []Extension{&KMExt{Cmd: ExtKMRSP, State: 4}, &RawExt{Cmd: 42, Data: []byte{1, 2, 3, 4}}},
+	}
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.PeerIP = net.IPv4zero
+	if !reflect.DeepEqual(p, h) {
+		t.Errorf("got %v; want %v", p, h)
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	b := mustHex(t, conclusionWire)
+	for _, n := range []int{HeaderLen + 40, HeaderLen + 50, HeaderLen + 60, HeaderLen + 80} {
+		if p, err := Parse(b[:n]); err == nil {
+			t.Errorf("%d bytes: got %v; want error", n, p)
+		}
+	}
+	bad := append([]byte(nil), b...)
+	bad[HeaderLen+48+16+4] = 0x99 // key material version
+	if p, err := Parse(bad); err == nil {
+		t.Errorf("got %v; want error for bad key material", p)
+	}
+	km := &KeyMaterial{Key: KeyBoth, KeyLen: 16, Wrap: make([]byte, 24)}
+	if _, err := km.MarshalBinary(); err == nil {
+		t.Error("got nil error for a wrap missing a key")
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package packet decodes and encodes SRT packets as they are sent in
+// UDP datagrams.
+//
+// Parse returns one of the packet types of the package: *Data for data
+// packets, *Handshake, *ACK, *ACKACK, *NAK, *Keepalive, *Shutdown,
+// *DropReq, *PeerError and *CongestionWarning for the control packets
+// and *Control for all other control packets. Each type marshals back
+// into the wire format:
+//
+//	p, err := packet.Parse(b)
+//	if err != nil {
+//		return err
+//	}
+//	if d, ok := p.(*packet.Data); ok && d.Retransmitted {
+//		log.Printf("retransmitted %v", d)
+//	}
+//
+// PcapReader reads the SRT packets of a capture file, e.g. one taken
+// with tcpdump or Wireshark.
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// HeaderLen is the length of the SRT packet header.
+const HeaderLen = 16
+
+const (
+	controlFlag = 1 << 31
+	seqMask     = 1<<31 - 1
+	msgNoMask   = 1<<26 - 1
+)
+
+var (
+	errShort     = errors.New("packet: too short")
+	errTruncated = errors.New("packet: truncated control information")
+)
+
+// Header holds the fields every SRT packet has.
+type Header struct {
+	// Timestamp is the time the packet was sent, in microseconds
+	// since the connection was established.
+	Timestamp uint32
+
+	// DestSocketID is the SRT socket id of the receiver, or 0 in
+	// the first handshake packet of a connection.
+	DestSocketID uint32
+}
+
+// A Packet is an SRT packet.
+type Packet interface {
+	// PacketHeader returns the header of the packet.
+	PacketHeader() *Header
+
+	// MarshalBinary encodes the packet into the wire format.
+	MarshalBinary() ([]byte, error)
+
+	String() string
+}
+
+// PacketHeader returns h. It lets the packet types embedding a Header
+// implement Packet.
+func (h *Header) PacketHeader() *Header { return h }
+
+func (h *Header) String() string {
+	return fmt.Sprintf("ts=%d dst=%#x", h.Timestamp, h.DestSocketID)
+}
+
+// IsControl reports whether b holds a control packet. It only looks
+// at the first bit of the header.
+func IsControl(b []byte) bool {
+	return len(b) > 0 && b[0]&0x80 != 0
+}
+
+// Parse decodes the SRT packet in b. The returned packet may refer to
+// b, e.g. for the payload of a data packet.
+func Parse(b []byte) (Packet, error) {
+	if len(b) < HeaderLen {
+		return nil, errShort
+	}
+	if !IsControl(b) {
+		return parseData(b)
+	}
+	return parseControl(b)
+}
+
+// Position is the position of a packet in a message.
+type Position uint8
+
+// Positions
+const (
+	PositionMiddle Position = 0
+	PositionLast   Position = 1
+	PositionFirst  Position = 2
+	PositionSolo   Position = 3
+)
+
+func (p Position) String() string {
+	switch p {
+	case PositionMiddle:
+		return "middle"
+	case PositionLast:
+		return "last"
+	case PositionFirst:
+		return "first"
+	case PositionSolo:
+		return "solo"
+	}
+	return "Position(" + strconv.Itoa(int(p)) + ")"
+}
+
+// KeyFlag tells which key encrypts the payload of a data packet.
+type KeyFlag uint8
+
+// Key flags
+const (
+	KeyNone KeyFlag = 0
+	KeyEven KeyFlag = 1
+	KeyOdd  KeyFlag = 2
+	// KeyBoth is only used in key material messages, which carry
+	// both keys.
+	KeyBoth KeyFlag = 3
+)
+
+func (k KeyFlag) String() string {
+	switch k {
+	case KeyNone:
+		return "none"
+	case KeyEven:
+		return "even"
+	case KeyOdd:
+		return "odd"
+	case KeyBoth:
+		return "both"
+	}
+	return "KeyFlag(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Data is a data packet.
+type Data struct {
+	Header
+
+	// Seq is the 31 bit packet sequence number.
+	Seq uint32
+
+	// Position is the position of the packet in its message.
+	Position Position
+
+	// InOrder is set if the message must be delivered in order.
+	InOrder bool
+
+	// Key tells whether and with which key the payload is
+	// encrypted.
+	Key KeyFlag
+
+	// Retransmitted is set if the packet is sent again.
+	Retransmitted bool
+
+	// MsgNo is the 26 bit message number.
+	MsgNo uint32
+
+	Payload []byte
+}
+
+func parseData(b []byte) (*Data, error) {
+	w1 := binary.BigEndian.Uint32(b[4:])
+	return &Data{
+		Header: Header{
+			Timestamp:    binary.BigEndian.Uint32(b[8:]),
+			DestSocketID: binary.BigEndian.Uint32(b[12:]),
+		},
+		Seq:           binary.BigEndian.Uint32(b) & seqMask,
+		Position:      Position(w1 >> 30),
+		InOrder:       w1&(1<<29) != 0,
+		Key:           KeyFlag(w1 >> 27 & 3),
+		Retransmitted: w1&(1<<26) != 0,
+		MsgNo:         w1 & msgNoMask,
+		Payload:       b[HeaderLen:],
+	}, nil
+}
+
+// MarshalBinary encodes d into the wire format.
+func (d *Data) MarshalBinary() ([]byte, error) {
+	if d.Seq > seqMask {
+		return nil, errors.New("packet: sequence number out of range")
+	}
+	if d.MsgNo > msgNoMask {
+		return nil, errors.New("packet: message number out of range")
+	}
+	b := make([]byte, HeaderLen+len(d.Payload))
+	w1 := uint32(d.Position&3)<<30 | uint32(d.Key&3)<<27 | d.MsgNo
+	if d.InOrder {
+		w1 |= 1 << 29
+	}
+	if d.Retransmitted {
+		w1 |= 1 << 26
+	}
+	binary.BigEndian.PutUint32(b, d.Seq)
+	binary.BigEndian.PutUint32(b[4:], w1)
+	binary.BigEndian.PutUint32(b[8:], d.Timestamp)
+	binary.BigEndian.PutUint32(b[12:], d.DestSocketID)
+	copy(b[HeaderLen:], d.Payload)
+	return b, nil
+}
+
+func (d *Data) String() string {
+	s := fmt.Sprintf("DATA seq=%d msg=%d pos=%v key=%v len=%d %v", d.Seq, d.MsgNo, d.Position, d.Key, len(d.Payload), &d.Header)
+	if d.InOrder {
+		s += " inorder"
+	}
+	if d.Retransmitted {
+		s += " rexmit"
+	}
+	return s
+}
+
+// SeqDiff returns the distance from the sequence number a to b,
+// taking the wrap around of the 31 bit sequence numbers into account.
+func SeqDiff(a, b uint32) int32 {
+	d := (b - a) & seqMask
+	if d > seqMask/2 {
+		return -int32(seqMask + 1 - d)
+	}
+	return int32(d)
+}
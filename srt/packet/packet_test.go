@@ -0,0 +1,192 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var parseTests = []struct {
+	name string
+	wire string
+	want Packet
+}{
+	{
+		"data",
+		"00000064 e4000007 00001000 2a2a2a2a 68656c6c6f",
+		&Data{
+			Header:        Header{Timestamp: 0x1000, DestSocketID: 0x2a2a2a2a},
+			Seq:           100,
+			Position:      PositionSolo,
+			InOrder:       true,
+			Key:           KeyNone,
+			Retransmitted: true,
+			MsgNo:         7,
+			Payload:       []byte("hello"),
+		},
+	},
+	{
+		"encrypted middle",
+		"7fffffff 10000001 00000001 00000002",
+		&Data{
+			Header:   Header{Timestamp: 1, DestSocketID: 2},
+			Seq:      1<<31 - 1,
+			Position: PositionMiddle,
+			Key:      KeyOdd,
+			MsgNo:    1,
+			Payload:  []byte{},
+		},
+	},
+	{
+		"keepalive",
+		"80010000 00000000 00000010 00000020 00000000",
+		&Keepalive{Header{Timestamp: 0x10, DestSocketID: 0x20}},
+	},
+	{
+		"shutdown",
+		"80050000 00000000 00000010 00000020 00000000",
+		&Shutdown{Header{Timestamp: 0x10, DestSocketID: 0x20}},
+	},
+	{
+		"full ack",
+		"80020000 00000003 00000010 00000020 00000065 00002710 00001388 00000190 000003e8 00002710 00100000",
+		&ACK{
+			Header:       Header{Timestamp: 0x10, DestSocketID: 0x20},
+			AckNo:        3,
+			LastAckSeq:   101,
+			RTT:          10000,
+			RTTVar:       5000,
+			AvailBuf:     400,
+			PacketRate:   1000,
+			LinkCapacity: 10000,
+			ByteRate:     0x100000,
+		},
+	},
+	{
+		"light ack",
+		"80020000 00000000 00000010 00000020 00000065",
+		&ACK{Header: Header{Timestamp: 0x10, DestSocketID: 0x20}, LastAckSeq: 101, Light: true},
+	},
+	{
+		"ackack",
+		"80060000 00000003 00000010 00000020 00000000",
+		&ACKACK{Header: Header{Timestamp: 0x10, DestSocketID: 0x20}, AckNo: 3},
+	},
+	{
+		"nak",
+		"80030000 00000000 00000010 00000020 00000005 80000007 00000009 0000000c",
+		&NAK{
+			Header: Header{Timestamp: 0x10, DestSocketID: 0x20},
+			Loss:   []SeqRange{{5, 5}, {7, 9}, {12, 12}},
+		},
+	},
+	{
+		"dropreq",
+		"80070000 00000009 00000010 00000020 00000005 00000008",
+		&DropReq{Header: Header{Timestamp: 0x10, DestSocketID: 0x20}, MsgNo: 9, Range: SeqRange{5, 8}},
+	},
+	{
+		"peer error",
+		"80080000 00000fa0 00000010 00000020 00000000",
+		&PeerError{Header: Header{Timestamp: 0x10, DestSocketID: 0x20}, Code: 4000},
+	},
+	{
+		"user defined",
+		"ffff0003 00000000 00000010 00000020 01020304",
+		&Control{
+			Header:  Header{Timestamp: 0x10, DestSocketID: 0x20},
+			Type:    TypeUserDefined,
+			Subtype: 3,
+			CIF:     []byte{1, 2, 3, 4},
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		b := mustHex(t, tt.wire)
+		p, err := Parse(b)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(p, tt.want) {
+			t.Errorf("%s: got %#v; want %#v", tt.name, p, tt.want)
+		}
+		if p.String() == "" {
+			t.Errorf("%s: empty string", tt.name)
+		}
+		out, err := p.MarshalBinary()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(out, b) {
+			t.Errorf("%s: got %x; want %x", tt.name, out, b)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, wire := range []string{
+		"",
+		"80020000 00000000 00000010",
+		"80020000 00000000 00000010 00000020",
+		"80020000 00000000 00000010 00000020 00000001 00000002",
+		"80030000 00000000 00000010 00000020 80000007",
+		"80070000 00000000 00000010 00000020 00000005",
+		"80000000 00000000 00000010 00000020 00000005",
+	} {
+		if p, err := Parse(mustHex(t, wire)); err == nil {
+			t.Errorf("%s: got %v; want error", wire, p)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	for _, p := range []Packet{
+		&Data{Seq: 1 << 31},
+		&Data{MsgNo: 1 << 26},
+		&NAK{Loss: []SeqRange{{1 << 31, 1 << 31}}},
+		&Control{Type: 0x8000},
+	} {
+		if b, err := p.MarshalBinary(); err == nil {
+			t.Errorf("%#v: got %x; want error", p, b)
+		}
+	}
+}
+
+func TestSeqDiff(t *testing.T) {
+	for _, tt := range []struct {
+		a, b uint32
+		want int32
+	}{
+		{1, 5, 4},
+		{5, 1, -4},
+		{1<<31 - 2, 1, 3},
+		{1, 1<<31 - 2, -3},
+		{7, 7, 0},
+	} {
+		if got := SeqDiff(tt.a, tt.b); got != tt.want {
+			t.Errorf("SeqDiff(%d, %d) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	n := &NAK{Loss: []SeqRange{{1<<31 - 2, 1}, {5, 5}}}
+	if got := n.Lost(); got != 5 {
+		t.Errorf("got %d lost packets; want 5", got)
+	}
+}
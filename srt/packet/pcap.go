@@ -0,0 +1,504 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package packet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Link types of the captures PcapReader understands
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLoop     = 108
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+)
+
+const (
+	pcapMagic      = 0xa1b2c3d4
+	pcapMagicNanos = 0xa1b23c4d
+	pcapngSHB      = 0x0a0d0d0a
+	pcapngIDB      = 0x00000001
+	pcapngSPB      = 0x00000003
+	pcapngEPB      = 0x00000006
+	pcapngBOM      = 0x1a2b3c4d
+
+	// maxBlockLen limits the records read, against corrupt files.
+	maxBlockLen = 1 << 24
+)
+
+var errBadPcap = errors.New("packet: not a pcap or pcapng file")
+
+// A Frame is a UDP datagram read from a capture.
+type Frame struct {
+	// Time is the capture time.
+	Time time.Time
+
+	Src, Dst *net.UDPAddr
+
+	// Payload is the UDP payload, usually an SRT packet.
+	Payload []byte
+}
+
+// Packet parses the payload of f as an SRT packet.
+func (f *Frame) Packet() (Packet, error) {
+	return Parse(f.Payload)
+}
+
+func (f *Frame) String() string {
+	return fmt.Sprintf("%s %v > %v len=%d", f.Time.Format("15:04:05.000000"), f.Src, f.Dst, len(f.Payload))
+}
+
+// pcapngInterface is an interface of a pcapng section.
+type pcapngInterface struct {
+	linkType int
+	// tsUnit is the duration of a timestamp unit.
+	tsUnit time.Duration
+}
+
+// PcapReader reads the UDP datagrams of a capture file in the pcap or
+// pcapng format. It understands Ethernet, Linux cooked, loopback and
+// raw IP captures. IP fragments and other protocols are skipped.
+type PcapReader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+
+	// pcap
+	ng       bool
+	linkType int
+	nanos    bool
+
+	// pcapng
+	ifaces []pcapngInterface
+}
+
+// NewPcapReader returns a reader of the capture read from r. It reads
+// the file header to find out the format.
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	pr := &PcapReader{r: bufio.NewReader(r)}
+	magic, err := pr.r.Peek(4)
+	if err != nil {
+		return nil, errBadPcap
+	}
+	if binary.BigEndian.Uint32(magic) == pcapngSHB {
+		pr.ng = true
+		if err := pr.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return pr, nil
+	}
+
+	var hdr [24]byte
+	if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+		return nil, errBadPcap
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(hdr[:]) {
+		case pcapMagic:
+			pr.order = order
+		case pcapMagicNanos:
+			pr.order, pr.nanos = order, true
+		}
+	}
+	if pr.order == nil {
+		return nil, errBadPcap
+	}
+	pr.linkType = int(pr.order.Uint32(hdr[20:]) & 0xffff)
+	return pr, nil
+}
+
+// Next returns the next UDP datagram of the capture. It returns io.EOF
+// at the end of the capture.
+func (pr *PcapReader) Next() (*Frame, error) {
+	for {
+		var (
+			ts   time.Time
+			data []byte
+			lt   int
+			err  error
+		)
+		if pr.ng {
+			ts, data, lt, err = pr.nextBlock()
+		} else {
+			ts, data, err = pr.nextRecord()
+			lt = pr.linkType
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f := decodeFrame(lt, data); f != nil {
+			f.Time = ts
+			return f, nil
+		}
+	}
+}
+
+func (pr *PcapReader) nextRecord() (time.Time, []byte, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return time.Time{}, nil, err
+		}
+		return time.Time{}, nil, io.EOF
+	}
+	sec := int64(pr.order.Uint32(hdr[:]))
+	frac := int64(pr.order.Uint32(hdr[4:]))
+	n := pr.order.Uint32(hdr[8:])
+	if n > maxBlockLen {
+		return time.Time{}, nil, errors.New("packet: pcap record too long")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(pr.r, data); err != nil {
+		return time.Time{}, nil, io.ErrUnexpectedEOF
+	}
+	if !pr.nanos {
+		frac *= 1000
+	}
+	return time.Unix(sec, frac), data, nil
+}
+
+// readBlock reads a pcapng block and returns its type and body.
+func (pr *PcapReader) readBlock() (uint32, []byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, err
+		}
+		return 0, nil, io.EOF
+	}
+	typ := pr.order.Uint32(hdr[:])
+	n := pr.order.Uint32(hdr[4:])
+	if n < 12 || n%4 != 0 || n > maxBlockLen {
+		return 0, nil, errors.New("packet: bad pcapng block length")
+	}
+	body := make([]byte, n-8)
+	if _, err := io.ReadFull(pr.r, body); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return typ, body[:len(body)-4], nil
+}
+
+func (pr *PcapReader) readSectionHeader() error {
+	var hdr [12]byte
+	if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+		return errBadPcap
+	}
+	switch {
+	case binary.LittleEndian.Uint32(hdr[8:]) == pcapngBOM:
+		pr.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr[8:]) == pcapngBOM:
+		pr.order = binary.BigEndian
+	default:
+		return errBadPcap
+	}
+	n := pr.order.Uint32(hdr[4:])
+	if n < 28 || n%4 != 0 || n > maxBlockLen {
+		return errBadPcap
+	}
+	if _, err := pr.r.Discard(int(n) - len(hdr)); err != nil {
+		return errBadPcap
+	}
+	pr.ifaces = pr.ifaces[:0]
+	return nil
+}
+
+func (pr *PcapReader) nextBlock() (time.Time, []byte, int, error) {
+	for {
+		if magic, err := pr.r.Peek(4); err == nil && binary.BigEndian.Uint32(magic) == pcapngSHB {
+			if err := pr.readSectionHeader(); err != nil {
+				return time.Time{}, nil, 0, err
+			}
+			continue
+		}
+		typ, body, err := pr.readBlock()
+		if err != nil {
+			return time.Time{}, nil, 0, err
+		}
+		switch typ {
+		case pcapngIDB:
+			if len(body) < 8 {
+				return time.Time{}, nil, 0, errors.New("packet: short pcapng interface block")
+			}
+			pr.ifaces = append(pr.ifaces, pcapngInterface{
+				linkType: int(pr.order.Uint16(body)),
+				tsUnit:   pr.tsUnit(body[8:]),
+			})
+		case pcapngEPB:
+			if len(body) < 20 {
+				return time.Time{}, nil, 0, errors.New("packet: short pcapng packet block")
+			}
+			id := int(pr.order.Uint32(body))
+			if id >= len(pr.ifaces) {
+				return time.Time{}, nil, 0, errors.New("packet: pcapng packet of unknown interface")
+			}
+			iface := pr.ifaces[id]
+			ts := uint64(pr.order.Uint32(body[4:]))<<32 | uint64(pr.order.Uint32(body[8:]))
+			n := int(pr.order.Uint32(body[12:]))
+			if n > len(body)-20 {
+				return time.Time{}, nil, 0, errors.New("packet: bad pcapng packet length")
+			}
+			return pcapngTime(ts, iface.tsUnit), body[20 : 20+n], iface.linkType, nil
+		case pcapngSPB:
+			if len(pr.ifaces) == 0 || len(body) < 4 {
+				return time.Time{}, nil, 0, errors.New("packet: bad pcapng simple packet block")
+			}
+			n := int(pr.order.Uint32(body))
+			if n > len(body)-4 {
+				n = len(body) - 4
+			}
+			return time.Time{}, body[4 : 4+n], pr.ifaces[0].linkType, nil
+		}
+	}
+}
+
+// tsUnit returns the timestamp unit given by the if_tsresol option in
+// the options of an interface description block.
+func (pr *PcapReader) tsUnit(opts []byte) time.Duration {
+	const optTsresol = 9
+	for len(opts) >= 4 {
+		code := pr.order.Uint16(opts)
+		n := int(pr.order.Uint16(opts[2:]))
+		if code == 0 || 4+n > len(opts) {
+			break
+		}
+		if code == optTsresol && n >= 1 {
+			res := opts[4]
+			if res&0x80 != 0 {
+				// Negative powers of two are not worth the
+				// trouble; nobody writes them.
+				break
+			}
+			unit := time.Second
+			for i := byte(0); i < res && unit > 1; i++ {
+				unit /= 10
+			}
+			return unit
+		}
+		opts = opts[4+(n+3)/4*4:]
+	}
+	return time.Microsecond
+}
+
+func pcapngTime(ts uint64, unit time.Duration) time.Time {
+	perSec := uint64(time.Second / unit)
+	return time.Unix(int64(ts/perSec), int64(ts%perSec)*int64(unit))
+}
+
+// decodeFrame decodes the UDP datagram in the captured frame data of
+// link type lt. It returns nil if data holds no unfragmented UDP
+// datagram.
+func decodeFrame(lt int, data []byte) *Frame {
+	switch lt {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil
+		}
+		et := binary.BigEndian.Uint16(data[12:])
+		data = data[14:]
+		for (et == 0x8100 || et == 0x88a8) && len(data) >= 4 {
+			et = binary.BigEndian.Uint16(data[2:])
+			data = data[4:]
+		}
+		return decodeEtherType(et, data)
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil
+		}
+		return decodeEtherType(binary.BigEndian.Uint16(data[14:]), data[16:])
+	case linkTypeSLL2:
+		if len(data) < 20 {
+			return nil
+		}
+		return decodeEtherType(binary.BigEndian.Uint16(data), data[20:])
+	case linkTypeNull, linkTypeLoop:
+		// The address family is in the byte order of the capturing
+		// host, so look at the IP version instead.
+		if len(data) < 4 {
+			return nil
+		}
+		return decodeIP(data[4:])
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return decodeIP(data)
+	}
+	return nil
+}
+
+func decodeEtherType(et uint16, data []byte) *Frame {
+	switch et {
+	case 0x0800, 0x86dd:
+		return decodeIP(data)
+	}
+	return nil
+}
+
+func decodeIP(data []byte) *Frame {
+	if len(data) < 1 {
+		return nil
+	}
+	var src, dst net.IP
+	switch data[0] >> 4 {
+	case 4:
+		if len(data) < 20 {
+			return nil
+		}
+		ihl := int(data[0]&0xf) * 4
+		total := int(binary.BigEndian.Uint16(data[2:]))
+		frag := binary.BigEndian.Uint16(data[6:])
+		if data[9] != 17 || ihl < 20 || total < ihl || total > len(data) || frag&0x3fff != 0 {
+			return nil
+		}
+		src, dst = net.IP(data[12:16]), net.IP(data[16:20])
+		data = data[ihl:total]
+	case 6:
+		if len(data) < 40 || data[6] != 17 {
+			return nil
+		}
+		n := int(binary.BigEndian.Uint16(data[4:]))
+		if 40+n > len(data) {
+			return nil
+		}
+		src, dst = net.IP(data[8:24]), net.IP(data[24:40])
+		data = data[40 : 40+n]
+	default:
+		return nil
+	}
+	if len(data) < 8 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	if n < 8 || n > len(data) {
+		return nil
+	}
+	return &Frame{
+		Src:     &net.UDPAddr{IP: append(net.IP(nil), src...), Port: int(binary.BigEndian.Uint16(data))},
+		Dst:     &net.UDPAddr{IP: append(net.IP(nil), dst...), Port: int(binary.BigEndian.Uint16(data[2:]))},
+		Payload: data[8:n],
+	}
+}
+
+// PcapWriter writes UDP datagrams to a capture file in the pcap format,
+// which Wireshark and PcapReader read.
+type PcapWriter struct {
+	w   io.Writer
+	hdr bool
+}
+
+// NewPcapWriter returns a writer of a capture to w. The file header is
+// written with the first frame.
+func NewPcapWriter(w io.Writer) *PcapWriter {
+	return &PcapWriter{w: w}
+}
+
+// WriteFrame writes the UDP datagram f. The addresses of f must be of
+// the same IP version.
+func (pw *PcapWriter) WriteFrame(f *Frame) error {
+	if !pw.hdr {
+		var hdr [24]byte
+		binary.LittleEndian.PutUint32(hdr[:], pcapMagicNanos)
+		binary.LittleEndian.PutUint16(hdr[4:], 2)
+		binary.LittleEndian.PutUint16(hdr[6:], 4)
+		binary.LittleEndian.PutUint32(hdr[16:], 65535)
+		binary.LittleEndian.PutUint32(hdr[20:], linkTypeRaw)
+		if _, err := pw.w.Write(hdr[:]); err != nil {
+			return err
+		}
+		pw.hdr = true
+	}
+	data, err := encodeIP(f)
+	if err != nil {
+		return err
+	}
+	var rec [16]byte
+	ts := f.Time.UnixNano()
+	binary.LittleEndian.PutUint32(rec[:], uint32(ts/1e9))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(ts%1e9))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(len(data)))
+	if _, err := pw.w.Write(rec[:]); err != nil {
+		return err
+	}
+	_, err = pw.w.Write(data)
+	return err
+}
+
+// encodeIP returns f as an IP packet.
+func encodeIP(f *Frame) ([]byte, error) {
+	if f.Src == nil || f.Dst == nil {
+		return nil, errors.New("packet: frame without addresses")
+	}
+	udpLen := 8 + len(f.Payload)
+	if udpLen > 0xffff-40 {
+		return nil, errors.New("packet: frame too long")
+	}
+	var b, src, dst []byte
+	src4, dst4 := f.Src.IP.To4(), f.Dst.IP.To4()
+	switch {
+	case src4 != nil && dst4 != nil:
+		b = make([]byte, 20+udpLen)
+		b[0] = 0x45
+		binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+		b[8] = 64
+		b[9] = 17
+		src, dst = b[12:16], b[16:20]
+		copy(src, src4)
+		copy(dst, dst4)
+		binary.BigEndian.PutUint16(b[10:], checksum(0, b[:20]))
+	case src4 == nil && dst4 == nil && len(f.Src.IP) == net.IPv6len && len(f.Dst.IP) == net.IPv6len:
+		b = make([]byte, 40+udpLen)
+		b[0] = 0x60
+		binary.BigEndian.PutUint16(b[4:], uint16(udpLen))
+		b[6] = 17
+		b[7] = 64
+		src, dst = b[8:24], b[24:40]
+		copy(src, f.Src.IP)
+		copy(dst, f.Dst.IP)
+	default:
+		return nil, errors.New("packet: frame addresses of different IP versions")
+	}
+	udp := b[len(b)-udpLen:]
+	binary.BigEndian.PutUint16(udp, uint16(f.Src.Port))
+	binary.BigEndian.PutUint16(udp[2:], uint16(f.Dst.Port))
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpLen))
+	copy(udp[8:], f.Payload)
+	pseudo := uint32(17 + udpLen)
+	pseudo = sum(pseudo, src)
+	pseudo = sum(pseudo, dst)
+	c := checksum(pseudo, udp)
+	if c == 0 {
+		c = 0xffff
+	}
+	binary.BigEndian.PutUint16(udp[6:], c)
+	return b, nil
+}
+
+// sum adds the 16 bit words of b to s, as for the Internet checksum.
+func sum(s uint32, b []byte) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 != 0 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	return s
+}
+
+// checksum returns the Internet checksum of b, starting with the
+// partial sum s.
+func checksum(s uint32, b []byte) uint16 {
+	s = sum(s, b)
+	for s > 0xffff {
+		s = s&0xffff + s>>16
+	}
+	return ^uint16(s)
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testFrames = []*Frame{
+	{
+		Time:    time.Unix(1600000000, 123456789),
+		Src:     &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 40000},
+		Dst:     &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 5000},
+		Payload: mustMarshal(&Keepalive{Header{Timestamp: 1, DestSocketID: 2}}),
+	},
+	{
+		Time:    time.Unix(1600000001, 0),
+		Src:     &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5000},
+		Dst:     &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 40000},
+		Payload: mustMarshal(&Data{Seq: 7, Position: PositionSolo, MsgNo: 1, Payload: []byte("x")}),
+	},
+}
+
+func mustMarshal(p Packet) []byte {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func readFrames(t *testing.T, r io.Reader) []*Frame {
+	t.Helper()
+	pr, err := NewPcapReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frames []*Frame
+	for {
+		f, err := pr.Next()
+		if err == io.EOF {
+			return frames
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f)
+	}
+}
+
+func TestPcapWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw := NewPcapWriter(&buf)
+	for _, f := range testFrames {
+		if err := pw.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frames := readFrames(t, &buf)
+	if len(frames) != len(testFrames) {
+		t.Fatalf("got %d frames; want %d", len(frames), len(testFrames))
+	}
+	for i, f := range frames {
+		want := testFrames[i]
+		if !f.Time.Equal(want.Time) || f.Src.String() != want.Src.String() || f.Dst.String() != want.Dst.String() || !bytes.Equal(f.Payload, want.Payload) {
+			t.Errorf("#%d: got %v; want %v", i, f, want)
+		}
+		if _, err := f.Packet(); err != nil {
+			t.Errorf("#%d: %v", i, err)
+		}
+	}
+
+	ip, err := encodeIP(testFrames[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := checksum(0, ip[:20]); c != 0 {
+		t.Errorf("got IP header checksum remainder %#x; want 0", c)
+	}
+	if err := pw.WriteFrame(&Frame{Src: testFrames[0].Src, Dst: testFrames[1].Dst}); err == nil {
+		t.Error("got nil error for addresses of different IP versions")
+	}
+}
+
+// pcapngBlock returns a little endian pcapng block.
+func pcapngBlock(typ uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	b := make([]byte, 8, 12+len(body))
+	binary.LittleEndian.PutUint32(b, typ)
+	binary.LittleEndian.PutUint32(b[4:], uint32(12+len(body)))
+	b = append(b, body...)
+	return append(b, b[4:8]...)
+}
+
+func TestPcapngEthernet(t *testing.T) {
+	f := testFrames[0]
+	ip, err := encodeIP(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ethernet with a VLAN tag.
+	eth := append(make([]byte, 12), 0x81, 0x00, 0x00, 0x05, 0x08, 0x00)
+	eth = append(eth, ip...)
+	arp := append(make([]byte, 12), 0x08, 0x06, 0, 0, 0, 0)
+	fragment := append([]byte(nil), eth...)
+	fragment[18+6] = 0x20 // more fragments
+
+	var buf bytes.Buffer
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb, pcapngBOM)
+	binary.LittleEndian.PutUint16(shb[4:], 1)
+	binary.LittleEndian.PutUint64(shb[8:], ^uint64(0))
+	buf.Write(pcapngBlock(pcapngSHB, shb))
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb, linkTypeEthernet)
+	idb = append(idb, 9, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0) // if_tsresol 9, end of options
+	buf.Write(pcapngBlock(pcapngIDB, idb))
+	ns := uint64(f.Time.UnixNano())
+	for _, data := range [][]byte{arp, fragment, eth} {
+		epb := make([]byte, 20)
+		binary.LittleEndian.PutUint32(epb[4:], uint32(ns>>32))
+		binary.LittleEndian.PutUint32(epb[8:], uint32(ns))
+		binary.LittleEndian.PutUint32(epb[12:], uint32(len(data)))
+		binary.LittleEndian.PutUint32(epb[16:], uint32(len(data)))
+		buf.Write(pcapngBlock(pcapngEPB, append(epb, data...)))
+	}
+
+	frames := readFrames(t, &buf)
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames; want 1", len(frames))
+	}
+	if !frames[0].Time.Equal(f.Time) {
+		t.Errorf("got time %v; want %v", frames[0].Time, f.Time)
+	}
+	if !reflect.DeepEqual(frames[0].Payload, f.Payload) || frames[0].Dst.Port != 5000 {
+		t.Errorf("got %v; want %v", frames[0], f)
+	}
+}
+
+func TestPcapBigEndianSLL(t *testing.T) {
+	f := testFrames[1]
+	ip, err := encodeIP(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sll := make([]byte, 16)
+	binary.BigEndian.PutUint16(sll[14:], 0x86dd)
+	data := append(sll, ip...)
+
+	var buf bytes.Buffer
+	hdr := make([]byte, 24)
+	binary.BigEndian.PutUint32(hdr, pcapMagic)
+	binary.BigEndian.PutUint32(hdr[20:], linkTypeLinuxSLL)
+	buf.Write(hdr)
+	rec := make([]byte, 16)
+	binary.BigEndian.PutUint32(rec, uint32(f.Time.Unix()))
+	binary.BigEndian.PutUint32(rec[4:], 250000)
+	binary.BigEndian.PutUint32(rec[8:], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[12:], uint32(len(data)))
+	buf.Write(rec)
+	buf.Write(data)
+
+	frames := readFrames(t, &buf)
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames; want 1", len(frames))
+	}
+	if want := f.Time.Add(250 * time.Millisecond); !frames[0].Time.Equal(want) {
+		t.Errorf("got time %v; want %v", frames[0].Time, want)
+	}
+	if frames[0].Src.String() != f.Src.String() {
+		t.Errorf("got source %v; want %v", frames[0].Src, f.Src)
+	}
+	p, err := frames[0].Packet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := p.(*Data); !ok || d.Seq != 7 {
+		t.Errorf("got %v; want data packet 7", p)
+	}
+}
+
+func TestPcapErrors(t *testing.T) {
+	if _, err := NewPcapReader(bytes.NewReader([]byte("not a capture file at all"))); err == nil {
+		t.Error("got nil error for a bad magic number")
+	}
+
+	var buf bytes.Buffer
+	if err := NewPcapWriter(&buf).WriteFrame(testFrames[0]); err != nil {
+		t.Fatal(err)
+	}
+	pr, err := NewPcapReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pr.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+}
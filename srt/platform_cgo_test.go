@@ -0,0 +1,12 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build !srtmock
+
+package srt
+
+import "testing"
+
+// mustHaveDualStack skips the test if the SRT library has no dual
+// stack networking. libsrt has.
+func mustHaveDualStack(t *testing.T) {}
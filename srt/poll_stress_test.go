@@ -0,0 +1,121 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPollManyConns runs an echo over many connections at once, so that
+// more sockets are ready than fit into the initial event buffer of the
+// poller.
+func TestPollManyConns(t *testing.T) {
+	n := 2000
+	if testing.Short() {
+		n = 200
+	}
+
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var wg sync.WaitGroup
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func(c net.Conn) {
+				defer wg.Done()
+				defer c.Close()
+				c.SetDeadline(time.Now().Add(someTimeout))
+				b := make([]byte, 64)
+				n, err := c.Read(b)
+				if err != nil {
+					return
+				}
+				c.Write(b[:n])
+			}(c)
+		}
+	}()
+
+	errc := make(chan error, n)
+	sem := make(chan struct{}, 256) // limit concurrent handshakes
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			sem <- struct{}{}
+			c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+			<-sem
+			if err != nil {
+				errc <- err
+				return
+			}
+			defer c.Close()
+			c.SetDeadline(time.Now().Add(someTimeout))
+			wb := []byte(fmt.Sprintf("ECHO %d", i))
+			if _, err := c.Write(wb); err != nil {
+				errc <- err
+				return
+			}
+			rb := make([]byte, 64)
+			m, err := c.Read(rb)
+			if err != nil {
+				errc <- err
+				return
+			}
+			if !bytes.Equal(rb[:m], wb) {
+				errc <- fmt.Errorf("got %q; want %q", rb[:m], wb)
+				return
+			}
+			errc <- nil
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errc; err != nil {
+			t.Error(err)
+		}
+	}
+	ln.Close()
+	wg.Wait()
+}
+
+// TestDialClosedListener tests that a connect to a closed socket is
+// reported as an error instead of blocking forever.
+func TestDialClosedListener(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr()
+	ln.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		c, err := Dial(addr.Network(), addr.String())
+		if err == nil {
+			c.Close()
+		}
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("should fail")
+		}
+		if perr := parseDialError(err); perr != nil {
+			t.Error(perr)
+		}
+	case <-time.After(someTimeout):
+		t.Fatal("dial to a closed listener is blocked")
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"time"
+
+	"github.com/xmedia-systems/gosrt/internal/poll"
+	"github.com/xmedia-systems/gosrt/internal/poll/runtime"
+)
+
+// PollerError is returned by Read, Write and Accept when the poller
+// failed while they were waiting. The poller rebuilds itself, so the
+// operation can be retried. A PollerError is temporary.
+type PollerError = poll.PollerError
+
+// PollerStatus describes the health of the poller.
+type PollerStatus struct {
+	Running       bool      // the library is running
+	Shards        int       // number of epoll shards
+	Broken        int       // number of shards with a failed epoll
+	Failures      uint64    // number of poller failures since the start of the process
+	Rebuilds      uint64    // number of rebuilt epolls since the start of the process
+	LastError     error     // last failure
+	LastErrorTime time.Time // time of the last failure
+}
+
+// Healthy reports whether all shards of the poller work.
+func (s *PollerStatus) Healthy() bool {
+	return s.Broken == 0
+}
+
+// PollerHealth returns the status of the poller.
+func PollerHealth() *PollerStatus {
+	h := runtime.PollServerStatus()
+	return &PollerStatus{
+		Running:       h.Running,
+		Shards:        h.Shards,
+		Broken:        h.Broken,
+		Failures:      h.Failures,
+		Rebuilds:      h.Rebuilds,
+		LastError:     h.LastError,
+		LastErrorTime: h.LastErrorTime,
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/internal/socktest"
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// failEpollWaits makes the waits on all epolls fail with err until
+// the returned function is called.
+func failEpollWaits(err error) (stop func()) {
+	var stopped int32
+	sw.Set(socktest.FilterEpollWait, func(so *socktest.Status) (socktest.AfterFilter, error) {
+		if atomic.LoadInt32(&stopped) == 0 {
+			return nil, err
+		}
+		return nil, nil
+	})
+	return func() {
+		atomic.StoreInt32(&stopped, 1)
+		sw.Set(socktest.FilterEpollWait, nil)
+	}
+}
+
+func waitPollerHealthy(rebuilds uint64) error {
+	deadline := time.Now().Add(someTimeout)
+	for time.Now().Before(deadline) {
+		if h := PollerHealth(); h.Healthy() && h.Rebuilds >= rebuilds {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("poller not rebuilt: %+v", PollerHealth())
+}
+
+func TestPollerFailure(t *testing.T) {
+	recovered := make(chan struct{})
+	server := func(c *SRTConn) error {
+		c.SetReadDeadline(time.Now().Add(someTimeout))
+		before := PollerHealth()
+		stopc := make(chan func(), 1)
+		go func() {
+			// Let Read block before the poller fails.
+			time.Sleep(100 * time.Millisecond)
+			stopc <- failEpollWaits(srtapi.EINVPOLLID)
+		}()
+		b := make([]byte, 128)
+		_, err := c.Read(b)
+		(<-stopc)()
+		if perr := parseReadError(err); perr != nil {
+			return perr
+		}
+		oe, ok := err.(*OpError)
+		if !ok {
+			return fmt.Errorf("got %v; want *OpError", err)
+		}
+		pe, ok := oe.Err.(*PollerError)
+		if !ok {
+			return fmt.Errorf("got %v; want *PollerError", oe.Err)
+		}
+		if pe.Err != srtapi.EINVPOLLID || !oe.Temporary() {
+			return fmt.Errorf("got %v; want temporary %v", pe, srtapi.EINVPOLLID)
+		}
+		if h := PollerHealth(); h.Failures <= before.Failures || h.LastError != srtapi.EINVPOLLID {
+			return fmt.Errorf("got %+v; want a failure", h)
+		}
+		if err := waitPollerHealthy(before.Rebuilds + 1); err != nil {
+			return err
+		}
+		close(recovered)
+
+		// The connection works again with the rebuilt epoll.
+		n, err := c.Read(b)
+		if err != nil {
+			return err
+		}
+		if string(b[:n]) != "AFTER FAILURE" {
+			return fmt.Errorf("got %q", b[:n])
+		}
+		return nil
+	}
+	client := func(c *SRTConn) error {
+		select {
+		case <-recovered:
+		case <-time.After(someTimeout):
+			return fmt.Errorf("poller not recovered")
+		}
+		_, err := c.Write([]byte("AFTER FAILURE"))
+		return err
+	}
+	withSRTConnPair(t, server, client)
+}
+
+func TestPollerHealth(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	h := PollerHealth()
+	if !h.Running || h.Shards < 1 || !h.Healthy() {
+		t.Errorf("got %+v; want a running, healthy poller", h)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"io"
+	"os"
+
+	"github.com/xmedia-systems/gosrt/internal/poll"
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// recvFile copies the data received on c to w using the srt_recvfile
+// call, which writes to the file directly.
+//
+// srt_recvfile opens the file by name and truncates it, so it is only
+// used for an empty regular file, and only in file mode, where the
+// connection is a stream without TSBPD.
+//
+// if handled == true, recvFile returns the number of bytes copied and
+// any error.
+//
+// if handled == false, recvFile performed no work.
+//
+//lint:ignore ST1008 mirrors sendFile
+func recvFile(c *netFD, w io.Writer) (written int64, err error, handled bool) {
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0, nil, false
+	}
+	if fi, err := f.Stat(); err != nil || !fi.Mode().IsRegular() || fi.Size() != 0 {
+		return 0, nil, false
+	}
+	for _, opt := range []int{srtapi.OptionMessageapi, srtapi.OptionTsbpdmode} {
+		if on, err := srtapi.GetsockflagBool(c.pfd.Sysfd, opt); err != nil || on {
+			return 0, nil, false
+		}
+	}
+	written, handled, err = poll.RecvFile(&c.pfd, f, 1<<62)
+	return written, wrapSyscallError("recvfile", err), handled
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// isConnLost reports whether err is the error of a lost connection.
+func isConnLost(err error) bool {
+	oe, ok := err.(*OpError)
+	if !ok {
+		return false
+	}
+	serr, ok := oe.Err.(*os.SyscallError)
+	return ok && serr.Err == srtapi.ECONNLOST
+}
+
+// serveData sends data to the first caller of ln once start is closed,
+// so that the caller is connected first, and closes the connection.
+func serveData(ln net.Listener, data []byte, start <-chan struct{}) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		<-start
+		_, err = c.Write(data)
+		errc <- err
+	}()
+	return errc
+}
+
+func TestWriteTo(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	dir, err := ioutil.TempDir("", "gosrt-recvfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		name     string
+		live     bool
+		deadline bool
+		prefix   string // content of the file before the transfer
+	}{
+		{name: "file"},
+		{name: "deadline", deadline: true},
+		{name: "nonempty", prefix: "header\n"},
+		{name: "live", live: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if !tt.live {
+				ctx = WithConfig(ctx, &Config{TransType: TransTypeFile})
+			}
+			ln, err := newLocalListenerContext(ctx, "srt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			payload := data
+			if tt.live {
+				payload = data[:1316]
+			}
+			start := make(chan struct{})
+			errc := serveData(ln, payload, start)
+
+			f, err := ioutil.TempFile(dir, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			f.WriteString(tt.prefix)
+			d := Dialer{}
+			c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			close(start)
+			if tt.deadline {
+				c.SetReadDeadline(time.Now().Add(someTimeout))
+			}
+
+			// the copy ends with the connection; with the message API
+			// of live mode, the close looks like a lost connection
+			n, err := c.(*SRTConn).WriteTo(f)
+			if tt.live != isConnLost(err) || n != int64(len(payload)) {
+				t.Errorf("got %d bytes, %v; want %d bytes", n, err, len(payload))
+			}
+			if err := <-errc; err != nil {
+				t.Error(err)
+			}
+			got, err := ioutil.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := append([]byte(tt.prefix), payload...); !bytes.Equal(got, want) {
+				t.Errorf("got a file of %d bytes; want the %d bytes of the prefix and data", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestWriteToBuffer(t *testing.T) {
+	ctx := WithConfig(context.Background(), &Config{TransType: TransTypeFile})
+	ln, err := newLocalListenerContext(ctx, "srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	data := bytes.Repeat([]byte("gosrt"), 100000)
+	start := make(chan struct{})
+	errc := serveData(ln, data, start)
+
+	d := Dialer{}
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	close(start)
+	var buf bytes.Buffer
+	n, err := c.(*SRTConn).WriteTo(&buf)
+	if err != nil || n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %d bytes, %v; want the %d bytes sent", n, err, len(data))
+	}
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+}
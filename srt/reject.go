@@ -0,0 +1,143 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"strconv"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// RejectReason is the reason why a connection was rejected.
+//
+// Values below RejectPredefined are set by the SRT library. Values
+// from RejectPredefined up to RejectUserDefined are the predefined
+// application codes of the SRT access control guidelines, which
+// follow the HTTP status codes (e.g. 1403 for "forbidden"). Values
+// from RejectUserDefined on are free for application use.
+//
+// RejectReason implements the error interface, so that a listen
+// callback can return it to reject a connection with that reason.
+type RejectReason int
+
+// Reject reasons set by the SRT library
+const (
+	RejectUnknown    RejectReason = srtapi.RejectUnknown
+	RejectSystem     RejectReason = srtapi.RejectSystem
+	RejectPeer       RejectReason = srtapi.RejectPeer
+	RejectResource   RejectReason = srtapi.RejectResource
+	RejectRogue      RejectReason = srtapi.RejectRogue
+	RejectBacklog    RejectReason = srtapi.RejectBacklog
+	RejectIPE        RejectReason = srtapi.RejectIPE
+	RejectClose      RejectReason = srtapi.RejectClose
+	RejectVersion    RejectReason = srtapi.RejectVersion
+	RejectRdvCookie  RejectReason = srtapi.RejectRdvcookie
+	RejectBadSecret  RejectReason = srtapi.RejectBadsecret
+	RejectUnsecure   RejectReason = srtapi.RejectUnsecure
+	RejectMessageAPI RejectReason = srtapi.RejectMessageapi
+	RejectCongestion RejectReason = srtapi.RejectCongestion
+	RejectFilter     RejectReason = srtapi.RejectFilter
+	RejectGroup      RejectReason = srtapi.RejectGroup
+	RejectTimeout    RejectReason = srtapi.RejectTimeout
+
+	RejectPredefined  RejectReason = srtapi.RejectPredefined
+	RejectUserDefined RejectReason = srtapi.RejectUserdefined
+)
+
+// Predefined application reject reasons of the SRT access control
+// guidelines
+const (
+	RejectFallback          = RejectPredefined + 0
+	RejectKeyNotSupported   = RejectPredefined + 1
+	RejectFilePath          = RejectPredefined + 2
+	RejectHostNotFound      = RejectPredefined + 3
+	RejectBadRequest        = RejectPredefined + 400
+	RejectUnauthorized      = RejectPredefined + 401
+	RejectOverload          = RejectPredefined + 402
+	RejectForbidden         = RejectPredefined + 403
+	RejectNotFound          = RejectPredefined + 404
+	RejectBadMode           = RejectPredefined + 405
+	RejectUnacceptable      = RejectPredefined + 406
+	RejectConflict          = RejectPredefined + 409
+	RejectNotSupMedia       = RejectPredefined + 415
+	RejectLocked            = RejectPredefined + 423
+	RejectFailedDepend      = RejectPredefined + 424
+	RejectInternalError     = RejectPredefined + 500
+	RejectUnimplemented     = RejectPredefined + 501
+	RejectGateway           = RejectPredefined + 502
+	RejectDown              = RejectPredefined + 503
+	RejectVersionNotSupport = RejectPredefined + 505
+	RejectNoRoom            = RejectPredefined + 507
+)
+
+var rejectNames = map[RejectReason]string{
+	RejectFallback:          "callback handler failed",
+	RejectKeyNotSupported:   "unsupported key in stream id",
+	RejectFilePath:          "invalid file path",
+	RejectHostNotFound:      "host not found",
+	RejectBadRequest:        "bad request",
+	RejectUnauthorized:      "unauthorized",
+	RejectOverload:          "overload",
+	RejectForbidden:         "forbidden",
+	RejectNotFound:          "not found",
+	RejectBadMode:           "bad mode",
+	RejectUnacceptable:      "unacceptable",
+	RejectConflict:          "conflict",
+	RejectNotSupMedia:       "media type not supported",
+	RejectLocked:            "locked",
+	RejectFailedDepend:      "failed dependency",
+	RejectInternalError:     "internal server error",
+	RejectUnimplemented:     "unimplemented",
+	RejectGateway:           "bad gateway",
+	RejectDown:              "service unavailable",
+	RejectVersionNotSupport: "version not supported",
+	RejectNoRoom:            "no room",
+}
+
+func (r RejectReason) String() string {
+	if r < RejectPredefined {
+		return srtapi.RejectReasonString(int(r))
+	}
+	if s, ok := rejectNames[r]; ok {
+		return s
+	}
+	if r < RejectUserDefined {
+		return "predefined reason " + strconv.Itoa(int(r-RejectPredefined))
+	}
+	return "user defined reason " + strconv.Itoa(int(r-RejectUserDefined))
+}
+
+func (r RejectReason) Error() string { return "connection rejected: " + r.String() }
+
+// RejectError is returned when a connection was rejected by the peer
+// or the SRT library.
+type RejectError struct {
+	Reason RejectReason
+}
+
+func (e *RejectError) Error() string { return e.Reason.Error() }
+
+// Timeout reports whether the connection was rejected because it timed
+// out.
+func (e *RejectError) Timeout() bool { return e.Reason == RejectTimeout }
+
+// Temporary reports whether the rejection may not happen again on
+// retry.
+func (e *RejectError) Temporary() bool {
+	switch e.Reason {
+	case RejectTimeout, RejectBacklog, RejectResource, RejectOverload, RejectDown:
+		return true
+	}
+	return false
+}
+
+// rejectError returns a *RejectError holding the reject reason of fd,
+// or nil if fd was not rejected.
+func (fd *netFD) rejectError() error {
+	reason := RejectReason(srtapi.GetRejectReason(fd.pfd.Sysfd))
+	if reason == RejectUnknown {
+		return nil
+	}
+	return &RejectError{Reason: reason}
+}
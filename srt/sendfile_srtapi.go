@@ -9,19 +9,27 @@ package srt
 
 import (
 	"io"
+	"os"
 
 	"github.com/xmedia-systems/gosrt/internal/poll"
+	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
-// sendFile copies the contents of r to c using the sendfile
-// system call to minimize copies.
+// sendFile copies the contents of r to c using the srt_sendfile call,
+// which reads the file directly, in blocks of block bytes.
+//
+// srt_sendfile opens the file by name, so it is only used for a regular
+// file that is still found under its name, and only with the stream
+// API. The data is sent from the current offset of the file, up to the
+// limit of an io.LimitedReader, and the offset is advanced past it.
 //
 // if handled == true, sendFile returns the number of bytes copied and any
 // non-EOF error.
 //
 // if handled == false, sendFile performed no work.
+//
 //lint:ignore ST1008 we don't want to change original go/net code
-func sendFile(c *netFD, r io.Reader) (written int64, err error, handled bool) {
+func sendFile(c *netFD, r io.Reader, block int) (written int64, err error, handled bool) {
 	var remain int64 = 1 << 62 // by default, copy until EOF
 
 	lr, ok := r.(*io.LimitedReader)
@@ -31,11 +39,40 @@ func sendFile(c *netFD, r io.Reader) (written int64, err error, handled bool) {
 			return 0, nil, true
 		}
 	}
+	f, ok := r.(*os.File)
+	if !ok {
+		return 0, nil, false
+	}
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0, nil, false
+	}
+	if nfi, err := os.Stat(f.Name()); err != nil || !os.SameFile(fi, nfi) {
+		return 0, nil, false
+	}
+	if on, err := srtapi.GetsockflagBool(c.pfd.Sysfd, srtapi.OptionMessageapi); err != nil || on {
+		return 0, nil, false
+	}
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, nil, false
+	}
+	if n := fi.Size() - pos; remain > n {
+		remain = n
+	}
+	if remain <= 0 {
+		return 0, nil, true
+	}
 
-	written, err = poll.SendFile(&c.pfd, r, remain)
-
+	written, handled, err = poll.SendFile(&c.pfd, f, pos, remain, block)
+	if !handled {
+		return 0, nil, false
+	}
+	if _, serr := f.Seek(pos+written, io.SeekStart); serr != nil && err == nil {
+		err = serr
+	}
 	if lr != nil {
-		lr.N = remain - written
+		lr.N -= written
 	}
-	return written, wrapSyscallError("sendfile", err), written > 0
+	return written, wrapSyscallError("sendfile", err), true
 }
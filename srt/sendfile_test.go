@@ -8,13 +8,18 @@
 package srt
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"net"
 	"os"
 	"testing"
+	"time"
 )
 
 const (
@@ -27,6 +32,9 @@ func TestSendfile(t *testing.T) {
 	if testing.Short() {
 		t.Skipf("skipping test: not supported yet")
 	}
+	if _, err := os.Stat(twain); err != nil {
+		t.Skipf("skipping test: %v", err)
+	}
 	ln, err := newLocalListener("srt")
 	if err != nil {
 		t.Fatal(err)
@@ -95,3 +103,216 @@ func TestSendfile(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// receiveAll returns the data received on the first connection to ln
+// until it ends, and the sizes of the messages read.
+func receiveAll(ln net.Listener) <-chan [][]byte {
+	ch := make(chan [][]byte, 1)
+	go func() {
+		var msgs [][]byte
+		defer func() { ch <- msgs }()
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		b := make([]byte, 1<<20)
+		for {
+			n, err := c.Read(b)
+			if n > 0 {
+				msgs = append(msgs, append([]byte(nil), b[:n]...))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func TestReadFrom(t *testing.T) {
+	data := make([]byte, 9<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	dir, err := ioutil.TempDir("", "gosrt-sendfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := dir + "/data"
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	small := data[:100000]
+
+	for _, tt := range []struct {
+		name     string
+		reader   func(f *os.File) io.Reader // f is at offset 0
+		want     []byte
+		pos      int64 // offset of f after the copy, if sendfile
+		sendfile bool
+		deadline bool
+	}{
+		{
+			name:     "file",
+			reader:   func(f *os.File) io.Reader { return f },
+			want:     data,
+			pos:      int64(len(data)),
+			sendfile: true,
+		},
+		{
+			name: "offset",
+			reader: func(f *os.File) io.Reader {
+				f.Seek(1000, io.SeekStart)
+				return f
+			},
+			want:     data[1000:],
+			pos:      int64(len(data)),
+			sendfile: true,
+		},
+		{
+			name: "limited",
+			reader: func(f *os.File) io.Reader {
+				f.Seek(1000, io.SeekStart)
+				return io.LimitReader(f, 5000)
+			},
+			want:     data[1000:6000],
+			pos:      6000,
+			sendfile: true,
+		},
+		{
+			name: "limited past end",
+			reader: func(f *os.File) io.Reader {
+				f.Seek(int64(len(data)-10), io.SeekStart)
+				return io.LimitReader(f, 100)
+			},
+			want:     data[len(data)-10:],
+			pos:      int64(len(data)),
+			sendfile: true,
+		},
+		{
+			name:   "section",
+			reader: func(f *os.File) io.Reader { return io.NewSectionReader(f, 3000, 7000) },
+			want:   data[3000:10000],
+		},
+		{
+			name: "pipe",
+			reader: func(*os.File) io.Reader {
+				pr, pw, err := os.Pipe()
+				if err != nil {
+					t.Fatal(err)
+				}
+				go func() {
+					pw.Write(small)
+					pw.Close()
+				}()
+				return pr
+			},
+			want: small,
+		},
+		{
+			name:   "reader",
+			reader: func(*os.File) io.Reader { return bytes.NewReader(small) },
+			want:   small,
+		},
+		{
+			name:     "deadline",
+			reader:   func(f *os.File) io.Reader { return io.LimitReader(f, int64(len(small))) },
+			want:     small,
+			deadline: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithConfig(context.Background(), &Config{TransType: TransTypeFile})
+			ln, err := newLocalListenerContext(ctx, "srt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			ch := receiveAll(ln)
+			f, err := os.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			r := tt.reader(f)
+			if c, ok := r.(io.Closer); ok && r != io.Reader(f) {
+				defer c.Close()
+			}
+
+			d := Dialer{}
+			c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sc := c.(*SRTConn)
+			if tt.deadline {
+				sc.SetWriteDeadline(time.Now().Add(someTimeout))
+			}
+			// ReadFrom falls back to the copy if sendFile is not
+			// handled
+			n, err, handled := sendFile(sc.fd, r, 0)
+			if handled != tt.sendfile {
+				t.Errorf("sendfile handled %v; want %v", handled, tt.sendfile)
+			}
+			if !handled {
+				n, err = sc.ReadFrom(r)
+			}
+			if err != nil || n != int64(len(tt.want)) {
+				t.Errorf("got %d bytes, %v; want %d bytes", n, err, len(tt.want))
+			}
+			if tt.sendfile {
+				if pos, _ := f.Seek(0, io.SeekCurrent); pos != tt.pos {
+					t.Errorf("got file offset %d; want %d", pos, tt.pos)
+				}
+			}
+			c.Close()
+			if got := bytes.Join(<-ch, nil); !bytes.Equal(got, tt.want) {
+				t.Errorf("received %d bytes; want the %d bytes sent", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestReadFromBlockSize(t *testing.T) {
+	data := bytes.Repeat([]byte{0x47}, 20000)
+	for _, tt := range []struct {
+		block, max int
+	}{
+		{0, 1316},
+		{500, 500},
+	} {
+		ln, err := newLocalListener("srt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := receiveAll(ln)
+		c, err := Dial("srt", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc := c.(*SRTConn)
+		if err := sc.SetBlockSize(-1); err == nil {
+			t.Error("got nil error for a negative block size")
+		}
+		if err := sc.SetBlockSize(tt.block); err != nil {
+			t.Fatal(err)
+		}
+		// live mode uses the message API, so the file is copied
+		n, err := sc.ReadFrom(bytes.NewReader(data))
+		if err != nil || n != int64(len(data)) {
+			t.Errorf("block %d: got %d bytes, %v; want %d bytes", tt.block, n, err, len(data))
+		}
+		c.Close()
+		msgs := <-ch
+		for _, m := range msgs {
+			if len(m) > tt.max {
+				t.Errorf("block %d: got a message of %d bytes; want at most %d", tt.block, len(m), tt.max)
+				break
+			}
+		}
+		if got := bytes.Join(msgs, nil); !bytes.Equal(got, data) {
+			t.Errorf("block %d: received %d bytes; want the %d bytes sent", tt.block, len(got), len(data))
+		}
+		ln.Close()
+	}
+}
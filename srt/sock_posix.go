@@ -45,6 +45,9 @@ type sockaddr interface {
 
 // socket returns a network file descriptor
 func socket(ctx context.Context, net string, family, sotype, proto int, ipv6only bool, laddr, raddr sockaddr) (fd *netFD, err error) {
+	if err := validateContext(ctx); err != nil {
+		return nil, err
+	}
 	s, err := srtSocket()
 	if err != nil {
 		return nil, err
@@ -53,7 +56,10 @@ func socket(ctx context.Context, net string, family, sotype, proto int, ipv6only
 		poll.CloseFunc(s)
 		return nil, err
 	}
-	configure(ctx, s, bindPre)
+	if err = configure(ctx, s, bindPre); err != nil {
+		poll.CloseFunc(s)
+		return nil, err
+	}
 	if fd, err = newFD(s, family, sotype, net); err != nil {
 		poll.CloseFunc(s)
 		return nil, err
@@ -109,7 +115,9 @@ func (fd *netFD) dial(ctx context.Context, laddr, raddr sockaddr) error {
 			return err
 		}
 		fd.isConnected = true
-		configure(ctx, fd.pfd.Sysfd, bindPost)
+		if err := configure(ctx, fd.pfd.Sysfd, bindPost); err != nil {
+			return err
+		}
 	} else {
 		if err := fd.init(); err != nil {
 			return err
@@ -6,6 +6,7 @@ package srt
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	"github.com/xmedia-systems/gosrt/srtapi"
 )
@@ -20,6 +21,7 @@ const (
 const (
 	bindPre = 0 + iota
 	bindPost
+	bindNone // read only
 )
 
 type socketOption struct {
@@ -33,21 +35,53 @@ type socketOption struct {
 func (o *socketOption) apply(s int, v string) error {
 	ov, err := o.extract(v)
 	if err != nil {
-		return err
+		if ne, ok := err.(*strconv.NumError); ok {
+			err = ne.Err
+		}
+		return &ConfigError{Field: o.name, Value: o.display(v), Err: err}
 	}
 	switch ov := ov.(type) {
 	case string:
-		return srtapi.SetsockoptString(s, 0, o.sym, ov)
+		err = srtapi.SetsockoptString(s, 0, o.sym, ov)
 	case int:
-		return srtapi.SetsockoptInt(s, 0, o.sym, ov)
+		err = srtapi.SetsockoptInt(s, 0, o.sym, ov)
 	case int64:
-		return srtapi.SetsockoptInt64(s, 0, o.sym, ov)
+		err = srtapi.SetsockoptInt64(s, 0, o.sym, ov)
 	case bool:
-		return srtapi.SetsockoptBool(s, 0, o.sym, ov)
+		err = srtapi.SetsockoptBool(s, 0, o.sym, ov)
+	}
+	if err != nil {
+		return &ConfigError{Field: o.name, Value: o.display(v), Err: wrapSyscallError("setsockopt", err)}
 	}
 	return nil
 }
 
+// display returns v as shown in errors; a passphrase is masked.
+func (o *socketOption) display(v string) string {
+	if o.sym == srtapi.OptionPassphrase {
+		// do not leak the passphrase into error messages
+		return strings.Repeat("*", len(v))
+	}
+	return v
+}
+
+func (o *socketOption) get(s int) (string, error) {
+	switch o.typ {
+	case typeString:
+		return srtapi.GetsockflagString(s, o.sym)
+	case typeInt:
+		v, err := srtapi.GetsockflagInt(s, o.sym)
+		return strconv.Itoa(v), err
+	case typeInt64:
+		v, err := srtapi.GetsockflagInt64(s, o.sym)
+		return strconv.FormatInt(v, 10), err
+	case typeBool:
+		v, err := srtapi.GetsockflagBool(s, o.sym)
+		return strconv.FormatBool(v), err
+	}
+	return "", nil
+}
+
 func (o *socketOption) extract(v string) (ov interface{}, err error) {
 	switch o.typ {
 	case typeString:
@@ -95,6 +129,26 @@ var srtOptions = []socketOption{
 	{"enforcedencryption", 0, srtapi.OptionEnforcedencryption, bindPre, typeBool},
 	{"peeridletimeo", 0, srtapi.OptionPeeridletimeo, bindPre, typeInt},
 	{"packetfilter", 0, srtapi.OptionPacketfilter, bindPre, typeString},
+	{"rendezvous", 0, srtapi.OptionRendezvous, bindPre, typeBool},
+	{"groupconnect", 0, srtapi.OptionGroupconnect, bindPre, typeBool},
+	{"groupminstabletimeo", 0, srtapi.OptionGroupminstabletimeo, bindPre, typeInt},
+
+	{"version", 0, srtapi.OptionVersion, bindNone, typeInt},
+	{"peerversion", 0, srtapi.OptionPeerversion, bindNone, typeInt},
+	{"kmstate", 0, srtapi.OptionKmstate, bindNone, typeInt},
+	{"sndkmstate", 0, srtapi.OptionSndkmstate, bindNone, typeInt},
+	{"rcvkmstate", 0, srtapi.OptionRcvkmstate, bindNone, typeInt},
+	{"snddata", 0, srtapi.OptionSnddata, bindNone, typeInt},
+	{"rcvdata", 0, srtapi.OptionRcvdata, bindNone, typeInt},
+}
+
+func lookupOption(name string) *socketOption {
+	for i := range srtOptions {
+		if srtOptions[i].name == name {
+			return &srtOptions[i]
+		}
+	}
+	return nil
 }
 
 type option struct {
@@ -159,9 +213,14 @@ func Option(ctx context.Context, key string) (string, bool) {
 }
 
 func configure(ctx context.Context, s int, binding int) error {
+	return configureExcept(ctx, s, binding, nil)
+}
+
+// configureExcept is configure without the options in except.
+func configureExcept(ctx context.Context, s int, binding int, except map[string]bool) error {
 	ctxOptions := optionValue(ctx)
 	for _, o := range srtOptions {
-		if o.binding == binding {
+		if o.binding == binding && !except[o.name] {
 			if v, ok := ctxOptions[o.name]; ok {
 				if err := o.apply(s, v); err != nil {
 					return err
@@ -25,9 +25,7 @@ import (
 	"os"
 	"time"
 
-	"github.com/xmedia-systems/gosrt/conf"
 	"github.com/xmedia-systems/gosrt/internal/poll"
-	"github.com/xmedia-systems/gosrt/internal/poll/runtime"
 	"github.com/xmedia-systems/gosrt/logging"
 	"github.com/xmedia-systems/gosrt/srtapi"
 )
@@ -71,6 +69,7 @@ func (c *conn) Close() error {
 	if !c.ok() {
 		return srtapi.EINVPARAM
 	}
+	untrackConn(c.fd)
 	err := c.fd.Close()
 	if err != nil {
 		err = &OpError{Op: "close", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
@@ -136,10 +135,6 @@ func (c *conn) StreamID() (string, error) {
 	return srtapi.GetsockflagString(c.fd.pfd.Sysfd, srtapi.OptionStreamid)
 }
 
-func (c *conn) Stats() map[string]interface{} {
-	return srtapi.GetStats(c.fd.pfd.Sysfd, !conf.SystemConf().FullStats())
-}
-
 var listenerBacklog = maxListenerBacklog()
 
 // Various errors contained in OpError.
@@ -150,6 +145,13 @@ var (
 	// For connection setup and write operations.
 	errMissingAddress = errors.New("missing address")
 
+	// For socket option access.
+	errUnknownOption  = errors.New("unknown option")
+	errReadOnlyOption = errors.New("read only option")
+
+	// For rendezvous connection setup.
+	errRendezvousLocalAddr = errors.New("rendezvous mode requires a local address with a port")
+
 	// For both read and write operations.
 	errCanceled = errors.New("operation was canceled")
 )
@@ -257,11 +259,28 @@ type writerOnly struct {
 	io.Writer
 }
 
+type readerOnly struct {
+	io.Reader
+}
+
 // Fallback implementation of io.ReaderFrom's ReadFrom, when sendfile isn't
+// applicable. The data is written in blocks of at most block bytes.
+func genericReadFrom(w io.Writer, r io.Reader, block int) (n int64, err error) {
+	// Use wrappers to hide existing w.ReadFrom and r.WriteTo from
+	// io.CopyBuffer, which would not use the buffer.
+	return io.CopyBuffer(writerOnly{w}, readerOnly{r}, make([]byte, block))
+}
+
+// writeToBufferSize is the size of the buffer of genericWriteTo, large
+// enough for a burst of file mode data.
+const writeToBufferSize = 256 << 10
+
+// Fallback implementation of io.WriterTo's WriteTo, when recvfile isn't
 // applicable.
-func genericReadFrom(w io.Writer, r io.Reader) (n int64, err error) {
-	// Use wrapper to hide existing r.ReadFrom from io.Copy.
-	return io.Copy(writerOnly{w}, r)
+func genericWriteTo(r io.Reader, w io.Writer) (n int64, err error) {
+	// Use wrappers to hide existing r.WriteTo and w.ReadFrom from
+	// io.CopyBuffer, which would not use the buffer.
+	return io.CopyBuffer(writerOnly{w}, readerOnly{r}, make([]byte, writeToBufferSize))
 }
 
 // SetLoggingHandler set logging handler
@@ -269,7 +288,8 @@ func SetLoggingHandler(handler LoggingHandlerFunc) {
 	logging.SetHandler(logging.HandlerFunc(handler))
 }
 
-// Shutdown clean up srt library
-func Shutdown() {
-	runtime.PollServerShutdown()
+// SetLogger makes the library pass its log messages to l; see package
+// logging. A nil l restores the output of the library.
+func SetLogger(l logging.Logger) {
+	logging.SetLogger(l)
 }
@@ -247,3 +247,50 @@ func TestReadTimeoutUnblocksRead(t *testing.T) {
 	}
 	withSRTConnPair(t, client, server)
 }
+
+func TestReadWriteMsg(t *testing.T) {
+	const N = 3
+	msgs := []string{"first message", "second message", "third message"}
+	serverDone := make(chan struct{})
+	server := func(cs *SRTConn) error {
+		defer close(serverDone)
+		cs.SetReadDeadline(time.Now().Add(someTimeout))
+		var prev MsgCtrl
+		for i := 0; i < N; i++ {
+			var buf [128]byte
+			n, ctrl, err := cs.ReadMsg(buf[:])
+			if err != nil {
+				return err
+			}
+			if string(buf[:n]) != msgs[i] {
+				return fmt.Errorf("#%d: got %q; want %q", i, buf[:n], msgs[i])
+			}
+			if ctrl.SrcTime == 0 {
+				return fmt.Errorf("#%d: got zero source time", i)
+			}
+			if i > 0 && ctrl.MsgNo != prev.MsgNo+1 {
+				return fmt.Errorf("#%d: got message number %d; want %d", i, ctrl.MsgNo, prev.MsgNo+1)
+			}
+			prev = ctrl
+		}
+		return nil
+	}
+	client := func(cc *SRTConn) error {
+		cc.SetWriteDeadline(time.Now().Add(someTimeout))
+		for i := 0; i < N; i++ {
+			ctrl := MsgCtrl{TTL: time.Second, SrcTime: SRTNow()}
+			n, err := cc.WriteMsg([]byte(msgs[i]), &ctrl)
+			if err != nil {
+				return err
+			}
+			if n != len(msgs[i]) {
+				return fmt.Errorf("#%d: wrote %d bytes; want %d", i, n, len(msgs[i]))
+			}
+		}
+		// Keep the connection open until the server has read
+		// everything.
+		<-serverDone
+		return nil
+	}
+	withSRTConnPair(t, server, client)
+}
@@ -12,6 +12,7 @@ import (
 	"context"
 	"io"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/xmedia-systems/gosrt/srtapi"
@@ -90,9 +91,15 @@ func ResolveSRTAddr(network, address string) (*SRTAddr, error) {
 // connections.
 type SRTConn struct {
 	conn
+
+	blockSize int32 // accessed atomically
 }
 
-// ReadFrom implements the io.ReaderFrom ReadFrom method.
+// ReadFrom implements the io.ReaderFrom ReadFrom method. A regular file,
+// or an io.LimitedReader of one, is read by the library directly with
+// the stream API, from the current offset of the file, if no write
+// deadline is set. Other readers are copied in writes of the block size
+// (see SetBlockSize).
 func (c *SRTConn) ReadFrom(r io.Reader) (int64, error) {
 	if !c.ok() {
 		return 0, srtapi.EINVPARAM
@@ -104,8 +111,77 @@ func (c *SRTConn) ReadFrom(r io.Reader) (int64, error) {
 	return n, err
 }
 
+// SetBlockSize sets the size of the blocks ReadFrom sends. With the
+// message API, every block is a message and must not be larger than the
+// payload size. Zero restores the default, which is the payload size
+// with the message API if it is limited, and
+// srtapi.DefaultSendfileBlock otherwise.
+func (c *SRTConn) SetBlockSize(n int) error {
+	if !c.ok() || n < 0 {
+		return srtapi.EINVPARAM
+	}
+	atomic.StoreInt32(&c.blockSize, int32(n))
+	return nil
+}
+
+// WriteTo implements the io.WriterTo WriteTo method. An empty regular
+// file is written by the library directly in file mode, if no read
+// deadline is set; otherwise the data is copied through a buffer.
+//
+// A stream, as in file mode, ends with a nil error when the peer closes
+// it. With the message API the library reports a close of the peer like
+// a broken link, so the copy always ends with the error of a lost
+// connection there.
+func (c *SRTConn) WriteTo(w io.Writer) (int64, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	n, err := c.writeTo(w)
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "writeto", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// ReadMsg reads a single message from c, copying the payload into b
+// and returning the message control information of the message.
+func (c *SRTConn) ReadMsg(b []byte) (n int, ctrl MsgCtrl, err error) {
+	if !c.ok() {
+		return 0, ctrl, srtapi.EINVPARAM
+	}
+	var mc srtapi.MsgCtrl
+	n, err = c.fd.readMsg(b, &mc)
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	ctrl.fromAPI(&mc)
+	return n, ctrl, err
+}
+
+// WriteMsg writes b as a single message to c with the message control
+// information ctrl. A nil ctrl uses the defaults. On success, the
+// message number and source time assigned by the library are stored
+// in ctrl.
+func (c *SRTConn) WriteMsg(b []byte, ctrl *MsgCtrl) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	mc := ctrl.toAPI()
+	n, err := c.fd.writeMsg(b, mc)
+	if err != nil {
+		return n, &OpError{Op: "write", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	if ctrl != nil {
+		ctrl.MsgNo = mc.MsgNo
+		ctrl.PktSeq = mc.PktSeq
+		ctrl.SrcTime = mc.SrcTime
+	}
+	return n, nil
+}
+
 func newSRTConn(fd *netFD) *SRTConn {
-	c := &SRTConn{conn{fd}}
+	c := &SRTConn{conn: conn{fd}}
+	trackConn(c)
 	return c
 }
 
@@ -156,6 +232,9 @@ func (l *SRTListener) AcceptSRT() (*SRTConn, error) {
 
 // Accept implements the Accept method in the Listener interface; it
 // waits for the next call and returns a generic Conn.
+// If the listener accepts grouped connections (see the "groupconnect"
+// option), the returned Conn is a *SRTGroupConn for a connection
+// from a socket group.
 func (l *SRTListener) Accept() (net.Conn, error) {
 	if !l.ok() {
 		return nil, srtapi.EINVPARAM
@@ -164,6 +243,9 @@ func (l *SRTListener) Accept() (net.Conn, error) {
 	if err != nil {
 		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
 	}
+	if srtapi.IsGroup(c.fd.pfd.Sysfd) {
+		return &SRTGroupConn{*c}, nil
+	}
 	return c, nil
 }
 
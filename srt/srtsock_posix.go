@@ -12,7 +12,10 @@ import (
 	"context"
 	"io"
 	"net"
+	"sync/atomic"
 	"syscall"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
 )
 
 func sockaddrToSRT(sa syscall.Sockaddr) net.Addr {
@@ -47,10 +50,26 @@ func (a *SRTAddr) toLocal(net string) sockaddr {
 }
 
 func (c *SRTConn) readFrom(r io.Reader) (int64, error) {
-	if n, err, handled := sendFile(c.fd, r); handled {
+	block := int(atomic.LoadInt32(&c.blockSize))
+	if n, err, handled := sendFile(c.fd, r, block); handled {
+		return n, err
+	}
+	if block == 0 {
+		block = srtapi.DefaultSendfileBlock
+		if on, _ := srtapi.GetsockflagBool(c.fd.pfd.Sysfd, srtapi.OptionMessageapi); on {
+			if n, _ := srtapi.GetsockflagInt(c.fd.pfd.Sysfd, srtapi.OptionPayloadsize); n > 0 {
+				block = n
+			}
+		}
+	}
+	return genericReadFrom(c, r, block)
+}
+
+func (c *SRTConn) writeTo(w io.Writer) (int64, error) {
+	if n, err, handled := recvFile(c.fd, w); handled {
 		return n, err
 	}
-	return genericReadFrom(c, r)
+	return genericWriteTo(c.fd, w)
 }
 
 func dialSRT(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
@@ -75,11 +94,18 @@ func (ln *SRTListener) accept() (*SRTConn, error) {
 	if err != nil {
 		return nil, err
 	}
-	configure(ln.ctx, fd.pfd.Sysfd, bindPost)
+	// the options an accept policy set take precedence
+	skip := takePolicyOptions(ln.ctx, fd.pfd.Sysfd)
+	if err := configureExcept(ln.ctx, fd.pfd.Sysfd, bindPost, skip); err != nil {
+		fd.Close()
+		return nil, err
+	}
 	return newSRTConn(fd), nil
 }
 
 func (ln *SRTListener) close() error {
+	untrackListener(ln.fd)
+	removeListener(ln)
 	return ln.fd.Close()
 }
 
@@ -88,5 +114,8 @@ func listenSRT(ctx context.Context, network string, laddr *SRTAddr) (*SRTListene
 	if err != nil {
 		return nil, err
 	}
-	return &SRTListener{fd, ctx}, nil
+	ln := &SRTListener{fd, ctx}
+	addListener(ln)
+	trackListener(ln)
+	return ln, nil
 }
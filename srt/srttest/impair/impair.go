@@ -0,0 +1,273 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package impair provides a UDP proxy which impairs the traffic it
+// forwards, for testing SRT connections against a bad network on the
+// loopback interface without netem or root privileges.
+//
+// A Proxy listens on a local UDP port and forwards the packets it
+// receives to a target address, typically an SRT listener. The
+// dialing side connects to the address of the proxy instead of the
+// listener:
+//
+//	ln, _ := srt.Listen("srt", "127.0.0.1:0")
+//	p, _ := impair.New(ln.Addr().String(), impair.Config{Loss: 0.05})
+//	defer p.Close()
+//	c, _ := srt.Dial("srt", p.Addr().String())
+//
+// Loss, delay, jitter, reordering, duplication and a rate limit can be
+// configured for each direction and changed while the proxy runs.
+//
+// A Tap sees the delivered packets, e.g. to decode them with the
+// srt/packet package or to write a capture for Wireshark:
+//
+//	pw := packet.NewPcapWriter(f)
+//	p.SetTap(func(d impair.Direction, f *packet.Frame) { pw.WriteFrame(f) })
+package impair
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/xmedia-systems/gosrt/srt/packet"
+)
+
+// Direction selects the direction of the traffic a configuration or
+// statistics apply to.
+type Direction int
+
+// Directions
+const (
+	// Upstream is the traffic from the dialing side to the target.
+	Upstream Direction = 1 << iota
+
+	// Downstream is the traffic from the target back to the dialing
+	// side.
+	Downstream
+
+	// Both is the traffic in both directions.
+	Both = Upstream | Downstream
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Upstream:
+		return "upstream"
+	case Downstream:
+		return "downstream"
+	case Both:
+		return "both"
+	}
+	return "invalid"
+}
+
+// maxPacketSize is the largest UDP payload the proxy forwards.
+const maxPacketSize = 65535
+
+var errClosed = errors.New("impair: proxy closed")
+
+// A Tap is called with every packet a Proxy delivers, after the
+// impairments. The addresses of the frame are the ones of the peer and
+// the target, as if there was no proxy. A Tap is called from one
+// goroutine per direction and must not retain the frame.
+type Tap func(d Direction, f *packet.Frame)
+
+// A Proxy forwards UDP packets between the peers which send to its
+// address and a target address, impairing them on the way. It is safe
+// for concurrent use.
+type Proxy struct {
+	conn   *net.UDPConn
+	target *net.UDPAddr
+	up     *link
+	down   *link
+
+	mu     sync.Mutex
+	peers  map[string]*peer
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// peer is a sender on the dialing side. Each peer has its own socket
+// towards the target, so that the target sees a distinct address per
+// peer like without the proxy.
+type peer struct {
+	addr *net.UDPAddr
+	conn *net.UDPConn
+}
+
+// New returns a proxy which listens on the loopback interface and
+// forwards to target, with c applied to both directions.
+func New(target string, c Config) (*Proxy, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	taddr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		return nil, err
+	}
+	laddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	if taddr.IP.To4() == nil && taddr.IP != nil {
+		laddr.IP = net.IPv6loopback
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	p := &Proxy{
+		conn:   conn,
+		target: taddr,
+		peers:  make(map[string]*peer),
+	}
+	p.up = newLink(Upstream, c, func(pr *peer, b []byte) { pr.conn.Write(b) })
+	p.down = newLink(Downstream, c, func(pr *peer, b []byte) { p.conn.WriteToUDP(b, pr.addr) })
+	p.wg.Add(3)
+	go p.up.run(&p.wg)
+	go p.down.run(&p.wg)
+	go p.serve()
+	return p, nil
+}
+
+// Addr returns the address the proxy listens on. Peers dial this
+// address instead of the target.
+func (p *Proxy) Addr() net.Addr {
+	return p.conn.LocalAddr()
+}
+
+// SetConfig replaces the configuration of the directions d. Packets
+// which are already on their way are not affected.
+func (p *Proxy) SetConfig(d Direction, c Config) error {
+	if d&^Both != 0 || d == 0 {
+		return errors.New("impair: invalid direction " + d.String())
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if d&Upstream != 0 {
+		p.up.setConfig(c)
+	}
+	if d&Downstream != 0 {
+		p.down.setConfig(c)
+	}
+	return nil
+}
+
+// SetTap sets the tap of the proxy. A nil tap removes it.
+func (p *Proxy) SetTap(tap Tap) {
+	p.up.setTap(tap, p.target)
+	p.down.setTap(tap, p.target)
+}
+
+// Stats returns the statistics of the directions d. For Both the
+// counters of the two directions are added.
+func (p *Proxy) Stats(d Direction) Stats {
+	var s Stats
+	if d&Upstream != 0 {
+		s.add(p.up.stats())
+	}
+	if d&Downstream != 0 {
+		s.add(p.down.stats())
+	}
+	return s
+}
+
+// Close stops the proxy. Packets which have not been delivered yet are
+// discarded.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errClosed
+	}
+	p.closed = true
+	err := p.conn.Close()
+	for _, pr := range p.peers {
+		pr.conn.Close()
+	}
+	p.mu.Unlock()
+	p.up.close()
+	p.down.close()
+	p.wg.Wait()
+	return err
+}
+
+// serve reads the packets of the peers and hands them to the upstream
+// link.
+func (p *Proxy) serve() {
+	defer p.wg.Done()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := p.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		pr, err := p.peer(addr)
+		if err != nil {
+			continue
+		}
+		b := make([]byte, n)
+		copy(b, buf[:n])
+		p.up.push(pr, b)
+	}
+}
+
+// peer returns the peer of addr, creating it on its first packet.
+func (p *Proxy) peer(addr *net.UDPAddr) (*peer, error) {
+	key := addr.String()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errClosed
+	}
+	if pr := p.peers[key]; pr != nil {
+		return pr, nil
+	}
+	conn, err := net.DialUDP("udp", nil, p.target)
+	if err != nil {
+		return nil, err
+	}
+	pr := &peer{addr: addr, conn: conn}
+	p.peers[key] = pr
+	p.wg.Add(1)
+	go p.serveTarget(pr)
+	return pr, nil
+}
+
+// serveTarget reads the packets the target sends to pr and hands them
+// to the downstream link.
+func (p *Proxy) serveTarget(pr *peer) {
+	defer p.wg.Done()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, err := pr.conn.Read(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			// A connected UDP socket reports ICMP errors of
+			// earlier packets, e.g. while the target is not
+			// listening yet.
+			if !p.isClosed() && isConnRefused(err) {
+				continue
+			}
+			return
+		}
+		b := make([]byte, n)
+		copy(b, buf[:n])
+		p.down.push(pr, b)
+	}
+}
+
+func (p *Proxy) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
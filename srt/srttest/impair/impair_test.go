@@ -0,0 +1,333 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package impair
+
+import (
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt/packet"
+)
+
+const someTimeout = 10 * time.Second
+
+// newEchoProxy returns a proxy in front of a UDP echo server and a
+// client socket connected to the proxy.
+func newEchoProxy(t *testing.T, c Config) (*Proxy, *net.UDPConn) {
+	t.Helper()
+	echo, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, addr, err := echo.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteToUDP(buf[:n], addr)
+		}
+	}()
+	p, err := New(echo.LocalAddr().String(), c)
+	if err != nil {
+		echo.Close()
+		t.Fatal(err)
+	}
+	client, err := net.DialUDP("udp", nil, p.Addr().(*net.UDPAddr))
+	if err != nil {
+		p.Close()
+		echo.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		p.Close()
+		echo.Close()
+	})
+	client.SetReadDeadline(time.Now().Add(someTimeout))
+	return p, client
+}
+
+// waitPackets waits until the proxy has received n packets in the
+// direction d.
+func waitPackets(t *testing.T, p *Proxy, d Direction, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(someTimeout)
+	for p.Stats(d).Packets < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d packets; want %d", p.Stats(d).Packets, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func readString(t *testing.T, c *net.UDPConn) string {
+	t.Helper()
+	buf := make([]byte, maxPacketSize)
+	n, err := c.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestProxyForward(t *testing.T) {
+	p, c := newEchoProxy(t, Config{})
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, c); got != "hello" {
+		t.Errorf("got %q; want %q", got, "hello")
+	}
+	for _, d := range []Direction{Upstream, Downstream} {
+		if st := p.Stats(d); st.Packets != 1 || st.Bytes != 5 || st.Delivered != 1 {
+			t.Errorf("%v: got %+v; want 1 packet of 5 bytes delivered", d, st)
+		}
+	}
+	if st := p.Stats(Both); st.Packets != 2 {
+		t.Errorf("got %d packets in both directions; want 2", st.Packets)
+	}
+}
+
+func TestProxyTap(t *testing.T) {
+	p, c := newEchoProxy(t, Config{})
+	var (
+		mu     sync.Mutex
+		frames = make(map[Direction]*packet.Frame)
+	)
+	p.SetTap(func(d Direction, f *packet.Frame) {
+		mu.Lock()
+		defer mu.Unlock()
+		frames[d] = &packet.Frame{Src: f.Src, Dst: f.Dst, Payload: append([]byte(nil), f.Payload...)}
+	})
+	if _, err := c.Write([]byte("tap")); err != nil {
+		t.Fatal(err)
+	}
+	readString(t, c)
+	p.SetTap(nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	up, down := frames[Upstream], frames[Downstream]
+	if up == nil || down == nil {
+		t.Fatalf("got frames %v; want one per direction", frames)
+	}
+	if string(up.Payload) != "tap" || string(down.Payload) != "tap" {
+		t.Errorf("got payloads %q, %q; want %q", up.Payload, down.Payload, "tap")
+	}
+	if up.Src.String() != c.LocalAddr().String() || down.Dst.String() != c.LocalAddr().String() {
+		t.Errorf("got %v and %v; want the client address %v", up, down, c.LocalAddr())
+	}
+	if up.Dst.String() != down.Src.String() {
+		t.Errorf("got target addresses %v and %v; want the same", up.Dst, down.Src)
+	}
+}
+
+func TestProxyDelay(t *testing.T) {
+	const delay = 30 * time.Millisecond
+	_, c := newEchoProxy(t, Config{Delay: delay, Jitter: delay / 3})
+	start := time.Now()
+	if _, err := c.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	readString(t, c)
+	if rtt := time.Since(start); rtt < 2*(delay-delay/3) {
+		t.Errorf("got round trip time %v; want at least %v", rtt, 2*(delay-delay/3))
+	}
+}
+
+func TestProxyDuplicate(t *testing.T) {
+	p, c := newEchoProxy(t, Config{})
+	if err := p.SetConfig(Upstream, Config{Duplicate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte("dup")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if got := readString(t, c); got != "dup" {
+			t.Errorf("#%d: got %q; want %q", i, got, "dup")
+		}
+	}
+	if st := p.Stats(Upstream); st.Duplicated != 1 || st.Delivered != 2 {
+		t.Errorf("got %+v; want 1 duplicated and 2 delivered packets", st)
+	}
+}
+
+func TestProxyReorder(t *testing.T) {
+	p, c := newEchoProxy(t, Config{})
+	if err := p.SetConfig(Upstream, Config{Reorder: 1, ReorderDelay: 50 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	waitPackets(t, p, Upstream, 1)
+	if err := p.SetConfig(Upstream, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"second", "first"} {
+		if got := readString(t, c); got != want {
+			t.Errorf("got %q; want %q", got, want)
+		}
+	}
+	if st := p.Stats(Upstream); st.Reordered != 1 {
+		t.Errorf("got %d reordered packets; want 1", st.Reordered)
+	}
+}
+
+func TestProxyRate(t *testing.T) {
+	const (
+		N    = 25
+		size = 1000
+		rate = 1000000 // 200ms for N packets
+	)
+	p, c := newEchoProxy(t, Config{})
+	if err := p.SetConfig(Upstream, Config{Rate: rate}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	b := make([]byte, size)
+	for i := 0; i < N; i++ {
+		if _, err := c.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < N; i++ {
+		readString(t, c)
+	}
+	want := time.Duration(N*size*8) * time.Second / rate
+	if d := time.Since(start); d < want*9/10 {
+		t.Errorf("got %d packets in %v; want at least %v", N, d, want*9/10)
+	}
+
+	if err := p.SetConfig(Upstream, Config{Rate: rate, QueueDelay: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		if _, err := c.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitPackets(t, p, Upstream, 2*N)
+	if st := p.Stats(Upstream); st.Dropped == 0 {
+		t.Errorf("got %+v; want packets dropped by the queue limit", st)
+	}
+}
+
+func TestProxyPeers(t *testing.T) {
+	target, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	p, err := New(target.LocalAddr().String(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	target.SetReadDeadline(time.Now().Add(someTimeout))
+	buf := make([]byte, 16)
+	srcs := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		c, err := net.DialUDP("udp", nil, p.Addr().(*net.UDPAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if _, err := c.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+		_, addr, err := target.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs[addr.String()] = true
+	}
+	if len(srcs) != 2 {
+		t.Errorf("got source addresses %v; want one per peer", srcs)
+	}
+}
+
+func TestProxyClose(t *testing.T) {
+	p, err := New("127.0.0.1:9", Config{Delay: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err == nil {
+		t.Fatal("got nil error on second close")
+	}
+}
+
+func TestLossModels(t *testing.T) {
+	const N = 100000
+	for _, c := range []Config{
+		{Loss: 0.1, Seed: 1},
+		{Burst: &GilbertElliott{P: 0.01, R: 0.25, LossBad: 1}, Seed: 1},
+		{Burst: &GilbertElliott{P: 0.05, R: 0.5, LossGood: 0.01, LossBad: 0.5}, Seed: 2},
+	} {
+		l := newLink(Upstream, c, nil)
+		lost, bursts := 0, 0
+		prev := false
+		for i := 0; i < N; i++ {
+			loss := l.lose()
+			if loss {
+				lost++
+				if !prev {
+					bursts++
+				}
+			}
+			prev = loss
+		}
+		want := c.Loss
+		if c.Burst != nil {
+			want = c.Burst.LossRate()
+		}
+		if got := float64(lost) / N; math.Abs(got-want) > want/10 {
+			t.Errorf("%+v: got loss rate %.4f; want %.4f", c, got, want)
+		}
+		if c.Burst != nil && c.Burst.LossGood == 0 && c.Burst.LossBad == 1 {
+			// The bad periods are the loss bursts.
+			if got, want := float64(lost)/float64(bursts), 1/c.Burst.R; math.Abs(got-want) > want/10 {
+				t.Errorf("%+v: got mean burst length %.2f; want %.2f", c, got, want)
+			}
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, c := range []Config{
+		{Loss: -0.1},
+		{Duplicate: 2},
+		{Burst: &GilbertElliott{R: 1.5}},
+		{Delay: -time.Second},
+		{Rate: -1},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: got nil error", c)
+		}
+		if _, err := New("127.0.0.1:9", c); err == nil {
+			t.Errorf("%+v: New got nil error", c)
+		}
+	}
+	p, err := New("127.0.0.1:9", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if err := p.SetConfig(0, Config{}); err == nil {
+		t.Error("got nil error for direction 0")
+	}
+}
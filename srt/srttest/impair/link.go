@@ -0,0 +1,395 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package impair
+
+import (
+	"container/heap"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt/packet"
+)
+
+// defaultReorderDelay is the time a reordered packet is held back when
+// Config.ReorderDelay is zero.
+const defaultReorderDelay = 10 * time.Millisecond
+
+// Config describes the impairments of one direction of a Proxy. The
+// zero value forwards all packets unchanged.
+//
+// A packet first passes the loss models, then waits for the rate
+// limit, is delayed by Delay and Jitter, possibly reordered and finally
+// duplicated.
+type Config struct {
+	// Loss is the probability from 0 to 1 that a packet is lost,
+	// independently of the other packets.
+	Loss float64
+
+	// Burst, if not nil, loses packets in bursts after the
+	// Gilbert-Elliott model. Loss is ignored then.
+	Burst *GilbertElliott
+
+	// Delay is the one-way delay added to every packet.
+	Delay time.Duration
+
+	// Jitter is the largest random deviation from Delay. Packets
+	// keep their order, a packet is never delivered before the
+	// packet sent prior to it.
+	Jitter time.Duration
+
+	// Reorder is the probability from 0 to 1 that a packet is held
+	// back by ReorderDelay, so that the following packets overtake
+	// it.
+	Reorder float64
+
+	// ReorderDelay is the extra delay of a reordered packet. Zero
+	// means 10ms.
+	ReorderDelay time.Duration
+
+	// Duplicate is the probability from 0 to 1 that a packet is
+	// delivered twice.
+	Duplicate float64
+
+	// Rate is the bandwidth of the link in bits per second. Packets
+	// queue until the link is free. Zero means unlimited.
+	Rate int64
+
+	// QueueDelay is the longest time a packet waits for the rate
+	// limit. A packet which would wait longer is dropped, like by a
+	// full router queue. Zero means no limit.
+	QueueDelay time.Duration
+
+	// Seed seeds the random source of the direction, which makes the
+	// impairments reproducible for the same sequence of packets.
+	Seed int64
+}
+
+// GilbertElliott is a two-state Markov model of bursty packet loss.
+// The link is either in the good or in the bad state, and changes the
+// state before each packet with the probabilities P and R. The mean
+// length of a bad period is 1/R packets.
+type GilbertElliott struct {
+	// P is the probability of a change from the good to the bad
+	// state.
+	P float64
+
+	// R is the probability of a change from the bad to the good
+	// state.
+	R float64
+
+	// LossGood and LossBad are the loss probabilities in the good
+	// and the bad state. The classic Gilbert model has LossGood 0
+	// and LossBad 1.
+	LossGood float64
+	LossBad  float64
+}
+
+// LossRate returns the mean loss probability of the model.
+func (g *GilbertElliott) LossRate() float64 {
+	if g.P+g.R == 0 {
+		return g.LossGood
+	}
+	bad := g.P / (g.P + g.R)
+	return (1-bad)*g.LossGood + bad*g.LossBad
+}
+
+func checkProbability(field string, p float64) error {
+	if p < 0 || p > 1 {
+		return errors.New("impair: " + field + " out of range [0, 1]")
+	}
+	return nil
+}
+
+// Validate reports whether c is a valid configuration.
+func (c *Config) Validate() error {
+	for _, f := range []struct {
+		name string
+		p    float64
+	}{
+		{"Loss", c.Loss},
+		{"Reorder", c.Reorder},
+		{"Duplicate", c.Duplicate},
+	} {
+		if err := checkProbability(f.name, f.p); err != nil {
+			return err
+		}
+	}
+	if g := c.Burst; g != nil {
+		for _, f := range []struct {
+			name string
+			p    float64
+		}{
+			{"Burst.P", g.P},
+			{"Burst.R", g.R},
+			{"Burst.LossGood", g.LossGood},
+			{"Burst.LossBad", g.LossBad},
+		} {
+			if err := checkProbability(f.name, f.p); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Delay < 0 || c.Jitter < 0 || c.ReorderDelay < 0 || c.QueueDelay < 0 {
+		return errors.New("impair: negative duration")
+	}
+	if c.Rate < 0 {
+		return errors.New("impair: negative rate")
+	}
+	return nil
+}
+
+// Stats holds the counters of a direction of a Proxy.
+type Stats struct {
+	// Packets and Bytes count the packets received by the proxy.
+	Packets int64
+	Bytes   int64
+
+	// Lost is the number of packets lost by the loss models.
+	Lost int64
+
+	// Dropped is the number of packets dropped because they would
+	// have waited longer than QueueDelay.
+	Dropped int64
+
+	// Reordered and Duplicated count the packets which were held
+	// back and which were delivered twice.
+	Reordered  int64
+	Duplicated int64
+
+	// Delivered is the number of packets sent on, including the
+	// duplicates.
+	Delivered int64
+}
+
+func (s *Stats) add(o Stats) {
+	s.Packets += o.Packets
+	s.Bytes += o.Bytes
+	s.Lost += o.Lost
+	s.Dropped += o.Dropped
+	s.Reordered += o.Reordered
+	s.Duplicated += o.Duplicated
+	s.Delivered += o.Delivered
+}
+
+// datagram is a packet on its way through a link.
+type datagram struct {
+	at  time.Time
+	seq uint64
+	to  *peer
+	b   []byte
+}
+
+// datagramQueue is a heap of datagrams ordered by delivery time.
+// Datagrams due at the same time keep the order they were scheduled
+// in.
+type datagramQueue []*datagram
+
+func (q datagramQueue) Len() int { return len(q) }
+
+func (q datagramQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+
+func (q datagramQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *datagramQueue) Push(x interface{}) { *q = append(*q, x.(*datagram)) }
+
+func (q *datagramQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	pkt := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return pkt
+}
+
+// link impairs the packets of one direction and delivers them with
+// write when they are due.
+type link struct {
+	dir   Direction
+	write func(*peer, []byte)
+	wake  chan struct{}
+	done  chan struct{}
+
+	mu     sync.Mutex
+	tap    Tap
+	target *net.UDPAddr
+	cfg    Config
+	rand   *rand.Rand
+	bad    bool      // state of the Gilbert-Elliott model
+	busy   time.Time // time the rate limited link is busy until
+	last   time.Time // delivery time of the last packet in order
+	seq    uint64
+	queue  datagramQueue
+	st     Stats
+}
+
+func newLink(d Direction, c Config, write func(*peer, []byte)) *link {
+	return &link{
+		dir:   d,
+		write: write,
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+		cfg:   c,
+		rand:  rand.New(rand.NewSource(c.Seed)),
+	}
+}
+
+func (l *link) setConfig(c Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = c
+	l.rand = rand.New(rand.NewSource(c.Seed))
+	l.bad = false
+}
+
+func (l *link) setTap(tap Tap, target *net.UDPAddr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tap = tap
+	l.target = target
+}
+
+func (l *link) stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.st
+}
+
+func (l *link) close() {
+	close(l.done)
+}
+
+// lose reports whether the next packet is lost.
+func (l *link) lose() bool {
+	g := l.cfg.Burst
+	if g == nil {
+		return l.cfg.Loss > 0 && l.rand.Float64() < l.cfg.Loss
+	}
+	if l.bad {
+		l.bad = l.rand.Float64() >= g.R
+	} else {
+		l.bad = l.rand.Float64() < g.P
+	}
+	p := g.LossGood
+	if l.bad {
+		p = g.LossBad
+	}
+	return p > 0 && l.rand.Float64() < p
+}
+
+// push impairs the packet b sent to pr and schedules its delivery.
+func (l *link) push(pr *peer, b []byte) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.st.Packets++
+	l.st.Bytes += int64(len(b))
+	if l.lose() {
+		l.st.Lost++
+		return
+	}
+	c := &l.cfg
+	at := now
+	if c.Rate > 0 {
+		if l.busy.Before(now) {
+			l.busy = now
+		}
+		if c.QueueDelay > 0 && l.busy.Sub(now) > c.QueueDelay {
+			l.st.Dropped++
+			return
+		}
+		l.busy = l.busy.Add(time.Duration(int64(len(b)) * 8 * int64(time.Second) / c.Rate))
+		at = l.busy
+	}
+	at = at.Add(c.Delay)
+	if c.Jitter > 0 {
+		at = at.Add(time.Duration(l.rand.Int63n(2*int64(c.Jitter)+1)) - c.Jitter)
+	}
+	if at.Before(l.last) {
+		at = l.last
+	}
+	l.last = at
+	if c.Reorder > 0 && l.rand.Float64() < c.Reorder {
+		d := c.ReorderDelay
+		if d == 0 {
+			d = defaultReorderDelay
+		}
+		at = at.Add(d)
+		l.st.Reordered++
+	}
+	l.schedule(at, pr, b)
+	if c.Duplicate > 0 && l.rand.Float64() < c.Duplicate {
+		l.schedule(at, pr, b)
+		l.st.Duplicated++
+	}
+}
+
+func (l *link) schedule(at time.Time, pr *peer, b []byte) {
+	l.seq++
+	heap.Push(&l.queue, &datagram{at: at, seq: l.seq, to: pr, b: b})
+	if l.queue[0].seq == l.seq {
+		select {
+		case l.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run delivers the scheduled packets when they are due, until the link
+// is closed.
+func (l *link) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	var due []*datagram
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		due = due[:0]
+		for len(l.queue) > 0 && !l.queue[0].at.After(now) {
+			due = append(due, heap.Pop(&l.queue).(*datagram))
+		}
+		wait := time.Duration(-1)
+		if len(l.queue) > 0 {
+			wait = l.queue[0].at.Sub(now)
+		}
+		l.st.Delivered += int64(len(due))
+		tap, target := l.tap, l.target
+		l.mu.Unlock()
+
+		for _, pkt := range due {
+			if tap != nil {
+				f := &packet.Frame{Time: now, Src: pkt.to.addr, Dst: target, Payload: pkt.b}
+				if l.dir == Downstream {
+					f.Src, f.Dst = f.Dst, f.Src
+				}
+				tap(l.dir, f)
+			}
+			l.write(pkt.to, pkt.b)
+		}
+		if wait >= 0 {
+			timer.Reset(wait)
+		}
+		select {
+		case <-l.wake:
+		case <-timer.C:
+		case <-l.done:
+			timer.Stop()
+			return
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build !srtmock
+
+// The srtmock backend does not send UDP packets, so these tests need
+// libsrt.
+
+package impair
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srt"
+	"github.com/xmedia-systems/gosrt/srt/packet"
+)
+
+// srtPair returns an SRT connection dialed through a proxy with c to
+// a listener, and the connection accepted by the listener. Both are
+// opened with the options held by ctx.
+func srtPair(t *testing.T, ctx context.Context, c Config) (client, server *srt.SRTConn, p *Proxy) {
+	t.Helper()
+	ln, err := srt.ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	p, err = New(ln.Addr().String(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	dctx, cancel := context.WithTimeout(ctx, someTimeout)
+	defer cancel()
+	var d srt.Dialer
+	cc, err := d.DialContext(dctx, "srt", p.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	sc := <-accepted
+	if sc == nil {
+		t.FailNow()
+	}
+	t.Cleanup(func() { sc.Close() })
+	return cc.(*srt.SRTConn), sc.(*srt.SRTConn), p
+}
+
+// writeSeq writes the messages from..to-1, each holding its index, one
+// every interval.
+func writeSeq(t *testing.T, c *srt.SRTConn, from, to int, interval time.Duration) {
+	t.Helper()
+	b := make([]byte, 1316)
+	c.SetWriteDeadline(time.Now().Add(someTimeout))
+	for i := from; i < to; i++ {
+		binary.BigEndian.PutUint32(b, uint32(i))
+		if _, err := c.Write(b); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// readSeq reads the messages written by writeSeq until no message
+// arrives for idle, and returns their indexes.
+func readSeq(c *srt.SRTConn, idle time.Duration) []int {
+	var seq []int
+	b := make([]byte, 1500)
+	for {
+		c.SetReadDeadline(time.Now().Add(idle))
+		n, err := c.Read(b)
+		if err != nil || n < 4 {
+			return seq
+		}
+		seq = append(seq, int(binary.BigEndian.Uint32(b)))
+	}
+}
+
+func TestSRTRetransmission(t *testing.T) {
+	const size = 1 << 20
+	ctx := srt.WithConfig(context.Background(), &srt.Config{TransType: srt.TransTypeFile})
+	cc, sc, p := srtPair(t, ctx, Config{})
+	if err := p.SetConfig(Both, Config{Loss: 0.05, Delay: 5 * time.Millisecond, Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu             sync.Mutex
+		rexmits, naked int
+	)
+	p.SetTap(func(d Direction, f *packet.Frame) {
+		pkt, err := f.Packet()
+		if err != nil {
+			t.Errorf("%v: %v", d, err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch pkt := pkt.(type) {
+		case *packet.Data:
+			if d == Upstream && pkt.Retransmitted {
+				rexmits++
+			}
+		case *packet.NAK:
+			if d == Downstream {
+				naked += pkt.Lost()
+			}
+		}
+	})
+
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	errc := make(chan error, 1)
+	go func() {
+		cc.SetWriteDeadline(time.Now().Add(someTimeout))
+		_, err := cc.Write(data)
+		errc <- err
+	}()
+	sc.SetReadDeadline(time.Now().Add(someTimeout))
+	got := make([]byte, size)
+	if _, err := io.ReadFull(sc, got); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("received data differs from sent data")
+	}
+
+	if st := p.Stats(Upstream); st.Lost == 0 {
+		t.Fatalf("got %+v; want lost packets", st)
+	}
+	st, err := cc.Stats(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Send.Total.PacketsRetransmitted == 0 {
+		t.Errorf("got no retransmitted packets; want the lost ones")
+	}
+	p.SetTap(nil)
+	mu.Lock()
+	defer mu.Unlock()
+	if rexmits == 0 || naked == 0 {
+		t.Errorf("got %d retransmitted data packets and %d packets reported lost on the wire; want both", rexmits, naked)
+	}
+}
+
+func TestSRTLatency(t *testing.T) {
+	const (
+		latency = 200 * time.Millisecond
+		delay   = 20 * time.Millisecond
+	)
+	ctx := srt.WithConfig(context.Background(), &srt.Config{TransType: srt.TransTypeLive, Latency: latency})
+	cc, sc, _ := srtPair(t, ctx, Config{Delay: delay, Jitter: delay / 2})
+
+	if l, err := sc.Latency(); err != nil {
+		t.Fatal(err)
+	} else if l < latency {
+		t.Errorf("got negotiated latency %v; want at least %v", l, latency)
+	}
+
+	// TSBPD delivers every message latency after it was sent, no
+	// matter how long it was on the way.
+	b := make([]byte, 188)
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		cc.SetWriteDeadline(start.Add(someTimeout))
+		if _, err := cc.Write(b); err != nil {
+			t.Fatal(err)
+		}
+		sc.SetReadDeadline(start.Add(someTimeout))
+		if _, err := sc.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d < latency*9/10 || d > latency+time.Second {
+			t.Errorf("#%d: message delivered after %v; want about %v", i, d, latency)
+		}
+	}
+}
+
+func TestSRTTLPktDrop(t *testing.T) {
+	const (
+		N    = 300
+		tail = 20
+	)
+	ctx := srt.WithConfig(context.Background(), &srt.Config{TransType: srt.TransTypeLive, Latency: 60 * time.Millisecond})
+	ctx = srt.WithOptions(ctx, srt.Options("tlpktdrop", "true"))
+	cc, sc, p := srtPair(t, ctx, Config{})
+
+	// With a round trip time above the latency, lost packets cannot
+	// be retransmitted in time and are dropped by the receiver.
+	err := p.SetConfig(Both, Config{
+		Delay: 40 * time.Millisecond,
+		Burst: &GilbertElliott{P: 0.02, R: 0.2, LossBad: 1},
+		Seed:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqc := make(chan []int, 1)
+	go func() { seqc <- readSeq(sc, time.Second) }()
+	writeSeq(t, cc, 0, N, 2*time.Millisecond)
+	if err := p.SetConfig(Both, Config{Delay: 40 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	writeSeq(t, cc, N, N+tail, 2*time.Millisecond)
+	seq := <-seqc
+
+	for i := 1; i < len(seq); i++ {
+		if seq[i] <= seq[i-1] {
+			t.Fatalf("message %d delivered after %d", seq[i], seq[i-1])
+		}
+	}
+	if len(seq) < tail || seq[len(seq)-tail] != N || seq[len(seq)-1] != N+tail-1 {
+		t.Fatalf("got messages %v; want the delivery to go on after the losses", seq)
+	}
+	if len(seq) == N+tail {
+		t.Fatalf("got all messages; want some dropped")
+	}
+	st, err := sc.Stats(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Recv.Total.PacketsDropped == 0 {
+		t.Errorf("got no dropped packets in %+v", st.Recv.Total)
+	}
+}
+
+func TestSRTPacketFilterFEC(t *testing.T) {
+	const N = 500
+	ctx := srt.WithConfig(context.Background(), &srt.Config{
+		TransType:    srt.TransTypeLive,
+		Latency:      200 * time.Millisecond,
+		PacketFilter: "fec,cols:10,rows:1",
+	})
+	ln, err := srt.ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("packet filter not supported: %v", err)
+	}
+	ln.Close()
+	cc, sc, p := srtPair(t, ctx, Config{})
+	if err := p.SetConfig(Upstream, Config{Loss: 0.01, Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	seqc := make(chan []int, 1)
+	go func() { seqc <- readSeq(sc, time.Second) }()
+	writeSeq(t, cc, 0, N, time.Millisecond)
+	seq := <-seqc
+	if len(seq) != N {
+		t.Errorf("got %d messages; want %d", len(seq), N)
+	}
+	if st := p.Stats(Upstream); st.Lost == 0 {
+		t.Fatalf("got %+v; want lost packets", st)
+	}
+
+	rst, err := sc.Stats(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rst.Recv.Total.PacketsFilterSupply == 0 {
+		t.Errorf("got no packets rebuilt by FEC in %+v", rst.Recv.Total)
+	}
+	sst, err := cc.Stats(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sst.Send.Total.PacketsFilterExtra == 0 {
+		t.Errorf("got no FEC packets sent in %+v", sst.Send.Total)
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// Stats holds the statistics of an SRT connection as reported by
+// srt_bistats.
+//
+// The interval counters of Send and Recv cover the time since the
+// statistics were last cleared, the Total counters cover the whole
+// lifetime of the connection. All other fields are instantaneous
+// values sampled at the time of the call.
+//
+// In JSON, the durations Time and Send.Duration are given in
+// milliseconds, like the other times.
+type Stats struct {
+	// SocketID is the SRT socket id of the connection.
+	SocketID int `json:"sid"`
+
+	// Time is the time elapsed since the connection was started.
+	Time time.Duration `json:"time"`
+
+	Window WindowStats `json:"window"`
+	Link   LinkStats   `json:"link"`
+	Send   SendStats   `json:"send"`
+	Recv   RecvStats   `json:"recv"`
+}
+
+// WindowStats holds the flow and congestion window statistics.
+type WindowStats struct {
+	// Flow is the flow window size, in packets.
+	Flow int `json:"flow"`
+
+	// Congestion is the congestion window size, in packets.
+	Congestion int `json:"congestion"`
+
+	// Flight is the number of packets on flight.
+	Flight int `json:"flight"`
+}
+
+// LinkStats holds the statistics of the link between the peers.
+type LinkStats struct {
+	// RTT is the round trip time, in milliseconds.
+	RTT float64 `json:"rtt"`
+
+	// Bandwidth is the estimated bandwidth, in Mb/s.
+	Bandwidth float64 `json:"bandwidth"`
+
+	// MaxBandwidth is the transmission bandwidth limit, in Mb/s.
+	MaxBandwidth float64 `json:"maxBandwidth"`
+
+	// MSS is the maximum segment size, in bytes.
+	MSS int `json:"mss"`
+}
+
+// SendCounters holds the cumulative sender side counters.
+type SendCounters struct {
+	Packets              int64  `json:"packets"`
+	PacketsLost          int    `json:"packetsLost"`
+	PacketsDropped       int    `json:"packetsDropped"`
+	PacketsRetransmitted int    `json:"packetsRetransmitted"`
+	PacketsFilterExtra   int    `json:"packetsFilterExtra"`
+	ACKsReceived         int    `json:"acksReceived"`
+	NAKsReceived         int    `json:"naksReceived"`
+	Bytes                uint64 `json:"bytes"`
+	BytesDropped         uint64 `json:"bytesDropped"`
+	BytesRetransmitted   uint64 `json:"bytesRetransmitted"`
+
+	// Duration is the time the sender was busy sending.
+	Duration time.Duration `json:"duration"`
+}
+
+// SendStats holds the sender side statistics.
+type SendStats struct {
+	// Interval counters
+	SendCounters
+
+	// MbitRate is the sending rate, in Mb/s.
+	MbitRate float64 `json:"mbitRate"`
+
+	// Total holds the counters since the connection was started.
+	Total SendCounters `json:"total"`
+
+	// PacketPeriod is the interval between sent packets, in
+	// microseconds.
+	PacketPeriod float64 `json:"usPacketPeriod"`
+
+	// BufferPackets, BufferBytes and BufferMs are the amount of
+	// unacknowledged data in the send buffer.
+	BufferPackets int `json:"bufferPackets"`
+	BufferBytes   int `json:"bufferBytes"`
+	BufferMs      int `json:"bufferMs"`
+
+	// BufferAvailBytes is the free space in the send buffer.
+	BufferAvailBytes int `json:"bufferAvailBytes"`
+
+	// TsbPdDelay is the timestamp-based packet delivery delay of
+	// the sender, in milliseconds.
+	TsbPdDelay int `json:"tsbPdDelay"`
+}
+
+// RecvCounters holds the cumulative receiver side counters.
+type RecvCounters struct {
+	Packets             int64  `json:"packets"`
+	PacketsLost         int    `json:"packetsLost"`
+	PacketsDropped      int    `json:"packetsDropped"`
+	PacketsUndecrypted  int    `json:"packetsUndecrypted"`
+	PacketsFilterExtra  int    `json:"packetsFilterExtra"`
+	PacketsFilterSupply int    `json:"packetsFilterSupply"`
+	PacketsFilterLoss   int    `json:"packetsFilterLoss"`
+	ACKsSent            int    `json:"acksSent"`
+	NAKsSent            int    `json:"naksSent"`
+	Bytes               uint64 `json:"bytes"`
+	BytesLost           uint64 `json:"bytesLost"`
+	BytesDropped        uint64 `json:"bytesDropped"`
+	BytesUndecrypted    uint64 `json:"bytesUndecrypted"`
+}
+
+// RecvStats holds the receiver side statistics.
+type RecvStats struct {
+	// Interval counters
+	RecvCounters
+
+	// PacketsRetransmitted is the number of retransmitted packets
+	// received.
+	PacketsRetransmitted int `json:"packetsRetransmitted"`
+
+	// PacketsBelated is the number of packets received too late to
+	// be delivered.
+	PacketsBelated int64 `json:"packetsBelated"`
+
+	// BelatedAvgTime is the average time of belated packets, in
+	// milliseconds.
+	BelatedAvgTime float64 `json:"belatedAvgTime"`
+
+	// MbitRate is the receiving rate, in Mb/s.
+	MbitRate float64 `json:"mbitRate"`
+
+	// Total holds the counters since the connection was started.
+	Total RecvCounters `json:"total"`
+
+	// ReorderDistance is the distance between reordered packets, in
+	// packets.
+	ReorderDistance int `json:"reorderDistance"`
+
+	// ReorderTolerance is the current reorder tolerance, in packets.
+	ReorderTolerance int `json:"reorderTolerance"`
+
+	// BufferPackets, BufferBytes and BufferMs are the amount of
+	// undelivered data in the receive buffer.
+	BufferPackets int `json:"bufferPackets"`
+	BufferBytes   int `json:"bufferBytes"`
+	BufferMs      int `json:"bufferMs"`
+
+	// BufferAvailBytes is the free space in the receive buffer.
+	BufferAvailBytes int `json:"bufferAvailBytes"`
+
+	// TsbPdDelay is the timestamp-based packet delivery delay of
+	// the receiver, in milliseconds.
+	TsbPdDelay int `json:"tsbPdDelay"`
+}
+
+// statsJSON is the JSON form of Stats, with the durations in
+// milliseconds. Its fields shadow the fields of the same name of the
+// embedded structs.
+type statsJSON struct {
+	stats
+	Time int64         `json:"time"`
+	Send sendStatsJSON `json:"send"`
+}
+
+// stats is Stats without its methods.
+type stats Stats
+
+type sendStatsJSON struct {
+	SendStats
+	Duration int64            `json:"duration"`
+	Total    sendCountersJSON `json:"total"`
+}
+
+type sendCountersJSON struct {
+	SendCounters
+	Duration int64 `json:"duration"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&statsJSON{
+		stats: stats(s),
+		Time:  int64(s.Time / time.Millisecond),
+		Send: sendStatsJSON{
+			SendStats: s.Send,
+			Duration:  int64(s.Send.Duration / time.Millisecond),
+			Total: sendCountersJSON{
+				SendCounters: s.Send.Total,
+				Duration:     int64(s.Send.Total.Duration / time.Millisecond),
+			},
+		},
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *Stats) UnmarshalJSON(b []byte) error {
+	var v statsJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*s = Stats(v.stats)
+	s.Time = time.Duration(v.Time) * time.Millisecond
+	s.Send = v.Send.SendStats
+	s.Send.Duration = time.Duration(v.Send.Duration) * time.Millisecond
+	s.Send.Total = v.Send.Total.SendCounters
+	s.Send.Total.Duration = time.Duration(v.Send.Total.Duration) * time.Millisecond
+	return nil
+}
+
+func newStats(fd int, mon *srtapi.TraceBStats) *Stats {
+	return &Stats{
+		SocketID: fd,
+		Time:     time.Duration(mon.MsTimeStamp) * time.Millisecond,
+		Window: WindowStats{
+			Flow:       mon.PktFlowWindow,
+			Congestion: mon.PktCongestionWindow,
+			Flight:     mon.PktFlightSize,
+		},
+		Link: LinkStats{
+			RTT:          mon.MsRTT,
+			Bandwidth:    mon.MbpsBandwidth,
+			MaxBandwidth: mon.MbpsMaxBW,
+			MSS:          mon.ByteMSS,
+		},
+		Send: SendStats{
+			SendCounters: SendCounters{
+				Packets:              mon.PktSent,
+				PacketsLost:          mon.PktSndLoss,
+				PacketsDropped:       mon.PktSndDrop,
+				PacketsRetransmitted: mon.PktRetrans,
+				PacketsFilterExtra:   mon.PktSndFilterExtra,
+				ACKsReceived:         mon.PktRecvACK,
+				NAKsReceived:         mon.PktRecvNAK,
+				Bytes:                mon.ByteSent,
+				BytesDropped:         mon.ByteSndDrop,
+				BytesRetransmitted:   mon.ByteRetrans,
+				Duration:             time.Duration(mon.UsSndDuration) * time.Microsecond,
+			},
+			MbitRate: mon.MbpsSendRate,
+			Total: SendCounters{
+				Packets:              mon.PktSentTotal,
+				PacketsLost:          mon.PktSndLossTotal,
+				PacketsDropped:       mon.PktSndDropTotal,
+				PacketsRetransmitted: mon.PktRetransTotal,
+				PacketsFilterExtra:   mon.PktSndFilterExtraTotal,
+				ACKsReceived:         mon.PktRecvACKTotal,
+				NAKsReceived:         mon.PktRecvNAKTotal,
+				Bytes:                mon.ByteSentTotal,
+				BytesDropped:         mon.ByteSndDropTotal,
+				BytesRetransmitted:   mon.ByteRetransTotal,
+				Duration:             time.Duration(mon.UsSndDurationTotal) * time.Microsecond,
+			},
+			PacketPeriod:     mon.UsPktSndPeriod,
+			BufferPackets:    mon.PktSndBuf,
+			BufferBytes:      mon.ByteSndBuf,
+			BufferMs:         mon.MsSndBuf,
+			BufferAvailBytes: mon.ByteAvailSndBuf,
+			TsbPdDelay:       mon.MsSndTsbPdDelay,
+		},
+		Recv: RecvStats{
+			RecvCounters: RecvCounters{
+				Packets:             mon.PktRecv,
+				PacketsLost:         mon.PktRcvLoss,
+				PacketsDropped:      mon.PktRcvDrop,
+				PacketsUndecrypted:  mon.PktRcvUndecrypt,
+				PacketsFilterExtra:  mon.PktRcvFilterExtra,
+				PacketsFilterSupply: mon.PktRcvFilterSupply,
+				PacketsFilterLoss:   mon.PktRcvFilterLoss,
+				ACKsSent:            mon.PktSentACK,
+				NAKsSent:            mon.PktSentNAK,
+				Bytes:               mon.ByteRecv,
+				BytesLost:           mon.ByteRcvLoss,
+				BytesDropped:        mon.ByteRcvDrop,
+				BytesUndecrypted:    mon.ByteRcvUndecrypt,
+			},
+			PacketsRetransmitted: mon.PktRcvRetrans,
+			PacketsBelated:       mon.PktRcvBelated,
+			BelatedAvgTime:       mon.PktRcvAvgBelatedTime,
+			MbitRate:             mon.MbpsRecvRate,
+			Total: RecvCounters{
+				Packets:             mon.PktRecvTotal,
+				PacketsLost:         mon.PktRcvLossTotal,
+				PacketsDropped:      mon.PktRcvDropTotal,
+				PacketsUndecrypted:  mon.PktRcvUndecryptTotal,
+				PacketsFilterExtra:  mon.PktRcvFilterExtraTotal,
+				PacketsFilterSupply: mon.PktRcvFilterSupplyTotal,
+				PacketsFilterLoss:   mon.PktRcvFilterLossTotal,
+				ACKsSent:            mon.PktSentACKTotal,
+				NAKsSent:            mon.PktSentNAKTotal,
+				Bytes:               mon.ByteRecvTotal,
+				BytesLost:           mon.ByteRcvLossTotal,
+				BytesDropped:        mon.ByteRcvDropTotal,
+				BytesUndecrypted:    mon.ByteRcvUndecryptTotal,
+			},
+			ReorderDistance:  mon.PktReorderDistance,
+			ReorderTolerance: mon.PktReorderTolerance,
+			BufferPackets:    mon.PktRcvBuf,
+			BufferBytes:      mon.ByteRcvBuf,
+			BufferMs:         mon.MsRcvBuf,
+			BufferAvailBytes: mon.ByteAvailRcvBuf,
+			TsbPdDelay:       mon.MsRcvTsbPdDelay,
+		},
+	}
+}
+
+// Stats returns the statistics of the connection. If clear is true,
+// the interval counters are reset after they have been read.
+func (c *conn) Stats(clear bool) (*Stats, error) {
+	if !c.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	mon, err := srtapi.Bistats(c.fd.pfd.Sysfd, clear, true)
+	if err != nil {
+		return nil, &OpError{Op: "stats", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("bistats", err)}
+	}
+	return newStats(c.fd.pfd.Sysfd, mon), nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	const N = 10
+	serverDone := make(chan struct{})
+	server := func(cs *SRTConn) error {
+		defer close(serverDone)
+		cs.SetReadDeadline(time.Now().Add(someTimeout))
+		var buf [1316]byte
+		for i := 0; i < N; i++ {
+			if _, err := cs.Read(buf[:]); err != nil {
+				return err
+			}
+		}
+		st, err := cs.Stats(false)
+		if err != nil {
+			return err
+		}
+		if st.Recv.Total.Packets < N {
+			return fmt.Errorf("got %d received packets; want at least %d", st.Recv.Total.Packets, N)
+		}
+		if st.Recv.Total.Bytes < N*uint64(len(buf)) {
+			return fmt.Errorf("got %d received bytes; want at least %d", st.Recv.Total.Bytes, N*len(buf))
+		}
+		return nil
+	}
+	client := func(cc *SRTConn) error {
+		cc.SetWriteDeadline(time.Now().Add(someTimeout))
+		b := make([]byte, 1316)
+		for i := 0; i < N; i++ {
+			if _, err := cc.Write(b); err != nil {
+				return err
+			}
+		}
+		<-serverDone
+		st, err := cc.Stats(true)
+		if err != nil {
+			return err
+		}
+		if st.Send.Packets < N || st.Send.Total.Packets < N {
+			return fmt.Errorf("got %d/%d sent packets; want at least %d", st.Send.Packets, st.Send.Total.Packets, N)
+		}
+		st, err = cc.Stats(false)
+		if err != nil {
+			return err
+		}
+		if st.Send.Packets != 0 {
+			return fmt.Errorf("got %d sent packets after clear; want 0", st.Send.Packets)
+		}
+		if st.Send.Total.Packets < N {
+			return fmt.Errorf("got %d total sent packets after clear; want at least %d", st.Send.Total.Packets, N)
+		}
+
+		b, err = json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return err
+		}
+		for _, key := range []string{"sid", "time", "window", "link", "send", "recv"} {
+			if _, ok := m[key]; !ok {
+				return fmt.Errorf("missing key %q in %s", key, b)
+			}
+		}
+		send := m["send"].(map[string]interface{})
+		for _, key := range []string{"packets", "mbitRate", "total"} {
+			if _, ok := send[key]; !ok {
+				return fmt.Errorf("missing key %q in send stats %s", key, b)
+			}
+		}
+		return nil
+	}
+	withSRTConnPair(t, server, client)
+}
+
+func TestStatsJSON(t *testing.T) {
+	st := Stats{SocketID: 1, Time: 1500 * time.Millisecond}
+	st.Link.RTT = 20
+	st.Send.Packets = 10
+	st.Send.Duration = 250 * time.Millisecond
+	st.Send.Total.Duration = 2 * time.Second
+	st.Recv.BufferMs = 120
+	b, err := json.Marshal(&st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct {
+		Time int64
+		Send struct {
+			Packets  int64
+			Duration int64
+			Total    struct{ Duration int64 }
+		}
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	// the durations are in milliseconds
+	if m.Time != 1500 || m.Send.Packets != 10 || m.Send.Duration != 250 || m.Send.Total.Duration != 2000 {
+		t.Errorf("got %s", b)
+	}
+	var st2 Stats
+	if err := json.Unmarshal(b, &st2); err != nil {
+		t.Fatal(err)
+	}
+	if st2 != st {
+		t.Errorf("got %+v; want %+v", st2, st)
+	}
+}
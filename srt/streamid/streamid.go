@@ -0,0 +1,375 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package streamid parses and builds SRT stream IDs in the access
+// control syntax of the SRT library:
+//
+//	#!::r=live/stream1,u=admin,m=publish
+//
+// The nested form "#!:{r=live/stream1,u=admin}" is accepted as well.
+// Values can contain braced, nested lists such as "x={a=1,b=2}", which
+// are kept verbatim. A backslash escapes the following character, so
+// that a value can contain ',', '{', '}' or '\'.
+//
+// A stream ID that does not start with "#!:" is a plain string; it is
+// stored in StreamID.Plain.
+package streamid
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxLen is the maximum length of a stream ID in bytes.
+const MaxLen = 512
+
+// Type is the type of the stream, the value of the "t" key.
+type Type string
+
+// Stream types
+const (
+	TypeStream Type = "stream"
+	TypeFile   Type = "file"
+	TypeAuth   Type = "auth"
+)
+
+// Mode is the mode of the connection, the value of the "m" key.
+type Mode string
+
+// Connection modes
+const (
+	ModeRequest       Mode = "request"
+	ModePublish       Mode = "publish"
+	ModeBidirectional Mode = "bidirectional"
+)
+
+// StreamID is a parsed stream ID. The zero value of a field means the
+// key is absent.
+type StreamID struct {
+	Resource string // r: resource name, e.g. a stream or file name
+	User     string // u: user name
+	Host     string // h: host name of the target
+	Session  string // s: session id
+	Type     Type   // t: type of the stream
+	Mode     Mode   // m: mode of the connection
+
+	// Custom holds the keys that are not standard keys.
+	Custom map[string]string
+
+	// Plain is the whole stream ID if it is not in the access
+	// control syntax. The other fields are empty then.
+	Plain string
+}
+
+// SyntaxError describes a malformed stream ID.
+type SyntaxError struct {
+	StreamID string // the stream ID
+	Offset   int    // byte offset of the error
+	Msg      string // description of the error
+}
+
+func (e *SyntaxError) Error() string {
+	return "streamid: " + e.Msg + " at offset " + strconv.Itoa(e.Offset) + " in " + strconv.Quote(e.StreamID)
+}
+
+var (
+	errTooLong   = errors.New("streamid: longer than 512 bytes")
+	errPlainKeys = errors.New("streamid: plain stream ID with keys")
+)
+
+const (
+	prefix       = "#!:"
+	standardForm = "#!::"
+)
+
+// escaped reports whether the byte of s at i is escaped, that is
+// preceded by an odd number of backslashes.
+func escaped(s string, i int) bool {
+	n := 0
+	for i > 0 && s[i-1] == '\\' {
+		n++
+		i--
+	}
+	return n%2 == 1
+}
+
+// Parse parses s. A string that does not start with "#!:" results in
+// a StreamID with only Plain set.
+func Parse(s string) (*StreamID, error) {
+	if len(s) > MaxLen {
+		return nil, errTooLong
+	}
+	if !strings.HasPrefix(s, prefix) {
+		return &StreamID{Plain: s}, nil
+	}
+	var body string
+	var offset int
+	switch {
+	case strings.HasPrefix(s, standardForm):
+		body, offset = s[len(standardForm):], len(standardForm)
+	case strings.HasPrefix(s, prefix+"{"):
+		if !strings.HasSuffix(s, "}") || escaped(s, len(s)-1) {
+			return nil, &SyntaxError{s, len(s), "missing closing brace"}
+		}
+		body, offset = s[len(prefix)+1:len(s)-1], len(prefix)+1
+	default:
+		return nil, &SyntaxError{s, len(prefix), "unknown syntax"}
+	}
+
+	id := &StreamID{}
+	items, err := split(body, ',')
+	if err != nil {
+		err.(*SyntaxError).StreamID = s
+		err.(*SyntaxError).Offset += offset
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, it := range items {
+		item := body[it.start:it.end]
+		kv, _ := split(item, '=')
+		if len(kv) < 2 {
+			if item == "" {
+				continue
+			}
+			return nil, &SyntaxError{s, offset + it.start, "missing '='"}
+		}
+		key := unescape(item[:kv[0].end])
+		value := unescape(item[kv[1].start:])
+		if key == "" {
+			return nil, &SyntaxError{s, offset + it.start, "empty key"}
+		}
+		if seen[key] {
+			return nil, &SyntaxError{s, offset + it.start, "duplicate key " + strconv.Quote(key)}
+		}
+		seen[key] = true
+		id.set(key, value)
+	}
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+func (id *StreamID) set(key, value string) {
+	switch key {
+	case "r":
+		id.Resource = value
+	case "u":
+		id.User = value
+	case "h":
+		id.Host = value
+	case "s":
+		id.Session = value
+	case "t":
+		id.Type = Type(value)
+	case "m":
+		id.Mode = Mode(value)
+	default:
+		if id.Custom == nil {
+			id.Custom = make(map[string]string)
+		}
+		id.Custom[key] = value
+	}
+}
+
+// Get returns the value of key, which can be a standard or a custom
+// key.
+func (id *StreamID) Get(key string) string {
+	switch key {
+	case "r":
+		return id.Resource
+	case "u":
+		return id.User
+	case "h":
+		return id.Host
+	case "s":
+		return id.Session
+	case "t":
+		return string(id.Type)
+	case "m":
+		return string(id.Mode)
+	}
+	return id.Custom[key]
+}
+
+// Validate reports whether id can be serialized into a valid stream
+// ID.
+func (id *StreamID) Validate() error {
+	if id.Plain != "" {
+		if strings.HasPrefix(id.Plain, prefix) {
+			return errors.New("streamid: plain stream ID starts with " + strconv.Quote(prefix))
+		}
+		if id.hasKeys() {
+			return errPlainKeys
+		}
+	}
+	switch id.Type {
+	case "", TypeStream, TypeFile, TypeAuth:
+	default:
+		return errors.New("streamid: unknown type " + strconv.Quote(string(id.Type)))
+	}
+	switch id.Mode {
+	case "", ModeRequest, ModePublish, ModeBidirectional:
+	default:
+		return errors.New("streamid: unknown mode " + strconv.Quote(string(id.Mode)))
+	}
+	for k := range id.Custom {
+		if k == "" {
+			return errors.New("streamid: empty key")
+		}
+		switch k {
+		case "r", "u", "h", "s", "t", "m":
+			return errors.New("streamid: standard key " + strconv.Quote(k) + " in Custom")
+		}
+	}
+	if len(id.String()) > MaxLen {
+		return errTooLong
+	}
+	return nil
+}
+
+func (id *StreamID) hasKeys() bool {
+	return id.Resource != "" || id.User != "" || id.Host != "" || id.Session != "" ||
+		id.Type != "" || id.Mode != "" || len(id.Custom) != 0
+}
+
+// String returns the stream ID in the standard access control syntax,
+// or Plain if id has no keys. The standard keys come first, the custom
+// keys follow in sorted order.
+func (id *StreamID) String() string {
+	if !id.hasKeys() {
+		return id.Plain
+	}
+	var b strings.Builder
+	b.WriteString(standardForm)
+	n := 0
+	add := func(k, v string) {
+		if v == "" {
+			return
+		}
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(escape(k))
+		b.WriteByte('=')
+		b.WriteString(escape(v))
+		n++
+	}
+	add("r", id.Resource)
+	add("u", id.User)
+	add("h", id.Host)
+	add("s", id.Session)
+	add("t", string(id.Type))
+	add("m", string(id.Mode))
+	keys := make([]string, 0, len(id.Custom))
+	for k := range id.Custom {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, id.Custom[k])
+	}
+	return b.String()
+}
+
+type span struct {
+	start, end int
+}
+
+// split splits s at the unescaped occurrences of sep outside of
+// braces. For sep '=' only the first occurrence splits.
+func split(s string, sep byte) ([]span, error) {
+	var spans []span
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, &SyntaxError{Offset: i, Msg: "trailing backslash"}
+			}
+			i++
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return nil, &SyntaxError{Offset: i, Msg: "unexpected closing brace"}
+			}
+			depth--
+		case c == sep && depth == 0:
+			spans = append(spans, span{start, i})
+			start = i + 1
+			if sep == '=' {
+				spans = append(spans, span{start, len(s)})
+				return spans, nil
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, &SyntaxError{Offset: len(s), Msg: "missing closing brace"}
+	}
+	return append(spans, span{start, len(s)}), nil
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// escape escapes backslashes and the separators ',' and '=' in s.
+// Balanced braces are kept, and separators inside them are not
+// escaped, so that nested lists survive a round trip; unbalanced
+// braces are escaped.
+func escape(s string) string {
+	keepBraces := balanced(s)
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			b.WriteByte('\\')
+		case c == '{' || c == '}':
+			if !keepBraces {
+				b.WriteByte('\\')
+			} else if c == '{' {
+				depth++
+			} else {
+				depth--
+			}
+		case (c == ',' || c == '=') && depth == 0:
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// balanced reports whether the braces in the unescaped string s are
+// balanced.
+func balanced(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return false
+			}
+			depth--
+		}
+	}
+	return depth == 0
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package streamid
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var parseTests = []struct {
+	in   string
+	want StreamID
+	out  string // String() of the result; in if empty
+}{
+	{"", StreamID{}, ""},
+	{"live/stream1", StreamID{Plain: "live/stream1"}, ""},
+	{"#!::", StreamID{}, "-"},
+	{"#!::r=live/stream1", StreamID{Resource: "live/stream1"}, ""},
+	{
+		"#!::u=admin,r=bluesbrothers1_hi",
+		StreamID{Resource: "bluesbrothers1_hi", User: "admin"},
+		"#!::r=bluesbrothers1_hi,u=admin",
+	},
+	{
+		"#!::r=movie.ts,u=john,h=example.com,s=abc123,t=file,m=request",
+		StreamID{Resource: "movie.ts", User: "john", Host: "example.com", Session: "abc123", Type: TypeFile, Mode: ModeRequest},
+		"",
+	},
+	{
+		"#!::r=live,m=publish,region=eu,tier=gold",
+		StreamID{Resource: "live", Mode: ModePublish, Custom: map[string]string{"region": "eu", "tier": "gold"}},
+		"",
+	},
+	{
+		"#!:{r=live,u=admin}",
+		StreamID{Resource: "live", User: "admin"},
+		"#!::r=live,u=admin",
+	},
+	{
+		"#!::r=live,x={a=1,b=2}",
+		StreamID{Resource: "live", Custom: map[string]string{"x": "{a=1,b=2}"}},
+		"",
+	},
+	{
+		`#!::r=a\,b\=c\\d,u=\{x`,
+		StreamID{Resource: `a,b=c\d`, User: "{x"},
+		"",
+	},
+	{"#!::r=a=b", StreamID{Resource: "a=b"}, `#!::r=a\=b`},
+	{`#!:{r=a\\}`, StreamID{Resource: `a\`}, `#!::r=a\\`},
+	{`#!:{r=a\\\\}`, StreamID{Resource: `a\\`}, `#!::r=a\\\\`},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		id, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(*id, tt.want) {
+			t.Errorf("Parse(%q) = %+v; want %+v", tt.in, *id, tt.want)
+		}
+		want := tt.out
+		switch want {
+		case "":
+			want = tt.in
+		case "-":
+			want = ""
+		}
+		if s := id.String(); s != want {
+			t.Errorf("Parse(%q).String() = %q; want %q", tt.in, s, want)
+		}
+		// round trip
+		id2, err := Parse(id.String())
+		if err != nil {
+			t.Errorf("Parse(%q): %v", id.String(), err)
+			continue
+		}
+		if !reflect.DeepEqual(id, id2) {
+			t.Errorf("round trip of %q = %+v; want %+v", tt.in, *id2, *id)
+		}
+	}
+}
+
+var parseErrorTests = []string{
+	"#!:r=live",
+	"#!::r",
+	"#!::=live",
+	"#!::r=a,r=b",
+	"#!::r=a}",
+	"#!::r={a",
+	`#!::r=a\`,
+	"#!:{r=live",
+	`#!:{r=a\}`,
+	`#!:{r=a\\\}`,
+	"#!::t=video",
+	"#!::m=pull",
+	"#!::r=" + strings.Repeat("x", MaxLen),
+}
+
+func TestParseError(t *testing.T) {
+	for _, in := range parseErrorTests {
+		if id, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %+v; want error", in, *id)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, id := range []StreamID{
+		{Plain: "#!::r=x"},
+		{Plain: "x", User: "u"},
+		{Type: "video"},
+		{Mode: "pull"},
+		{Custom: map[string]string{"r": "x"}},
+		{Custom: map[string]string{"": "x"}},
+		{Resource: strings.Repeat("x", MaxLen)},
+	} {
+		if err := id.Validate(); err == nil {
+			t.Errorf("%+v: got no error", id)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	id, err := Parse("#!::r=live,u=admin,region=eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]string{"r": "live", "u": "admin", "region": "eu", "m": "", "zone": ""} {
+		if v := id.Get(k); v != want {
+			t.Errorf("Get(%q) = %q; want %q", k, v, want)
+		}
+	}
+}
@@ -213,3 +213,33 @@ func TestAcceptTimeout(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+// The zero time clears a deadline, also one that has already passed.
+func TestDeadlineReset(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sln := ln.(*SRTListener)
+	if err := sln.SetDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sln.SetDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dialed := make(chan net.Conn, 1)
+	go func() {
+		c, _ := Dial(ln.Addr().Network(), ln.Addr().String())
+		dialed <- c
+	}()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc := <-dialed; dc != nil {
+		dc.Close()
+	}
+	c.Close()
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import "sync"
+
+// A ConnTracker is notified when SRT connections and listeners are
+// opened and closed. It allows to follow all live connections of the
+// process, e.g. to export their statistics.
+//
+// The methods are called synchronously from the goroutine opening or
+// closing the connection and must not block.
+type ConnTracker interface {
+	// ConnOpened is called when a connection was dialed or
+	// accepted.
+	ConnOpened(c *SRTConn)
+
+	// ConnClosed is called when a connection was closed. c is the
+	// same value that was passed to ConnOpened.
+	ConnClosed(c *SRTConn)
+
+	// ListenerOpened is called when a listener was opened.
+	ListenerOpened(l *SRTListener)
+
+	// ListenerClosed is called when a listener was closed.
+	ListenerClosed(l *SRTListener)
+}
+
+var connTracking struct {
+	sync.Mutex
+	trackers  []ConnTracker
+	conns     map[*netFD]*SRTConn
+	listeners map[*netFD]*SRTListener
+}
+
+// AddConnTracker registers t to be notified about connections and
+// listeners opened from now on.
+func AddConnTracker(t ConnTracker) {
+	connTracking.Lock()
+	defer connTracking.Unlock()
+	if connTracking.conns == nil {
+		connTracking.conns = make(map[*netFD]*SRTConn)
+		connTracking.listeners = make(map[*netFD]*SRTListener)
+	}
+	connTracking.trackers = append(connTracking.trackers, t)
+}
+
+// RemoveConnTracker unregisters t.
+func RemoveConnTracker(t ConnTracker) {
+	connTracking.Lock()
+	defer connTracking.Unlock()
+	for i, tt := range connTracking.trackers {
+		if tt == t {
+			connTracking.trackers = append(connTracking.trackers[:i], connTracking.trackers[i+1:]...)
+			break
+		}
+	}
+}
+
+func trackConn(c *SRTConn) {
+	connTracking.Lock()
+	defer connTracking.Unlock()
+	if len(connTracking.trackers) == 0 {
+		return
+	}
+	connTracking.conns[c.fd] = c
+	for _, t := range connTracking.trackers {
+		t.ConnOpened(c)
+	}
+}
+
+func untrackConn(fd *netFD) {
+	connTracking.Lock()
+	defer connTracking.Unlock()
+	c, ok := connTracking.conns[fd]
+	if !ok {
+		return
+	}
+	delete(connTracking.conns, fd)
+	for _, t := range connTracking.trackers {
+		t.ConnClosed(c)
+	}
+}
+
+func trackListener(l *SRTListener) {
+	connTracking.Lock()
+	defer connTracking.Unlock()
+	if len(connTracking.trackers) == 0 {
+		return
+	}
+	connTracking.listeners[l.fd] = l
+	for _, t := range connTracking.trackers {
+		t.ListenerOpened(l)
+	}
+}
+
+func untrackListener(fd *netFD) {
+	connTracking.Lock()
+	defer connTracking.Unlock()
+	l, ok := connTracking.listeners[fd]
+	if !ok {
+		return
+	}
+	delete(connTracking.listeners, fd)
+	for _, t := range connTracking.trackers {
+		t.ListenerClosed(l)
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xmedia-systems/gosrt/srtapi"
+)
+
+// Mode is the connection mode of an SRT URI.
+type Mode int
+
+// Connection modes
+const (
+	// ModeCaller connects to a listener.
+	ModeCaller Mode = iota
+
+	// ModeListener waits for callers.
+	ModeListener
+
+	// ModeRendezvous connects to a peer dialing back at the same
+	// time.
+	ModeRendezvous
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeCaller:
+		return "caller"
+	case ModeListener:
+		return "listener"
+	case ModeRendezvous:
+		return "rendezvous"
+	}
+	return "Mode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// URI is a parsed SRT URI of the form
+//
+//	srt://[host]:port[?param=value[&param=value...]]
+//
+// as used by srt-live-transmit and ffmpeg.
+//
+// The parameter mode selects the connection mode: "caller" (or
+// "client"), "listener" (or "server") or "rendezvous". Without it, the
+// mode is listener if the host is empty and caller otherwise. The
+// parameter adapter is the local IP address and port the local port
+// of a caller or a rendezvous peer. A rendezvous peer uses the port of
+// the URI as local port unless port is given. A listener binds to the
+// host, or to adapter if the host is empty.
+//
+// All other parameters are socket options. Their values are not
+// required to be escaped, so that a stream ID such as
+// "#!::r=live,m=request" can be given as it is.
+type URI struct {
+	// Mode is the connection mode.
+	Mode Mode
+
+	// Address is the address of the listener or the rendezvous peer
+	// to connect to, or the local address of a listener.
+	Address string
+
+	// LocalAddr is the local address of a caller or a rendezvous
+	// peer, or empty if the system chooses it.
+	LocalAddr string
+
+	// Options holds the socket options given as parameters, in the
+	// string form used by Options.
+	Options OptionSet
+}
+
+var errURIScheme = errors.New(`scheme must be "srt"`)
+
+// uriParam describes how a parameter of an ffmpeg URI maps to a socket
+// option.
+type uriParam struct {
+	name string // name of the socket option
+	usec bool   // the value is in microseconds instead of milliseconds
+}
+
+// ffmpegParams lists the parameters of ffmpeg whose name or unit
+// differ from the socket option. The other parameters are the same.
+var ffmpegParams = map[string]uriParam{
+	"latency":             {"latency", true},
+	"rcvlatency":          {"rcvlatency", true},
+	"peerlatency":         {"peerlatency", true},
+	"snddropdelay":        {"snddropdelay", true},
+	"connect_timeout":     {"conntimeo", false},
+	"tsbpd":               {"tsbpdmode", false},
+	"enforced_encryption": {"enforcedencryption", false},
+	"ffs":                 {"fc", false},
+	"payload_size":        {"payloadsize", false},
+	"pkt_size":            {"payloadsize", false},
+	"smoother":            {"congestion", false},
+	"srt_streamid":        {"streamid", false},
+}
+
+// ffmpegIgnoredParams lists the parameters of ffmpeg that set timeouts
+// of ffmpeg itself in microseconds rather than socket options. Their
+// values are checked, but not used; the deadlines of a connection take
+// their place.
+var ffmpegIgnoredParams = map[string]bool{
+	"timeout":        true,
+	"listen_timeout": true,
+	"rw_timeout":     true,
+}
+
+// ParseURI parses an SRT URI in the form used by srt-live-transmit.
+// The parameters are named like the socket options of Options and have
+// the same units, latencies and timeouts are in milliseconds.
+// Integers may be given in hexadecimal as in "minversion=0x010300",
+// booleans as "yes", "no", "on", "off", "true", "false", "1" or "0",
+// and transtype as "live" or "file".
+func ParseURI(uri string) (*URI, error) {
+	return parseURI(uri, false)
+}
+
+// ParseFFmpegURI parses an SRT URI in the form used by ffmpeg. It
+// differs from ParseURI in that latency, rcvlatency, peerlatency and
+// snddropdelay are in microseconds, and in that it also accepts the
+// ffmpeg parameter names connect_timeout, tsbpd, enforced_encryption,
+// ffs, payload_size, pkt_size, smoother and srt_streamid. A negative
+// value in microseconds, such as the default -1 of ffmpeg, leaves the
+// option unset; a positive one must be at least a millisecond. The
+// timeouts of ffmpeg itself, timeout, listen_timeout and rw_timeout,
+// must be integers and are ignored.
+func ParseFFmpegURI(uri string) (*URI, error) {
+	return parseURI(uri, true)
+}
+
+func parseURI(uri string, ffmpeg bool) (*URI, error) {
+	rest := uri
+	i := strings.Index(rest, "://")
+	if i < 0 || !strings.EqualFold(rest[:i], "srt") {
+		scheme := ""
+		if i >= 0 {
+			scheme = rest[:i]
+		}
+		return nil, &ConfigError{Field: "URI", Value: scheme, Err: errURIScheme}
+	}
+	rest = rest[i+3:]
+	var query string
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		rest, query = rest[:i], rest[i+1:]
+	}
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		if rest[i:] != "/" {
+			return nil, &ConfigError{Field: "URI", Value: rest[i:], Err: errors.New("unexpected path")}
+		}
+		rest = rest[:i]
+	}
+	host, portStr, err := net.SplitHostPort(rest)
+	if err != nil {
+		return nil, &ConfigError{Field: "URI", Value: rest, Err: err}
+	}
+	port, err := parseURIPort(portStr)
+	if err != nil {
+		return nil, &ConfigError{Field: "port", Value: portStr, Err: err}
+	}
+
+	u := &URI{Mode: ModeCaller}
+	if host == "" {
+		u.Mode = ModeListener
+	}
+	var (
+		adapter   string
+		localPort = -1
+		options   = make(map[string]string)
+	)
+	for _, kv := range strings.Split(query, "&") {
+		if kv == "" {
+			continue
+		}
+		key, value := kv, ""
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			key, value = kv[:i], kv[i+1:]
+		}
+		if key, err = url.PathUnescape(key); err != nil {
+			return nil, &ConfigError{Field: "URI", Value: kv, Err: err}
+		}
+		raw := value
+		if value, err = url.PathUnescape(value); err != nil {
+			if key == "passphrase" {
+				// do not leak the passphrase into error messages
+				raw = strings.Repeat("*", len(raw))
+			}
+			return nil, &ConfigError{Field: key, Value: raw, Err: err}
+		}
+		switch key {
+		case "mode":
+			switch value {
+			case "caller", "client":
+				u.Mode = ModeCaller
+			case "listener", "server":
+				u.Mode = ModeListener
+			case "rendezvous":
+				u.Mode = ModeRendezvous
+			default:
+				return nil, &ConfigError{Field: key, Value: value, Err: errors.New("must be caller, listener or rendezvous")}
+			}
+			continue
+		case "adapter":
+			adapter = value
+			continue
+		case "port":
+			if localPort, err = parseURIPort(value); err != nil {
+				return nil, &ConfigError{Field: key, Value: value, Err: err}
+			}
+			continue
+		}
+		if ffmpeg && ffmpegIgnoredParams[key] {
+			if _, err := strconv.ParseInt(value, 0, 64); err != nil {
+				return nil, &ConfigError{Field: key, Value: value, Err: err.(*strconv.NumError).Err}
+			}
+			continue
+		}
+		p := uriParam{name: key}
+		if ffmpeg {
+			if fp, ok := ffmpegParams[key]; ok {
+				p = fp
+			}
+		}
+		if p.unset(value) {
+			continue
+		}
+		v, err := p.convert(value)
+		if err != nil {
+			return nil, &ConfigError{Field: key, Value: value, Err: err}
+		}
+		options[p.name] = v
+	}
+
+	switch u.Mode {
+	case ModeListener:
+		if localPort >= 0 {
+			return nil, &ConfigError{Field: "port", Value: localPort, Err: errors.New("not allowed in listener mode")}
+		}
+		if host == "" {
+			host = adapter
+		}
+		u.Address = net.JoinHostPort(host, portStr)
+	case ModeCaller, ModeRendezvous:
+		if host == "" {
+			return nil, &ConfigError{Field: "URI", Value: rest, Err: errMissingAddress}
+		}
+		if port == 0 {
+			return nil, &ConfigError{Field: "port", Value: portStr, Err: errors.New("zero port")}
+		}
+		u.Address = net.JoinHostPort(host, portStr)
+		if u.Mode == ModeRendezvous && localPort < 0 {
+			localPort = port
+		}
+		if adapter != "" || localPort >= 0 {
+			if localPort < 0 {
+				localPort = 0
+			}
+			u.LocalAddr = net.JoinHostPort(adapter, strconv.Itoa(localPort))
+		}
+	}
+
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		u.Options.list = append(u.Options.list, option{key: k, value: options[k]})
+	}
+	return u, nil
+}
+
+func parseURIPort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok {
+			err = ne.Err
+		}
+		return 0, err
+	}
+	if port < 0 || port > 0xffff {
+		return 0, errors.New("out of range")
+	}
+	return port, nil
+}
+
+// unset reports whether value leaves the option of p unset, as the
+// negative values ffmpeg uses as defaults of the options in
+// microseconds do.
+func (p uriParam) unset(value string) bool {
+	if !p.usec {
+		return false
+	}
+	v, err := strconv.ParseInt(value, 0, 64)
+	return err == nil && v < 0
+}
+
+// convert returns value in the string form of the socket option of p.
+func (p uriParam) convert(value string) (string, error) {
+	o := lookupOption(p.name)
+	if o == nil {
+		return "", errUnknownOption
+	}
+	if o.binding == bindNone {
+		return "", errReadOnlyOption
+	}
+	if o.sym == srtapi.OptionRendezvous {
+		return "", errors.New("use mode=rendezvous")
+	}
+	switch o.typ {
+	case typeBool:
+		switch strings.ToLower(value) {
+		case "1", "yes", "on", "true":
+			return "true", nil
+		case "0", "no", "off", "false":
+			return "false", nil
+		}
+		return "", strconv.ErrSyntax
+	case typeInt, typeInt64:
+		if o.sym == srtapi.OptionTranstype {
+			switch value {
+			case "live":
+				return strconv.Itoa(srtapi.TypeLive), nil
+			case "file":
+				return strconv.Itoa(srtapi.TypeFile), nil
+			}
+		}
+		v, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return "", err.(*strconv.NumError).Err
+		}
+		if p.usec {
+			if v != 0 && v/1000 == 0 {
+				return "", errors.New("less than a millisecond")
+			}
+			v /= 1000
+		}
+		return strconv.FormatInt(v, 10), nil
+	}
+	return value, nil
+}
+
+// Context returns a new context.Context with the options of u added.
+func (u *URI) Context(ctx context.Context) context.Context {
+	return WithOptions(ctx, u.Options)
+}
+
+// Dial connects as a caller or a rendezvous peer as described by u,
+// with the options of u added to ctx.
+func (u *URI) Dial(ctx context.Context) (net.Conn, error) {
+	ctx = u.Context(ctx)
+	switch u.Mode {
+	case ModeCaller:
+		var d Dialer
+		if u.LocalAddr != "" {
+			la, err := ResolveSRTAddr("srt", u.LocalAddr)
+			if err != nil {
+				return nil, &OpError{Op: "dial", Net: "srt", Source: nil, Addr: nil, Err: err}
+			}
+			d.LocalAddr = la
+		}
+		return d.DialContext(ctx, "srt", u.Address)
+	case ModeRendezvous:
+		return DialRendezvous(ctx, "srt", u.LocalAddr, u.Address)
+	}
+	return nil, &OpError{Op: "dial", Net: "srt", Source: nil, Addr: nil, Err: errors.New("cannot dial in " + u.Mode.String() + " mode")}
+}
+
+// Listen announces on the local address of a listener URI, with the
+// options of u added to ctx.
+func (u *URI) Listen(ctx context.Context) (net.Listener, error) {
+	if u.Mode != ModeListener {
+		return nil, &OpError{Op: "listen", Net: "srt", Source: nil, Addr: nil, Err: errors.New("cannot listen in " + u.Mode.String() + " mode")}
+	}
+	return ListenContext(u.Context(ctx), "srt", u.Address)
+}
+
+// DialURI parses uri with ParseURI and connects as described by it.
+func DialURI(ctx context.Context, uri string) (net.Conn, error) {
+	u, err := ParseURI(uri)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: "srt", Source: nil, Addr: nil, Err: err}
+	}
+	return u.Dial(ctx)
+}
+
+// ListenURI parses uri with ParseURI and announces on the local
+// address of it. The mode of uri must be listener.
+func ListenURI(ctx context.Context, uri string) (net.Listener, error) {
+	u, err := ParseURI(uri)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: "srt", Source: nil, Addr: nil, Err: err}
+	}
+	return u.Listen(ctx)
+}
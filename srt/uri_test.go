@@ -0,0 +1,247 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func (o OptionSet) toMap() optionMap {
+	m := make(optionMap)
+	for _, opt := range o.list {
+		m[opt.key] = opt.value
+	}
+	return m
+}
+
+var parseURITests = []struct {
+	uri    string
+	ffmpeg bool
+
+	mode      Mode
+	address   string
+	localAddr string
+	options   optionMap
+}{
+	// srt-live-transmit
+	{
+		uri:     "srt://example.com:9000",
+		mode:    ModeCaller,
+		address: "example.com:9000",
+	},
+	{
+		uri:     "srt://:9000",
+		mode:    ModeListener,
+		address: ":9000",
+	},
+	{
+		uri:     "srt://:9000?adapter=10.0.0.1",
+		mode:    ModeListener,
+		address: "10.0.0.1:9000",
+	},
+	{
+		uri:     "SRT://0.0.0.0:9000/?mode=server&latency=200&passphrase=0123456789&pbkeylen=16",
+		mode:    ModeListener,
+		address: "0.0.0.0:9000",
+		options: optionMap{"latency": "200", "passphrase": "0123456789", "pbkeylen": "16"},
+	},
+	{
+		uri:       "srt://[::1]:9000?mode=client&adapter=::1&port=5000&transtype=file&tlpktdrop=no&messageapi=on",
+		mode:      ModeCaller,
+		address:   "[::1]:9000",
+		localAddr: "[::1]:5000",
+		options:   optionMap{"transtype": "1", "tlpktdrop": "false", "messageapi": "true"},
+	},
+	{
+		uri:       "srt://10.0.0.2:9000?mode=caller&adapter=10.0.0.1",
+		mode:      ModeCaller,
+		address:   "10.0.0.2:9000",
+		localAddr: "10.0.0.1:0",
+	},
+	{
+		uri:       "srt://10.0.0.2:9000?mode=rendezvous",
+		mode:      ModeRendezvous,
+		address:   "10.0.0.2:9000",
+		localAddr: ":9000",
+	},
+	{
+		uri:       "srt://10.0.0.2:9000?mode=rendezvous&adapter=10.0.0.1&port=9001",
+		mode:      ModeRendezvous,
+		address:   "10.0.0.2:9000",
+		localAddr: "10.0.0.1:9001",
+	},
+	{
+		uri:     "srt://example.com:9000?streamid=#!::r=live/cam1,m=publish&minversion=0x010300&maxbw=-1",
+		mode:    ModeCaller,
+		address: "example.com:9000",
+		options: optionMap{"streamid": "#!::r=live/cam1,m=publish", "minversion": "66304", "maxbw": "-1"},
+	},
+	{
+		uri:     "srt://example.com:9000?streamid=a%26b%3Dc&packetfilter=fec,cols:10,rows:5&conntimeo=3000",
+		mode:    ModeCaller,
+		address: "example.com:9000",
+		options: optionMap{"streamid": "a&b=c", "packetfilter": "fec,cols:10,rows:5", "conntimeo": "3000"},
+	},
+
+	// ffmpeg
+	{
+		uri:     "srt://example.com:9000?latency=200000&rcvlatency=120000&peerlatency=80000&snddropdelay=1000",
+		ffmpeg:  true,
+		mode:    ModeCaller,
+		address: "example.com:9000",
+		options: optionMap{"latency": "200", "rcvlatency": "120", "peerlatency": "80", "snddropdelay": "1"},
+	},
+	{
+		uri:     "srt://0.0.0.0:5001?mode=listener&pkt_size=1316&connect_timeout=5000&tsbpd=1&ffs=25600&smoother=live",
+		ffmpeg:  true,
+		mode:    ModeListener,
+		address: "0.0.0.0:5001",
+		options: optionMap{"payloadsize": "1316", "conntimeo": "5000", "tsbpdmode": "true", "fc": "25600", "congestion": "live"},
+	},
+	{
+		uri:     "srt://127.0.0.1:5000?streamid=#!::u=user,t=file,m=publish,r=results.csv&passphrase=verylongpassword&enforced_encryption=0",
+		ffmpeg:  true,
+		mode:    ModeCaller,
+		address: "127.0.0.1:5000",
+		options: optionMap{"streamid": "#!::u=user,t=file,m=publish,r=results.csv", "passphrase": "verylongpassword", "enforcedencryption": "false"},
+	},
+	{
+		uri:     "srt://127.0.0.1:5000?srt_streamid=cam1&payload_size=1456&transtype=live",
+		ffmpeg:  true,
+		mode:    ModeCaller,
+		address: "127.0.0.1:5000",
+		options: optionMap{"streamid": "cam1", "payloadsize": "1456", "transtype": "0"},
+	},
+	{
+		uri:     "srt://:5001?timeout=5000000&listen_timeout=-1&rw_timeout=2500&latency=1500",
+		ffmpeg:  true,
+		mode:    ModeListener,
+		address: ":5001",
+		options: optionMap{"latency": "1"},
+	},
+	{
+		uri:     "srt://example.com:9000?latency=-1&rcvlatency=-1&peerlatency=0&snddropdelay=-2",
+		ffmpeg:  true,
+		mode:    ModeCaller,
+		address: "example.com:9000",
+		options: optionMap{"peerlatency": "0"},
+	},
+}
+
+func TestParseURI(t *testing.T) {
+	for _, tt := range parseURITests {
+		parse := ParseURI
+		if tt.ffmpeg {
+			parse = ParseFFmpegURI
+		}
+		u, err := parse(tt.uri)
+		if err != nil {
+			t.Errorf("%s: %v", tt.uri, err)
+			continue
+		}
+		if u.Mode != tt.mode || u.Address != tt.address || u.LocalAddr != tt.localAddr {
+			t.Errorf("%s: got %v %q %q; want %v %q %q", tt.uri, u.Mode, u.Address, u.LocalAddr, tt.mode, tt.address, tt.localAddr)
+		}
+		want := tt.options
+		if want == nil {
+			want = optionMap{}
+		}
+		if got := u.Options.toMap(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got options %v; want %v", tt.uri, got, want)
+		}
+	}
+}
+
+var parseURIErrorTests = []struct {
+	uri    string
+	field  string
+	ffmpeg bool
+}{
+	{"udp://127.0.0.1:9000", "URI", false},
+	{"127.0.0.1:9000", "URI", false},
+	{"srt://127.0.0.1", "URI", false},
+	{"srt://127.0.0.1:9000/live", "URI", false},
+	{"srt://127.0.0.1:http", "port", false},
+	{"srt://127.0.0.1:0", "port", false},
+	{"srt://:9000?mode=caller", "URI", false},
+	{"srt://:9000?port=5000", "port", false},
+	{"srt://127.0.0.1:9000?mode=push", "mode", false},
+	{"srt://127.0.0.1:9000?port=70000", "port", false},
+	{"srt://127.0.0.1:9000?latency=fast", "latency", false},
+	{"srt://127.0.0.1:9000?tlpktdrop=maybe", "tlpktdrop", false},
+	{"srt://127.0.0.1:9000?nonexistent=1", "nonexistent", false},
+	{"srt://127.0.0.1:9000?kmstate=1", "kmstate", false},
+	{"srt://127.0.0.1:9000?rendezvous=1", "rendezvous", false},
+	{"srt://127.0.0.1:9000?streamid=%zz", "streamid", false},
+	{"srt://127.0.0.1:9000?pkt_size=1316", "pkt_size", false},  // ffmpeg only
+	{"srt://127.0.0.1:9000?timeout=5000000", "timeout", false}, // ffmpeg only
+
+	// ffmpeg
+	{"srt://127.0.0.1:9000?latency=999", "latency", true},
+	{"srt://127.0.0.1:9000?peerlatency=1", "peerlatency", true},
+	{"srt://127.0.0.1:9000?timeout=5s", "timeout", true},
+	{"srt://:9000?listen_timeout=", "listen_timeout", true},
+	{"srt://127.0.0.1:9000?rw_timeout=1e6", "rw_timeout", true},
+}
+
+func TestParseURIErrors(t *testing.T) {
+	for _, tt := range parseURIErrorTests {
+		parse := ParseURI
+		if tt.ffmpeg {
+			parse = ParseFFmpegURI
+		}
+		u, err := parse(tt.uri)
+		ce, ok := err.(*ConfigError)
+		if !ok {
+			t.Errorf("%s: got %+v, %v; want *ConfigError", tt.uri, u, err)
+			continue
+		}
+		if ce.Field != tt.field {
+			t.Errorf("%s: got field %s; want %s", tt.uri, ce.Field, tt.field)
+		}
+	}
+}
+
+func TestDialListenURI(t *testing.T) {
+	ln, err := ListenURI(context.Background(), "srt://127.0.0.1:0?mode=listener&latency=300")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), someTimeout)
+	defer cancel()
+	c, err := DialURI(ctx, "srt://127.0.0.1:"+port+"?streamid=#!::r=cam1&latency=300")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := <-accepted
+	if sc == nil {
+		t.FailNow()
+	}
+	defer sc.Close()
+	if id, err := sc.(*SRTConn).GetOption("streamid"); err != nil || id != "#!::r=cam1" {
+		t.Errorf("got stream ID %q, %v; want %q", id, err, "#!::r=cam1")
+	}
+
+	if _, err := DialURI(ctx, "srt://:"+port); err == nil {
+		t.Error("got nil error dialing a listener URI")
+	}
+	if _, err := ListenURI(ctx, "srt://127.0.0.1:"+port); err == nil {
+		t.Error("got nil error listening on a caller URI")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srtapi
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+//lint:ignore U1000 we want to use it to calculate size
+var rsa syscall.RawSockaddrAny
+
+//lint:ignore U1000 we want to use it to calculate size
+var rs4 syscall.RawSockaddrInet4
+
+//lint:ignore U1000 we want to use it to calculate size
+var rs6 syscall.RawSockaddrInet6
+
+// Size of raw sock addr structures
+const (
+	SizeofSockaddrAny   = _Socklen(unsafe.Sizeof(rsa))
+	SizeofSockaddrInet4 = _Socklen(unsafe.Sizeof(rs4))
+	SizeofSockaddrInet6 = _Socklen(unsafe.Sizeof(rs6))
+)
+
+// SRT log level
+const (
+	LogEmerg   = 0
+	LogAlert   = 1
+	LogFatal   = 2
+	LogError   = 3
+	LogWarning = 4
+	LogNote    = 5
+	LogInfo    = 6
+	LogDebug   = 7
+)
+
+// SRT log FA
+const (
+	LogFAGeneral = 0
+	LogFABstats  = 1
+	LogFAControl = 2
+	LogFAData    = 3
+	LogFATsbpd   = 4
+	LogFARexmit  = 5
+)
+
+// SRT log flags
+const (
+	LogFlagDisableTime       = 1
+	LogFlagDisableThreadname = 2
+	LogFlagDisableSeverity   = 4
+	LogFlagDisableEOF        = 8
+)
+
+// MsgCtrl mirrors SRT C API SRT_MSGCTRL structure
+type MsgCtrl struct {
+	Flags    int   // reserved for future use, must be 0
+	MsgTTL   int   // TTL for a message in milliseconds, -1 means infinite
+	InOrder  bool  // only for message mode, deliver in order
+	Boundary int   // only for file mode, PB_SUBSEQUENT, PB_FIRST, PB_LAST, PB_SOLO
+	SrcTime  int64 // source time in microseconds since SRT internal clock epoch, 0 means now
+	PktSeq   int32 // sequence number of the first packet in received message
+	MsgNo    int32 // message number
+}
+
+// TraceBStats mirrors SRT C API SRT_TRACEBSTATS structure
+type TraceBStats struct {
+	MsTimeStamp             int64
+	PktSentTotal            int64
+	PktRecvTotal            int64
+	PktSndLossTotal         int
+	PktRcvLossTotal         int
+	PktRetransTotal         int
+	PktSentACKTotal         int
+	PktRecvACKTotal         int
+	PktSentNAKTotal         int
+	PktRecvNAKTotal         int
+	UsSndDurationTotal      int64
+	PktSndDropTotal         int
+	PktRcvDropTotal         int
+	PktRcvUndecryptTotal    int
+	ByteSentTotal           uint64
+	ByteRecvTotal           uint64
+	ByteRcvLossTotal        uint64
+	ByteRetransTotal        uint64
+	ByteSndDropTotal        uint64
+	ByteRcvDropTotal        uint64
+	ByteRcvUndecryptTotal   uint64
+	PktSent                 int64
+	PktRecv                 int64
+	PktSndLoss              int
+	PktRcvLoss              int
+	PktRetrans              int
+	PktRcvRetrans           int
+	PktSentACK              int
+	PktRecvACK              int
+	PktSentNAK              int
+	PktRecvNAK              int
+	MbpsSendRate            float64
+	MbpsRecvRate            float64
+	UsSndDuration           int64
+	PktReorderDistance      int
+	PktRcvAvgBelatedTime    float64
+	PktRcvBelated           int64
+	PktSndDrop              int
+	PktRcvDrop              int
+	PktRcvUndecrypt         int
+	ByteSent                uint64
+	ByteRecv                uint64
+	ByteRcvLoss             uint64
+	ByteRetrans             uint64
+	ByteSndDrop             uint64
+	ByteRcvDrop             uint64
+	ByteRcvUndecrypt        uint64
+	UsPktSndPeriod          float64
+	PktFlowWindow           int
+	PktCongestionWindow     int
+	PktFlightSize           int
+	MsRTT                   float64
+	MbpsBandwidth           float64
+	ByteAvailSndBuf         int
+	ByteAvailRcvBuf         int
+	MbpsMaxBW               float64
+	ByteMSS                 int
+	PktSndBuf               int
+	ByteSndBuf              int
+	MsSndBuf                int
+	MsSndTsbPdDelay         int
+	PktRcvBuf               int
+	ByteRcvBuf              int
+	MsRcvBuf                int
+	MsRcvTsbPdDelay         int
+	PktSndFilterExtraTotal  int
+	PktRcvFilterExtraTotal  int
+	PktRcvFilterSupplyTotal int
+	PktRcvFilterLossTotal   int
+	PktSndFilterExtra       int
+	PktRcvFilterExtra       int
+	PktRcvFilterSupply      int
+	PktRcvFilterLoss        int
+	PktReorderTolerance     int
+}
+
+// SRT const
+const (
+	InvalidSock          = -1
+	APIError             = -1
+	DefaultSendfileBlock = 364000
+	DefaultRecvfileBlock = 7280000
+)
+
+// groupMask is the bit which is set in the id of a socket group. It
+// mirrors SRTGROUP_MASK.
+const groupMask = 1 << 30
+
+// GroupMemberConfig represents SRT C API SRT_SOCKGROUPCONFIG structure
+type GroupMemberConfig struct {
+	Source syscall.Sockaddr // local address to bind, nil for any
+	Peer   syscall.Sockaddr // remote address to connect
+	Weight int
+	Token  int
+
+	// Filled in by ConnectGroup
+	ID  int
+	Err error
+}
+
+// GroupMemberData represents SRT C API SRT_SOCKGROUPDATA structure
+type GroupMemberData struct {
+	ID          int
+	Peer        syscall.Sockaddr
+	SockState   int
+	Weight      int
+	MemberState int
+	Result      int
+	Token       int
+}
+
+// IsGroup reports whether fd is the id of a socket group
+func IsGroup(fd int) bool {
+	return fd != InvalidSock && fd&groupMask != 0
+}
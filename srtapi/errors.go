@@ -1,6 +1,8 @@
 // Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
 // https://github.com/openfresh/gosrt
 
+// +build !srtmock
+
 package srtapi
 
 // #cgo LDFLAGS: -lsrt
@@ -0,0 +1,124 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build !srtmock
+
+package srtapi
+
+// #cgo LDFLAGS: -lsrt
+// #include <srt/srt.h>
+import "C"
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// SRT group types
+const (
+	GroupTypeUndefined = C.SRT_GTYPE_UNDEFINED
+	GroupTypeBroadcast = C.SRT_GTYPE_BROADCAST
+	GroupTypeBackup    = C.SRT_GTYPE_BACKUP
+	GroupTypeBalancing = C.SRT_GTYPE_BALANCING
+)
+
+// SRT group member status
+const (
+	MemberStatusPending = C.SRT_GST_PENDING
+	MemberStatusIdle    = C.SRT_GST_IDLE
+	MemberStatusRunning = C.SRT_GST_RUNNING
+	MemberStatusBroken  = C.SRT_GST_BROKEN
+)
+
+// CreateGroup call srt_create_group
+func CreateGroup(typ int) (fd int, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	fd = int(C.srt_create_group(C.SRT_GROUP_TYPE(typ)))
+	if fd == APIError {
+		err = getLastError()
+	}
+	return
+}
+
+// GroupOf call srt_groupof
+func GroupOf(fd int) (gfd int, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	gfd = int(C.srt_groupof(C.SRTSOCKET(fd)))
+	if gfd == APIError {
+		err = getLastError()
+	}
+	return
+}
+
+// ConnectGroup call srt_connect_group
+func ConnectGroup(group int, members []GroupMemberConfig) (fd int, err error) {
+	if len(members) == 0 {
+		return APIError, EINVPARAM
+	}
+	cfgs := make([]C.SRT_SOCKGROUPCONFIG, len(members))
+	for i := range members {
+		adr, n, err := sockaddr(members[i].Peer)
+		if err != nil {
+			return APIError, err
+		}
+		var src unsafe.Pointer
+		if members[i].Source != nil {
+			if src, _, err = sockaddr(members[i].Source); err != nil {
+				return APIError, err
+			}
+		}
+		cfgs[i] = C.srt_prepare_endpoint((*C.struct_sockaddr)(src), (*C.struct_sockaddr)(adr), C.int(n))
+		cfgs[i].weight = C.uint16_t(members[i].Weight)
+		cfgs[i].token = C.int(members[i].Token)
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	fd = int(C.srt_connect_group(C.SRTSOCKET(group), &cfgs[0], C.int(len(cfgs))))
+	if fd == APIError {
+		err = getLastError()
+	}
+	for i := range members {
+		members[i].ID = int(cfgs[i].id)
+		members[i].Token = int(cfgs[i].token)
+		if code := Errno(cfgs[i].errorcode); code != SUCCESS {
+			members[i].Err = code
+		}
+	}
+	return
+}
+
+// GroupData call srt_group_data
+func GroupData(group int) (members []GroupMemberData, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var size C.size_t
+	// Query the number of members first.
+	if stat := C.srt_group_data(C.SRTSOCKET(group), nil, &size); stat == APIError {
+		return nil, getLastError()
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	data := make([]C.SRT_SOCKGROUPDATA, size)
+	if stat := C.srt_group_data(C.SRTSOCKET(group), &data[0], &size); stat == APIError {
+		return nil, getLastError()
+	}
+	members = make([]GroupMemberData, 0, size)
+	for i := 0; i < int(size); i++ {
+		d := &data[i]
+		peer, _ := anyToSockaddr((*syscall.RawSockaddrAny)(unsafe.Pointer(&d.peeraddr)))
+		members = append(members, GroupMemberData{
+			ID:          int(d.id),
+			Peer:        peer,
+			SockState:   int(d.sockstate),
+			Weight:      int(d.weight),
+			MemberState: int(d.memberstate),
+			Result:      int(d.result),
+			Token:       int(d.token),
+		})
+	}
+	return members, nil
+}
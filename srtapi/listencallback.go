@@ -1,6 +1,8 @@
 // Copyright (c) 2020 CyberAgent, Inc. All rights reserved.
 // https://github.com/openfresh/gosrt
 
+// +build !srtmock
+
 package srtapi
 
 /*
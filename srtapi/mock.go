@@ -0,0 +1,1092 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build srtmock
+
+package srtapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MockNetwork configures the in-memory network of the srtmock
+// backend. Sockets are paired within the process; no packet leaves it.
+type MockNetwork struct {
+	// Delay is the one-way delay of handshake and data packets.
+	Delay time.Duration
+	// Loss is the probability from 0 to 1 that a message is lost. In
+	// live mode a lost message is dropped; in file mode it is
+	// retransmitted, which costs another round trip.
+	Loss float64
+	// Seed seeds the random source that decides about losses.
+	Seed int64
+	// Fail, if not nil, is called at the start of every API call with
+	// the name of the SRT C function, e.g. "srt_connect", and the
+	// socket or epoll id the call is made for. A non-nil result fails
+	// the call with that error.
+	Fail func(call string, id int) error
+	// Clock, if not nil, is the clock of the network, which then only
+	// moves when the test advances it. Nil means the real time.
+	Clock *MockClock
+}
+
+// SetMockNetwork sets the configuration of the in-memory network. A
+// nil n restores the default of a perfect network. The configuration
+// applies to handshakes and messages sent after the call.
+func SetMockNetwork(n *MockNetwork) {
+	if n == nil {
+		n = &MockNetwork{}
+	}
+	mock.Lock()
+	mock.net = *n
+	mock.rand = rand.New(rand.NewSource(n.Seed))
+	mockClock.Store(n.Clock)
+	mock.Unlock()
+}
+
+// mockVersion is the SRT version reported by the mock, 1.5.0.
+const mockVersion = 0x010500
+
+const (
+	mockDefaultBuf = 8192 * (1500 - 28)
+	mockFirstPort  = 32768
+	mockPktSize    = 1316
+)
+
+// mockMsg is a message in the receive queue of a socket.
+type mockMsg struct {
+	data    []byte
+	off     int // bytes already read in stream mode
+	due     time.Time
+	srcTime int64
+	pktSeq  int32
+	msgNo   int32
+}
+
+// mockCounters are the statistics of a socket that can be cleared.
+type mockCounters struct {
+	pktSent, pktRecv       int64
+	byteSent, byteRecv     uint64
+	pktSndLoss             int
+	pktRcvLoss             int
+	pktRetrans             int
+	pktRcvDrop             int
+	byteRcvLoss            uint64
+	byteRetrans            uint64
+	byteRcvDrop            uint64
+	pktSndDrop             int
+	byteSndDrop            uint64
+	pktSentACK, pktRecvACK int
+}
+
+type mockSocket struct {
+	id    int
+	state int
+	opts  map[int][]byte
+	laddr syscall.Sockaddr
+	raddr syscall.Sockaddr
+	bound bool
+
+	// listener
+	backlog  int
+	pending  []*mockSocket
+	callback SrtListenCallbackFunc
+
+	// connection
+	peer     *mockSocket
+	rcvq     []mockMsg
+	rcvBytes int
+	reject   int
+	accepted bool // created by a listener
+	shutdown bool // the peer closed the connection
+	kmState  int
+	connTime time.Time
+	nextSeq  int32
+	nextMsg  int32
+
+	epolls map[int]bool
+	total  mockCounters
+	intvl  mockCounters
+}
+
+type mockEpoll struct {
+	id    int
+	flags int
+	subs  map[int]int // socket id to subscribed events
+	ready map[int]int // socket id to pending edge triggered events
+}
+
+// mock is the state of the in-memory network. Blocking calls wait on
+// cond, which is broadcast whenever a socket changes.
+var mock = struct {
+	sync.Mutex
+	cond    *sync.Cond
+	net     MockNetwork
+	rand    *rand.Rand
+	socks   map[int]*mockSocket
+	epolls  map[int]*mockEpoll
+	ports   map[int]int // bound port to number of sockets
+	lastID  int
+	lastEid int
+	start   time.Time
+}{
+	socks:  map[int]*mockSocket{},
+	epolls: map[int]*mockEpoll{},
+	ports:  map[int]int{},
+	rand:   rand.New(rand.NewSource(0)),
+	start:  time.Now(),
+}
+
+func init() {
+	mock.cond = sync.NewCond(&mock.Mutex)
+}
+
+// mockFail runs the Fail hook of the network configuration.
+func mockFail(call string, id int) error {
+	mock.Lock()
+	fail := mock.net.Fail
+	mock.Unlock()
+	if fail == nil {
+		return nil
+	}
+	return fail(call, id)
+}
+
+// mockWait waits on mock.cond until the deadline, if any. It reports
+// whether the deadline has passed. mock must be locked.
+func mockWait(deadline time.Time) bool {
+	if deadline.IsZero() {
+		mock.cond.Wait()
+		return false
+	}
+	d := time.Until(deadline)
+	if d <= 0 {
+		return true
+	}
+	t := time.AfterFunc(d, mockBroadcast)
+	mock.cond.Wait()
+	t.Stop()
+	return !time.Now().Before(deadline)
+}
+
+func mockBroadcast() {
+	mock.Lock()
+	mock.cond.Broadcast()
+	mock.Unlock()
+}
+
+// mockDeadline converts a timeout option in milliseconds to a
+// deadline; -1 means none.
+func mockDeadline(ms int) time.Time {
+	if ms < 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(ms) * time.Millisecond)
+}
+
+// option kinds
+const (
+	mockInt = iota
+	mockInt64
+	mockBool
+	mockString
+	mockLinger
+)
+
+type mockOption struct {
+	kind     int
+	def      interface{}
+	pre      bool // can only be set before connecting
+	readOnly bool
+}
+
+var mockOptions = map[int]mockOption{
+	OptionMss:                 {kind: mockInt, def: 1500, pre: true},
+	OptionSndsyn:              {kind: mockBool, def: true},
+	OptionRcvsyn:              {kind: mockBool, def: true},
+	OptionIsn:                 {kind: mockInt, def: 0, readOnly: true},
+	OptionFc:                  {kind: mockInt, def: 25600, pre: true},
+	OptionSndbuf:              {kind: mockInt, def: mockDefaultBuf, pre: true},
+	OptionRcvbuf:              {kind: mockInt, def: mockDefaultBuf, pre: true},
+	OptionLinger:              {kind: mockLinger, def: 0},
+	OptionUDPSndbuf:           {kind: mockInt, def: 65536, pre: true},
+	OptionUDPRcvbuf:           {kind: mockInt, def: mockDefaultBuf, pre: true},
+	OptionRendezvous:          {kind: mockBool, def: false, pre: true},
+	OptionSndtimeo:            {kind: mockInt, def: -1},
+	OptionRcvtimeo:            {kind: mockInt, def: -1},
+	OptionReuseaddr:           {kind: mockBool, def: true, pre: true},
+	OptionMaxbw:               {kind: mockInt64, def: int64(-1)},
+	OptionState:               {kind: mockInt, readOnly: true},
+	OptionEvent:               {kind: mockInt, readOnly: true},
+	OptionSnddata:             {kind: mockInt, readOnly: true},
+	OptionRcvdata:             {kind: mockInt, readOnly: true},
+	OptionSender:              {kind: mockBool, def: false, pre: true},
+	OptionTsbpdmode:           {kind: mockBool, def: true, pre: true},
+	OptionLatency:             {kind: mockInt, def: 120, pre: true},
+	OptionInputbw:             {kind: mockInt64, def: int64(0)},
+	OptionOheadbw:             {kind: mockInt, def: 25},
+	OptionPassphrase:          {kind: mockString, def: "", pre: true},
+	OptionPbkeylen:            {kind: mockInt, def: 0, pre: true},
+	OptionKmstate:             {kind: mockInt, readOnly: true},
+	OptionIpttl:               {kind: mockInt, def: 64, pre: true},
+	OptionIptos:               {kind: mockInt, def: 0xB8, pre: true},
+	OptionTlpktdrop:           {kind: mockBool, def: true, pre: true},
+	OptionSnddropdelay:        {kind: mockInt, def: 0},
+	OptionNakreport:           {kind: mockBool, def: true, pre: true},
+	OptionVersion:             {kind: mockInt, readOnly: true},
+	OptionPeerversion:         {kind: mockInt, readOnly: true},
+	OptionConntimeo:           {kind: mockInt, def: 3000, pre: true},
+	OptionSndkmstate:          {kind: mockInt, readOnly: true},
+	OptionRcvkmstate:          {kind: mockInt, readOnly: true},
+	OptionLossmaxttl:          {kind: mockInt, def: 0},
+	OptionRcvlatency:          {kind: mockInt, def: 120, pre: true},
+	OptionPeerlatency:         {kind: mockInt, def: 0, pre: true},
+	OptionMinversion:          {kind: mockInt, def: 0x010000, pre: true},
+	OptionStreamid:            {kind: mockString, def: "", pre: true},
+	OptionCongestion:          {kind: mockString, def: "live", pre: true},
+	OptionMessageapi:          {kind: mockBool, def: true, pre: true},
+	OptionPayloadsize:         {kind: mockInt, def: mockPktSize, pre: true},
+	OptionTranstype:           {kind: mockInt, def: TypeLive, pre: true},
+	OptionKmrefreshrate:       {kind: mockInt, def: 0x1000000, pre: true},
+	OptionKmpreannounce:       {kind: mockInt, def: 0x1000, pre: true},
+	OptionEnforcedencryption:  {kind: mockBool, def: true, pre: true},
+	OptionIpv60only:           {kind: mockInt, def: -1, pre: true},
+	OptionPeeridletimeo:       {kind: mockInt, def: 5000, pre: true},
+	OptionPacketfilter:        {kind: mockString, def: "", pre: true},
+	OptionGroupconnect:        {kind: mockInt, def: 0, pre: true},
+	OptionGroupminstabletimeo: {kind: mockInt, def: 60, pre: true},
+	OptionGrouptype:           {kind: mockInt, readOnly: true},
+}
+
+func newMockSocket() *mockSocket {
+	mock.lastID++
+	s := &mockSocket{
+		id:     mock.lastID,
+		state:  StatusInit,
+		opts:   map[int][]byte{},
+		epolls: map[int]bool{},
+	}
+	for opt, o := range mockOptions {
+		if o.def != nil {
+			s.opts[opt] = mockEncode(o.kind, o.def)
+		}
+	}
+	return s
+}
+
+func mockEncode(kind int, v interface{}) []byte {
+	var b bytes.Buffer
+	switch kind {
+	case mockInt:
+		binary.Write(&b, binary.LittleEndian, int32(v.(int)))
+	case mockInt64:
+		binary.Write(&b, binary.LittleEndian, v.(int64))
+	case mockBool:
+		if v.(bool) {
+			b.WriteByte(1)
+		} else {
+			b.WriteByte(0)
+		}
+	case mockString:
+		b.WriteString(v.(string))
+	case mockLinger:
+		binary.Write(&b, binary.LittleEndian, [2]int32{0, int32(v.(int))})
+	}
+	return b.Bytes()
+}
+
+func (s *mockSocket) intOpt(opt int) int {
+	b := s.opts[opt]
+	switch len(b) {
+	case 1:
+		return int(b[0])
+	case 4:
+		return int(int32(binary.LittleEndian.Uint32(b)))
+	case 8:
+		return int(int64(binary.LittleEndian.Uint64(b)))
+	}
+	return 0
+}
+
+func (s *mockSocket) boolOpt(opt int) bool {
+	return s.intOpt(opt) != 0
+}
+
+func (s *mockSocket) stringOpt(opt int) string {
+	return string(s.opts[opt])
+}
+
+func (s *mockSocket) setIntOpt(opt, v int) {
+	s.opts[opt] = mockEncode(mockOptions[opt].kind, v)
+}
+
+// setOpt stores the raw value of an option as set by the application.
+func (s *mockSocket) setOpt(opt int, val []byte) error {
+	o, ok := mockOptions[opt]
+	if !ok || o.readOnly {
+		return EINVOP
+	}
+	if o.pre && s.state != StatusInit && s.state != StatusOpened && s.state != StatusListening && !s.isPendingAccept() {
+		return ECONNSOCK
+	}
+	switch o.kind {
+	case mockInt:
+		if len(val) != 4 {
+			return EINVPARAM
+		}
+	case mockInt64:
+		switch len(val) {
+		case 4:
+			val = mockEncode(mockInt64, int64(int32(binary.LittleEndian.Uint32(val))))
+		case 8:
+		default:
+			return EINVPARAM
+		}
+	case mockBool:
+		switch len(val) {
+		case 1, 4:
+			v := false
+			for _, c := range val {
+				v = v || c != 0
+			}
+			val = mockEncode(mockBool, v)
+		default:
+			return EINVPARAM
+		}
+	case mockString:
+		if opt == OptionPassphrase && len(val) != 0 && (len(val) < 10 || len(val) > 79) {
+			return EINVPARAM
+		}
+		if opt == OptionStreamid && len(val) > 512 {
+			return EINVPARAM
+		}
+		if opt == OptionCongestion && string(val) != "live" && string(val) != "file" {
+			return EINVPARAM
+		}
+	case mockLinger:
+		if len(val) != 8 {
+			return EINVPARAM
+		}
+	}
+	if opt == OptionPbkeylen {
+		switch v := int(int32(binary.LittleEndian.Uint32(val))); v {
+		case 0, 16, 24, 32:
+		default:
+			return EINVPARAM
+		}
+	}
+	s.opts[opt] = append([]byte(nil), val...)
+
+	switch opt {
+	case OptionLatency:
+		s.opts[OptionRcvlatency] = s.opts[opt]
+		s.opts[OptionPeerlatency] = s.opts[opt]
+	case OptionTranstype:
+		switch s.intOpt(opt) {
+		case TypeLive:
+			s.setTransDefaults(true)
+		case TypeFile:
+			s.setTransDefaults(false)
+		default:
+			return EINVPARAM
+		}
+	}
+	return nil
+}
+
+// setTransDefaults sets the defaults of the live or file transmission
+// type, as SRTO_TRANSTYPE does.
+func (s *mockSocket) setTransDefaults(live bool) {
+	latency, payload, cc := 0, 0, "file"
+	if live {
+		latency, payload, cc = 120, mockPktSize, "live"
+	}
+	s.opts[OptionMessageapi] = mockEncode(mockBool, live)
+	s.opts[OptionTsbpdmode] = mockEncode(mockBool, live)
+	s.opts[OptionTlpktdrop] = mockEncode(mockBool, live)
+	s.opts[OptionNakreport] = mockEncode(mockBool, live)
+	s.setIntOpt(OptionLatency, latency)
+	s.setIntOpt(OptionRcvlatency, latency)
+	s.setIntOpt(OptionPeerlatency, latency)
+	s.setIntOpt(OptionPayloadsize, payload)
+	s.opts[OptionCongestion] = []byte(cc)
+}
+
+// getOpt returns the raw value of an option.
+func (s *mockSocket) getOpt(opt int) ([]byte, error) {
+	o, ok := mockOptions[opt]
+	if !ok || opt == OptionTranstype {
+		return nil, EINVOP
+	}
+	switch opt {
+	case OptionState:
+		return mockEncode(o.kind, s.state), nil
+	case OptionEvent:
+		return mockEncode(o.kind, s.readiness()), nil
+	case OptionSnddata:
+		n := 0
+		if s.peer != nil {
+			n = s.peer.undue(mockNow())
+		}
+		return mockEncode(o.kind, n), nil
+	case OptionRcvdata:
+		return mockEncode(o.kind, len(s.rcvq)-s.undue(mockNow())), nil
+	case OptionKmstate, OptionSndkmstate, OptionRcvkmstate:
+		return mockEncode(o.kind, s.kmState), nil
+	case OptionVersion:
+		return mockEncode(o.kind, mockVersion), nil
+	case OptionPeerversion:
+		v := 0
+		if s.peer != nil || s.state == StatusBroken {
+			v = mockVersion
+		}
+		return mockEncode(o.kind, v), nil
+	case OptionGrouptype:
+		return mockEncode(o.kind, GroupTypeUndefined), nil
+	}
+	return s.opts[opt], nil
+}
+
+// isPendingAccept reports whether s is a socket created by a listener
+// whose handshake is still in progress.
+func (s *mockSocket) isPendingAccept() bool {
+	return s.state == StatusConnecting && s.accepted
+}
+
+// undue returns the number of messages in the receive queue that have
+// not arrived yet.
+func (s *mockSocket) undue(now time.Time) int {
+	n := 0
+	for i := len(s.rcvq) - 1; i >= 0 && s.rcvq[i].due.After(now); i-- {
+		n++
+	}
+	return n
+}
+
+// readable reports whether a message can be received.
+func (s *mockSocket) readable(now time.Time) bool {
+	return len(s.rcvq) > 0 && !s.rcvq[0].due.After(now)
+}
+
+// sendSpace returns the number of bytes the peer can take.
+func (s *mockSocket) sendSpace() int {
+	if s.peer == nil {
+		return 0
+	}
+	return s.peer.intOpt(OptionRcvbuf) - s.peer.rcvBytes
+}
+
+// readiness returns the epoll events that are on for s.
+func (s *mockSocket) readiness() int {
+	switch s.state {
+	case StatusListening:
+		if len(s.pending) > 0 {
+			return EpollIn
+		}
+	case StatusConnected:
+		ev := 0
+		if s.readable(mockNow()) {
+			ev |= EpollIn
+		}
+		if s.sendSpace() > 0 || s.boolOpt(OptionTsbpdmode) {
+			ev |= EpollOut
+		}
+		return ev
+	case StatusBroken, StatusClosed:
+		return EpollIn | EpollOut | EpollErr
+	}
+	return 0
+}
+
+// notify reports the events of s to the epolls it is subscribed to and
+// wakes up blocked calls. mock must be locked.
+func (s *mockSocket) notify() {
+	ev := s.readiness()
+	for eid := range s.epolls {
+		ep := mock.epolls[eid]
+		if ep == nil {
+			continue
+		}
+		if e := ev & ep.subs[s.id]; e != 0 {
+			ep.ready[s.id] |= e
+		}
+	}
+	mock.cond.Broadcast()
+}
+
+// notifyAt notifies s again at t, when delayed messages arrive.
+func (s *mockSocket) notifyAt(t time.Time) {
+	mockAt(t, func() {
+		mock.Lock()
+		if mock.socks[s.id] == s {
+			s.notify()
+		}
+		mock.Unlock()
+	})
+}
+
+// breakConn marks s as broken because its peer went away.
+func (s *mockSocket) breakConn() {
+	if s.state == StatusConnected || s.state == StatusConnecting {
+		s.state = StatusBroken
+		s.notify()
+	}
+}
+
+// close releases s. mock must be locked.
+func (s *mockSocket) close() {
+	delete(mock.socks, s.id)
+	for eid := range s.epolls {
+		if ep := mock.epolls[eid]; ep != nil {
+			delete(ep.subs, s.id)
+			delete(ep.ready, s.id)
+		}
+	}
+	if s.bound {
+		if p := mockPort(s.laddr); mock.ports[p] > 1 {
+			mock.ports[p]--
+		} else {
+			delete(mock.ports, p)
+		}
+	}
+	for _, ns := range s.pending {
+		if ns.peer != nil {
+			ns.peer.breakConn()
+		}
+		delete(mock.socks, ns.id)
+	}
+	s.pending = nil
+	if s.peer != nil {
+		s.peer.peer = nil
+		s.peer.shutdown = true
+		s.peer.breakConn()
+	}
+	s.state = StatusClosed
+	mock.cond.Broadcast()
+}
+
+func mockPort(sa syscall.Sockaddr) int {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return sa.Port
+	case *syscall.SockaddrInet6:
+		return sa.Port
+	}
+	return 0
+}
+
+func mockIP(sa syscall.Sockaddr) net.IP {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return net.IP(sa.Addr[:])
+	case *syscall.SockaddrInet6:
+		return net.IP(sa.Addr[:])
+	}
+	return nil
+}
+
+// mockAddr returns sa with the port replaced.
+func mockAddr(sa syscall.Sockaddr, port int) syscall.Sockaddr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		a := *sa
+		a.Port = port
+		return &a
+	case *syscall.SockaddrInet6:
+		a := *sa
+		a.Port = port
+		return &a
+	}
+	return nil
+}
+
+// bind binds s to sa, choosing a free port if the port of sa is 0.
+// mock must be locked.
+func (s *mockSocket) bind(sa syscall.Sockaddr) error {
+	port := mockPort(sa)
+	if port == 0 {
+		for port = mockFirstPort; mock.ports[port] > 0; port++ {
+		}
+	}
+	mock.ports[port]++
+	s.laddr = mockAddr(sa, port)
+	s.bound = true
+	s.state = StatusOpened
+	return nil
+}
+
+// lookupListener returns the listener bound to sa.
+func lookupListener(sa syscall.Sockaddr) *mockSocket {
+	port, ip := mockPort(sa), mockIP(sa)
+	for _, l := range mock.socks {
+		if l.state != StatusListening || mockPort(l.laddr) != port {
+			continue
+		}
+		if lip := mockIP(l.laddr); lip.IsUnspecified() || lip.Equal(ip) || ip.IsUnspecified() {
+			return l
+		}
+	}
+	return nil
+}
+
+// handshake connects the caller s to the listener at sa. It runs in
+// its own goroutine, so that the listen callback can call back into
+// the API.
+func (s *mockSocket) handshake(sa syscall.Sockaddr, delay time.Duration) {
+	start := mockNow()
+	mockSleepUntil(start.Add(delay))
+
+	mock.Lock()
+	if mock.socks[s.id] != s || s.state != StatusConnecting {
+		mock.Unlock()
+		return
+	}
+	var l *mockSocket
+	if s.boolOpt(OptionRendezvous) {
+		if p := lookupRendezvous(s, sa); p != nil {
+			if reason := negotiate(s, p); reason != RejectUnknown {
+				s.fail(reason)
+				p.fail(reason)
+			} else {
+				s.pair(p)
+			}
+			mock.Unlock()
+			return
+		}
+	} else {
+		l = lookupListener(sa)
+	}
+	if l == nil {
+		// Nobody answers; the caller gives up after the connection
+		// timeout. A rendezvous peer that connects in the meantime
+		// completes the handshake.
+		timeout := start.Add(time.Duration(s.intOpt(OptionConntimeo)) * time.Millisecond)
+		mock.Unlock()
+		mockSleepUntil(timeout)
+		mock.Lock()
+		s.fail(RejectTimeout)
+		mock.Unlock()
+		return
+	}
+	if len(l.pending) >= l.backlog {
+		mock.Unlock()
+		s.respond(start.Add(2*delay), nil, RejectBacklog)
+		return
+	}
+	ns := newMockSocket()
+	for opt, v := range l.opts {
+		ns.opts[opt] = v
+	}
+	ns.opts[OptionStreamid] = s.opts[OptionStreamid]
+	ns.state = StatusConnecting
+	ns.accepted = true
+	ns.bound = true
+	ns.laddr = mockAddr(sa, mockPort(l.laddr))
+	ns.raddr = s.laddr
+	mock.socks[ns.id] = ns
+	callback := l.callback
+	streamID := s.stringOpt(OptionStreamid)
+	mock.Unlock()
+
+	if callback != nil && callback(ns.id, 5, s.laddr, streamID) < 0 {
+		mock.Lock()
+		reason := ns.reject
+		if reason == RejectUnknown {
+			reason = RejectPredefined
+		}
+		delete(mock.socks, ns.id)
+		mock.Unlock()
+		s.respond(start.Add(2*delay), nil, reason)
+		return
+	}
+
+	mock.Lock()
+	reason := negotiate(s, ns)
+	if reason == RejectUnknown && (mock.socks[l.id] != l || l.state != StatusListening) {
+		reason = RejectClose
+	}
+	if reason != RejectUnknown {
+		delete(mock.socks, ns.id)
+		mock.Unlock()
+		s.respond(start.Add(2*delay), nil, reason)
+		return
+	}
+	ns.connTime = mockNow()
+	ns.state = StatusConnected
+	if delay == 0 {
+		// Connect the caller before the listener can hand out ns, so
+		// that it sees the connection before anything happens to it.
+		s.pair(ns)
+		l.pending = append(l.pending, ns)
+		l.notify()
+		mock.Unlock()
+		return
+	}
+	l.pending = append(l.pending, ns)
+	l.notify()
+	mock.Unlock()
+	s.respond(start.Add(2*delay), ns, RejectUnknown)
+}
+
+// lookupRendezvous returns the rendezvous socket at sa that connects to
+// s.
+func lookupRendezvous(s *mockSocket, sa syscall.Sockaddr) *mockSocket {
+	port, ip := mockPort(sa), mockIP(sa)
+	for _, p := range mock.socks {
+		if p == s || p.state != StatusConnecting || !p.boolOpt(OptionRendezvous) {
+			continue
+		}
+		if mockPort(p.laddr) != port || mockPort(p.raddr) != mockPort(s.laddr) {
+			continue
+		}
+		if lip := mockIP(p.laddr); lip.IsUnspecified() || lip.Equal(ip) {
+			return p
+		}
+	}
+	return nil
+}
+
+// respond completes the handshake of the caller s when the response
+// arrives at at: with the accepted socket ns, or with a rejection.
+func (s *mockSocket) respond(at time.Time, ns *mockSocket, reason int) {
+	mockSleepUntil(at)
+	mock.Lock()
+	defer mock.Unlock()
+	if ns == nil {
+		s.fail(reason)
+		return
+	}
+	if mock.socks[s.id] != s || s.state != StatusConnecting || mock.socks[ns.id] != ns {
+		if mock.socks[ns.id] == ns {
+			ns.breakConn()
+		}
+		if mock.socks[s.id] == s {
+			s.fail(RejectClose)
+		}
+		return
+	}
+	s.pair(ns)
+}
+
+// pair connects s and p with each other. mock must be locked.
+func (s *mockSocket) pair(p *mockSocket) {
+	s.peer, p.peer = p, s
+	p.raddr = s.laddr
+	now := mockNow()
+	for _, so := range []*mockSocket{s, p} {
+		if so.connTime.IsZero() {
+			so.connTime = now
+		}
+		so.state = StatusConnected
+		so.notify()
+	}
+}
+
+// fail rejects the connection of the caller s.
+func (s *mockSocket) fail(reason int) {
+	if mock.socks[s.id] != s || s.state != StatusConnecting {
+		return
+	}
+	s.reject = reason
+	s.state = StatusBroken
+	s.notify()
+}
+
+// negotiate checks the settings of the caller c against the ones of
+// the accepted socket a and agrees on the common parameters. It
+// returns the reject reason or RejectUnknown if they match.
+func negotiate(c, a *mockSocket) int {
+	if c.boolOpt(OptionMessageapi) != a.boolOpt(OptionMessageapi) {
+		return RejectMessageapi
+	}
+	if c.stringOpt(OptionCongestion) != a.stringOpt(OptionCongestion) {
+		return RejectCongestion
+	}
+	cp, ap := c.stringOpt(OptionPassphrase), a.stringOpt(OptionPassphrase)
+	switch {
+	case cp != "" && ap != "" && cp != ap:
+		return RejectBadsecret
+	case (cp == "") != (ap == ""):
+		if c.boolOpt(OptionEnforcedencryption) || a.boolOpt(OptionEnforcedencryption) {
+			return RejectUnsecure
+		}
+	case cp != "":
+		c.kmState, a.kmState = KmStateSecured, KmStateSecured
+	}
+
+	// Each side delivers with the larger of its receiver latency and
+	// the sender latency of the peer.
+	crcv := c.intOpt(OptionRcvlatency)
+	if p := a.intOpt(OptionPeerlatency); p > crcv {
+		crcv = p
+	}
+	arcv := a.intOpt(OptionRcvlatency)
+	if p := c.intOpt(OptionPeerlatency); p > arcv {
+		arcv = p
+	}
+	c.setIntOpt(OptionRcvlatency, crcv)
+	a.setIntOpt(OptionPeerlatency, crcv)
+	a.setIntOpt(OptionRcvlatency, arcv)
+	c.setIntOpt(OptionPeerlatency, arcv)
+	return RejectUnknown
+}
+
+// send queues p as one message at the peer of s. It returns the number
+// of bytes sent. mock must be locked.
+func (s *mockSocket) send(p []byte, mctrl *MsgCtrl) (int, error) {
+	switch s.state {
+	case StatusConnected:
+	case StatusBroken:
+		return 0, ECONNLOST
+	case StatusInit, StatusOpened, StatusListening, StatusConnecting:
+		return 0, ENOCONN
+	default:
+		return 0, EINVSOCK
+	}
+	live := s.boolOpt(OptionTsbpdmode)
+	message := s.boolOpt(OptionMessageapi)
+	if message {
+		if max := s.intOpt(OptionPayloadsize); live && max > 0 && len(p) > max {
+			return 0, ELARGEMSG
+		}
+		if len(p) > s.intOpt(OptionSndbuf) {
+			return 0, ELARGEMSG
+		}
+	}
+
+	n := len(p)
+	if space := s.sendSpace(); !live && space < n {
+		if message || space <= 0 {
+			return 0, EASYNCSND
+		}
+		n = space
+	}
+	pkts := (n + mockPktSize - 1) / mockPktSize
+	if pkts == 0 {
+		pkts = 1
+	}
+	now := mockNow()
+	due := now.Add(mock.net.Delay)
+	m := mockMsg{
+		data:    append([]byte(nil), p[:n]...),
+		due:     due,
+		srcTime: mockSince(now),
+		pktSeq:  s.nextSeq,
+		msgNo:   s.nextMsg + 1,
+	}
+	if mctrl != nil && mctrl.SrcTime != 0 {
+		m.srcTime = mctrl.SrcTime
+	}
+	s.nextSeq += int32(pkts)
+	s.nextMsg++
+	s.count(func(c *mockCounters) {
+		c.pktSent += int64(pkts)
+		c.byteSent += uint64(n)
+	})
+	if mctrl != nil {
+		mctrl.PktSeq = m.pktSeq
+		mctrl.MsgNo = m.msgNo
+		mctrl.SrcTime = m.srcTime
+	}
+
+	peer := s.peer
+	if mock.net.Loss > 0 && mock.rand.Float64() < mock.net.Loss {
+		if live {
+			s.count(func(c *mockCounters) { c.pktSndLoss += pkts })
+			peer.count(func(c *mockCounters) {
+				c.pktRcvLoss += pkts
+				c.byteRcvLoss += uint64(n)
+			})
+			return n, nil
+		}
+		m.due = m.due.Add(2 * mock.net.Delay)
+		s.count(func(c *mockCounters) {
+			c.pktRetrans += pkts
+			c.byteRetrans += uint64(n)
+		})
+	}
+	if live && peer.rcvBytes+n > peer.intOpt(OptionRcvbuf) {
+		// The receiver is too slow; too late messages are dropped.
+		peer.count(func(c *mockCounters) {
+			c.pktRcvDrop += pkts
+			c.byteRcvDrop += uint64(n)
+		})
+		return n, nil
+	}
+	peer.rcvq = append(peer.rcvq, m)
+	peer.rcvBytes += n
+	if m.due.After(now) {
+		peer.notifyAt(m.due)
+	} else {
+		peer.notify()
+	}
+	return n, nil
+}
+
+// recv receives from s into p. mock must be locked.
+func (s *mockSocket) recv(p []byte, mctrl *MsgCtrl) (int, error) {
+	switch s.state {
+	case StatusConnected, StatusBroken:
+	case StatusInit, StatusOpened, StatusListening, StatusConnecting:
+		return 0, ENOCONN
+	default:
+		return 0, EINVSOCK
+	}
+	now := mockNow()
+	if !s.readable(now) {
+		if s.state == StatusBroken {
+			// Like libsrt, a stream closed by the peer ends with 0
+			// bytes received.
+			if s.shutdown && !s.boolOpt(OptionMessageapi) {
+				return 0, nil
+			}
+			return 0, ECONNLOST
+		}
+		return 0, EASYNCRCV
+	}
+	var n int
+	m := &s.rcvq[0]
+	if s.boolOpt(OptionMessageapi) {
+		// The rest of a message that does not fit into p is lost.
+		n = copy(p, m.data)
+		s.rcvBytes -= len(m.data)
+		if mctrl != nil {
+			mctrl.SrcTime = m.srcTime
+			mctrl.PktSeq = m.pktSeq
+			mctrl.MsgNo = m.msgNo
+		}
+		s.rcvq = s.rcvq[1:]
+	} else {
+		for n < len(p) && s.readable(now) {
+			m = &s.rcvq[0]
+			c := copy(p[n:], m.data[m.off:])
+			n += c
+			m.off += c
+			s.rcvBytes -= c
+			if m.off == len(m.data) {
+				s.rcvq = s.rcvq[1:]
+			}
+		}
+	}
+	pkts := (n + mockPktSize - 1) / mockPktSize
+	s.count(func(c *mockCounters) {
+		c.pktRecv += int64(pkts)
+		c.byteRecv += uint64(n)
+	})
+	if s.peer != nil {
+		// Space was freed for the sender.
+		s.peer.notify()
+	}
+	return n, nil
+}
+
+// count updates the total and the interval statistics of s.
+func (s *mockSocket) count(f func(c *mockCounters)) {
+	f(&s.total)
+	f(&s.intvl)
+}
+
+// stats returns the statistics of s.
+func (s *mockSocket) stats(clear bool) *TraceBStats {
+	now := mockNow()
+	var elapsed int64
+	if !s.connTime.IsZero() {
+		elapsed = int64(now.Sub(s.connTime) / time.Millisecond)
+	}
+	t, i := s.total, s.intvl
+	rtt := float64(2*mock.net.Delay) / float64(time.Millisecond)
+	maxBW := 1000.0
+	if bw := s.intOpt(OptionMaxbw); bw > 0 {
+		maxBW = float64(bw) * 8 / 1e6
+	}
+	st := &TraceBStats{
+		MsTimeStamp:      elapsed,
+		PktSentTotal:     t.pktSent,
+		PktRecvTotal:     t.pktRecv,
+		PktSndLossTotal:  t.pktSndLoss,
+		PktRcvLossTotal:  t.pktRcvLoss,
+		PktRetransTotal:  t.pktRetrans,
+		PktSndDropTotal:  t.pktSndDrop,
+		PktRcvDropTotal:  t.pktRcvDrop,
+		ByteSentTotal:    t.byteSent,
+		ByteRecvTotal:    t.byteRecv,
+		ByteRcvLossTotal: t.byteRcvLoss,
+		ByteRetransTotal: t.byteRetrans,
+		ByteSndDropTotal: t.byteSndDrop,
+		ByteRcvDropTotal: t.byteRcvDrop,
+		PktSent:          i.pktSent,
+		PktRecv:          i.pktRecv,
+		PktSndLoss:       i.pktSndLoss,
+		PktRcvLoss:       i.pktRcvLoss,
+		PktRetrans:       i.pktRetrans,
+		PktSndDrop:       i.pktSndDrop,
+		PktRcvDrop:       i.pktRcvDrop,
+		ByteSent:         i.byteSent,
+		ByteRecv:         i.byteRecv,
+		ByteRcvLoss:      i.byteRcvLoss,
+		ByteRetrans:      i.byteRetrans,
+		ByteSndDrop:      i.byteSndDrop,
+		ByteRcvDrop:      i.byteRcvDrop,
+		PktFlowWindow:    s.intOpt(OptionFc),
+		MsRTT:            rtt,
+		MbpsBandwidth:    1000,
+		ByteAvailSndBuf:  s.intOpt(OptionSndbuf),
+		ByteAvailRcvBuf:  s.intOpt(OptionRcvbuf) - s.rcvBytes,
+		MbpsMaxBW:        maxBW,
+		ByteMSS:          s.intOpt(OptionMss),
+		PktRcvBuf:        len(s.rcvq),
+		ByteRcvBuf:       s.rcvBytes,
+		MsSndTsbPdDelay:  s.intOpt(OptionPeerlatency),
+		MsRcvTsbPdDelay:  s.intOpt(OptionRcvlatency),
+	}
+	if clear {
+		s.intvl = mockCounters{}
+	}
+	return st
+}
+
+// wait collects the events of ep into events. It returns the number of
+// ready sockets, which may exceed len(events). mock must be locked.
+func (ep *mockEpoll) wait(events []SrtEpollEvent) int {
+	n := 0
+	for id, mask := range ep.subs {
+		var ev int
+		if mask&EpollEt != 0 {
+			ev = ep.ready[id] & mask
+		} else if s := mock.socks[id]; s != nil {
+			ev = s.readiness() & mask
+		}
+		if ev == 0 {
+			continue
+		}
+		if n < len(events) {
+			events[n] = SrtEpollEvent{fd: SrtSocket(id), events: int32(ev)}
+			delete(ep.ready, id)
+		}
+		n++
+	}
+	return n
+}
+
+// subscribe sets the events ep waits for on s. Edge triggered events
+// that are already on are reported by the next wait.
+func (ep *mockEpoll) subscribe(s *mockSocket, events int) {
+	ep.subs[s.id] = events
+	s.epolls[ep.id] = true
+	ev := ep.ready[s.id] & events
+	if events&EpollEt != 0 {
+		ev |= s.readiness() & events
+	}
+	if ev != 0 {
+		ep.ready[s.id] = ev
+	} else {
+		delete(ep.ready, s.id)
+	}
+	mock.cond.Broadcast()
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build srtmock
+
+package srtapi
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MockClock is a clock for the in-memory network that only moves when
+// a test advances it. Set as the Clock of a MockNetwork, it times the
+// delays and losses, the connection timeout, the source times and the
+// statistics of the network, which then no longer depend on the speed
+// of the machine.
+//
+// The timeouts of blocking calls, e.g. SRTO_RCVTIMEO or the timeout of
+// srt_epoll_uwait, and the deadlines of connections keep the real
+// time, as they do not belong to the network.
+type MockClock struct {
+	mu     sync.Mutex
+	start  time.Time
+	now    time.Time
+	timers []mockTimer
+}
+
+// mockTimer is a function run by a MockClock at when.
+type mockTimer struct {
+	when time.Time
+	f    func()
+}
+
+// NewMockClock returns a MockClock that stands at now.
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{start: now, now: now}
+}
+
+// Now returns the time of c.
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves c forward by d. Everything that falls due on the way,
+// e.g. a message arriving or a handshake completing, happens in the
+// order of time. Goroutines of the network woken up by Advance may
+// still run after it returns; the time they see is the one they were
+// due at, so the outcome does not depend on when they run.
+func (c *MockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		i := c.next(end)
+		if i < 0 {
+			break
+		}
+		t := c.timers[i]
+		c.timers = append(c.timers[:i], c.timers[i+1:]...)
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	if end.After(c.now) {
+		c.now = end
+	}
+	c.mu.Unlock()
+}
+
+// next returns the index of the earliest timer due at end, or -1.
+func (c *MockClock) next(end time.Time) int {
+	n := -1
+	for i, t := range c.timers {
+		if !t.when.After(end) && (n < 0 || t.when.Before(c.timers[n].when)) {
+			n = i
+		}
+	}
+	return n
+}
+
+// at runs f when c reaches t, at once if it has already.
+func (c *MockClock) at(t time.Time, f func()) {
+	c.mu.Lock()
+	if !t.After(c.now) {
+		c.mu.Unlock()
+		f()
+		return
+	}
+	c.timers = append(c.timers, mockTimer{when: t, f: f})
+	c.mu.Unlock()
+}
+
+// mockClock holds the *MockClock of the network, nil for the real time.
+var mockClock atomic.Value
+
+func init() {
+	mockClock.Store((*MockClock)(nil))
+}
+
+// mockNow returns the time of the network.
+func mockNow() time.Time {
+	if c := mockClock.Load().(*MockClock); c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// mockSince returns the time of the network in microseconds since it
+// started, the clock of the source times and srt_time_now.
+func mockSince(now time.Time) int64 {
+	start := mock.start
+	if c := mockClock.Load().(*MockClock); c != nil {
+		start = c.start
+	}
+	return int64(now.Sub(start) / time.Microsecond)
+}
+
+// mockAt runs f in its own goroutine or timer when the network reaches
+// t.
+func mockAt(t time.Time, f func()) {
+	if c := mockClock.Load().(*MockClock); c != nil {
+		c.at(t, func() { go f() })
+		return
+	}
+	time.AfterFunc(time.Until(t), f)
+}
+
+// mockSleepUntil blocks until the network reaches t.
+func mockSleepUntil(t time.Time) {
+	if c := mockClock.Load().(*MockClock); c != nil {
+		done := make(chan struct{})
+		c.at(t, func() { close(done) })
+		<-done
+		return
+	}
+	time.Sleep(time.Until(t))
+}
@@ -1,6 +1,8 @@
 // Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
 // https://github.com/openfresh/gosrt
 
+// +build !srtmock
+
 package srtapi
 
 /*
@@ -17,6 +19,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"sync"
 	"syscall"
 	"unsafe"
 )
@@ -24,7 +27,10 @@ import (
 // SrtListenCallbackFunc listen callback function type
 type SrtListenCallbackFunc func(ns int, hsversion int, peeraddr syscall.Sockaddr, streamid string) int
 
-var listenCallbackMap map[string]SrtListenCallbackFunc
+var (
+	listenCallbackLock sync.RWMutex // protects listenCallbackMap
+	listenCallbackMap  map[string]SrtListenCallbackFunc
+)
 
 // Startup call srt_startup
 func Startup() (err error) {
@@ -34,7 +40,9 @@ func Startup() (err error) {
 	if stat == APIError {
 		err = getLastError()
 	}
+	listenCallbackLock.Lock()
 	listenCallbackMap = map[string]SrtListenCallbackFunc{}
+	listenCallbackLock.Unlock()
 	return
 }
 
@@ -46,7 +54,9 @@ func Cleanup() (err error) {
 	if stat == APIError {
 		err = getLastError()
 	}
+	listenCallbackLock.Lock()
 	listenCallbackMap = nil
+	listenCallbackLock.Unlock()
 	return
 }
 
@@ -94,8 +104,9 @@ func EpollUpdateUsock(epfd int, fd int, events int) (err error) {
 	return
 }
 
-// EpollWait call srt_epoll_wait
-func EpollWait(epfd int, rfds *SrtSocket, rfdslen *int, wfds *SrtSocket, wfdslen *int, timeout int64) (n int) {
+// EpollWait call srt_epoll_wait. A timeout is not an error; it
+// returns 0 then.
+func EpollWait(epfd int, rfds *SrtSocket, rfdslen *int, wfds *SrtSocket, wfdslen *int, timeout int64) (n int, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
@@ -103,37 +114,42 @@ func EpollWait(epfd int, rfds *SrtSocket, rfdslen *int, wfds *SrtSocket, wfdslen
 	wnum := C.int(*wfdslen)
 	n = int(C.srt_epoll_wait(C.int(epfd), (*C.SRTSOCKET)(unsafe.Pointer(rfds)), &rnum, (*C.SRTSOCKET)(unsafe.Pointer(wfds)), &wnum, C.int64_t(timeout), nil, nil, nil, nil))
 	if n < 0 {
-		err := getLastError()
-		switch err {
-		case ETIMEOUT:
-		default:
-			println("runtime: srt_epoll_wait on fd", epfd, "failed with", err.Error())
-			panic("runtime: netpoll failed")
+		n = 0
+		if err = getLastError(); err == ETIMEOUT {
+			err = nil
 		}
 		ClearLastError()
-		n = 0
 	}
 	*rfdslen = int(rnum)
 	*wfdslen = int(wnum)
 	return
 }
 
-// EpollUwait call srt_epoll_uwait
-func EpollUwait(epfd int, fdsSet *SrtEpollEvent, fdsSize int, msTimeOut int64) (n int) {
+// EpollUwait call srt_epoll_uwait. It returns the number of ready
+// sockets, which may be greater than fdsSize if not all of them fit
+// into fdsSet. A timeout is not an error; it returns 0 then.
+func EpollUwait(epfd int, fdsSet *SrtEpollEvent, fdsSize int, msTimeOut int64) (n int, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
 	n = int(C.srt_epoll_uwait(C.int(epfd), (*C.SRT_EPOLL_EVENT)(fdsSet), C.int(fdsSize), C.int64_t(msTimeOut)))
 	if n < 0 {
-		err := getLastError()
-		switch err {
-		case ETIMEOUT:
-		default:
-			println("runtime: srt_epoll_uwait on fd", epfd, "failed with", err.Error())
-			panic("runtime: netpoll failed")
+		n = 0
+		if err = getLastError(); err == ETIMEOUT {
+			err = nil
 		}
 		ClearLastError()
-		n = 0
+	}
+	return
+}
+
+// EpollRelease call srt_epoll_release
+func EpollRelease(epfd int) (err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	stat := C.srt_epoll_release(C.int(epfd))
+	if stat == APIError {
+		err = getLastError()
 	}
 	return
 }
@@ -273,7 +289,9 @@ func Listen(s int, n int) (err error) {
 //export srtListenCallback
 func srtListenCallback(opaq unsafe.Pointer, ns C.SRTSOCKET, hsversion int, peeraddr *C.struct_sockaddr, streamid *C.char) int {
 	key := C.GoString((*C.char)(*(*unsafe.Pointer)(opaq)))
+	listenCallbackLock.RLock()
 	callback, ok := listenCallbackMap[key]
+	listenCallbackLock.RUnlock()
 	if !ok {
 		println("srtListenCallback: not found callback with key ", key)
 		return -1
@@ -291,7 +309,12 @@ func ListenCallback(s int, callback SrtListenCallbackFunc) (err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 	key := strconv.Itoa(s)
+	listenCallbackLock.Lock()
+	if listenCallbackMap == nil {
+		listenCallbackMap = map[string]SrtListenCallbackFunc{}
+	}
 	listenCallbackMap[key] = callback
+	listenCallbackLock.Unlock()
 	cKey := C.CString(key)
 	stat := C.srt_listen_callback(C.SRTSOCKET(s), (*C.srt_listen_callback_fn)(C.SrtListenCallback_cgo), unsafe.Pointer(&cKey))
 	if stat == APIError {
@@ -300,12 +323,35 @@ func ListenCallback(s int, callback SrtListenCallbackFunc) (err error) {
 	return
 }
 
+// SetRejectReason call srt_setrejectreason
+func SetRejectReason(fd int, reason int) (err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	stat := C.srt_setrejectreason(C.SRTSOCKET(fd), C.int(reason))
+	if stat == APIError {
+		err = getLastError()
+	}
+	return
+}
+
+// GetRejectReason call srt_getrejectreason
+func GetRejectReason(fd int) int {
+	return int(C.srt_getrejectreason(C.SRTSOCKET(fd)))
+}
+
+// RejectReasonString call srt_rejectreason_str
+func RejectReasonString(reason int) string {
+	return C.GoString(C.srt_rejectreason_str(C.int(reason)))
+}
+
 // Close call srt_close
 func Close(fd int) (err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 	key := strconv.Itoa(fd)
+	listenCallbackLock.Lock()
 	delete(listenCallbackMap, key)
+	listenCallbackLock.Unlock()
 	stat := C.srt_close(C.SRTSOCKET(fd))
 	if stat == APIError {
 		err = getLastError()
@@ -330,23 +376,134 @@ func read(fd int, p []byte) (n int, err error) {
 	return
 }
 
-func sendfile(outfd int, r io.Reader, offset *int64, count int) (written int, err error) {
+func msgctrlIn(mc *C.SRT_MSGCTRL, mctrl *MsgCtrl) {
+	C.srt_msgctrl_init(mc)
+	if mctrl == nil {
+		return
+	}
+	mc.flags = C.int(mctrl.Flags)
+	mc.msgttl = C.int(mctrl.MsgTTL)
+	mc.inorder = 0
+	if mctrl.InOrder {
+		mc.inorder = 1
+	}
+	mc.boundary = C.int(mctrl.Boundary)
+	mc.srctime = C.int64_t(mctrl.SrcTime)
+	mc.pktseq = C.int32_t(mctrl.PktSeq)
+	mc.msgno = C.int32_t(mctrl.MsgNo)
+}
+
+func msgctrlOut(mctrl *MsgCtrl, mc *C.SRT_MSGCTRL) {
+	if mctrl == nil {
+		return
+	}
+	mctrl.Flags = int(mc.flags)
+	mctrl.MsgTTL = int(mc.msgttl)
+	mctrl.InOrder = mc.inorder != 0
+	mctrl.Boundary = int(mc.boundary)
+	mctrl.SrcTime = int64(mc.srctime)
+	mctrl.PktSeq = int32(mc.pktseq)
+	mctrl.MsgNo = int32(mc.msgno)
+}
+
+func recvmsg2(fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var _p0 unsafe.Pointer
+	if len(p) > 0 {
+		_p0 = unsafe.Pointer(&p[0])
+	} else {
+		_p0 = unsafe.Pointer(&_zero)
+	}
+	var mc C.SRT_MSGCTRL
+	msgctrlIn(&mc, nil)
+	r0 := C.srt_recvmsg2(C.SRTSOCKET(fd), (*C.char)(_p0), C.int(len(p)), &mc)
+	n = int(r0)
+	if r0 == APIError {
+		err = getLastError()
+		return
+	}
+	msgctrlOut(mctrl, &mc)
+	return
+}
+
+func sendmsg2(fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var _p0 unsafe.Pointer
+	if len(p) > 0 {
+		_p0 = unsafe.Pointer(&p[0])
+	} else {
+		_p0 = unsafe.Pointer(&_zero)
+	}
+	var mc C.SRT_MSGCTRL
+	msgctrlIn(&mc, mctrl)
+	r0 := C.srt_sendmsg2(C.SRTSOCKET(fd), (*C.char)(_p0), C.int(len(p)), &mc)
+	n = int(r0)
+	if r0 == APIError {
+		err = getLastError()
+		return
+	}
+	msgctrlOut(mctrl, &mc)
+	return
+}
+
+// TimeNow call srt_time_now
+func TimeNow() int64 {
+	return int64(C.srt_time_now())
+}
+
+func sendfile(outfd int, r io.Reader, offset *int64, count int, block int) (written int, err error) {
 	f, ok := r.(*os.File)
 	if !ok {
-		return 0, nil
+		return APIError, EINVPARAM
+	}
+	if block <= 0 {
+		block = DefaultSendfileBlock
 	}
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 	name := C.CString(f.Name())
 	defer C.free(unsafe.Pointer(name))
-	r0 := C.srt_sendfile(C.SRTSOCKET(outfd), name, (*C.int64_t)(offset), C.int64_t(count), DefaultSendfileBlock)
+	var off int64
+	if offset != nil {
+		off = *offset
+	}
+	r0 := C.srt_sendfile(C.SRTSOCKET(outfd), name, (*C.int64_t)(&off), C.int64_t(count), C.int(block))
 	if r0 == APIError {
 		err = getLastError()
 	}
+	if offset != nil {
+		*offset = off
+	}
 	written = int(r0)
 	return
 }
 
+func recvfile(infd int, w io.Writer, offset *int64, count int) (received int, err error) {
+	f, ok := w.(*os.File)
+	if !ok {
+		return APIError, EINVPARAM
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	name := C.CString(f.Name())
+	defer C.free(unsafe.Pointer(name))
+	var off int64
+	if offset != nil {
+		off = *offset
+	}
+	r0 := C.srt_recvfile(C.SRTSOCKET(infd), name, (*C.int64_t)(&off), C.int64_t(count), DefaultRecvfileBlock)
+	if r0 == APIError {
+		err = getLastError()
+	}
+	if offset != nil {
+		*offset = off
+	}
+	received = int(r0)
+	return
+}
+
 func write(fd int, p []byte) (n int, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
@@ -387,56 +544,117 @@ func AddLogFA(fa int) {
 	C.srt_addlogfa(C.int(fa))
 }
 
+// DelLogFA call srt_dellogfa
+func DelLogFA(fa int) {
+	C.srt_dellogfa(C.int(fa))
+}
+
+// ResetLogFA call srt_resetlogfa, which enables exactly the functional
+// areas fas.
+func ResetLogFA(fas []int) {
+	cfas := make([]C.int, len(fas)+1) // never empty, for &cfas[0]
+	for i, fa := range fas {
+		cfas[i] = C.int(fa)
+	}
+	C.srt_resetlogfa(&cfas[0], C.size_t(len(fas)))
+}
+
 // SetLogFlags call srt_setlogflags
 func SetLogFlags(flags int) {
 	C.srt_setlogflags(C.int(flags))
 }
 
-func GetStats(fd int, clear bool) map[string]interface{} {
-	var mon C.struct_CBytePerfMon
-	clearStats := 0
+// Bistats call srt_bistats
+func Bistats(fd int, clear bool, instantaneous bool) (stats *TraceBStats, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var mon C.SRT_TRACEBSTATS
+	var clearStats, instStats C.int
 	if clear {
 		clearStats = 1
 	}
-	C.srt_bstats(C.SRTSOCKET(fd), &mon, C.int(clearStats))
-	output := map[string]interface{}{
-		"sid":  fd,
-		"time": mon.msTimeStamp,
-		"window": map[string]interface{}{
-			"flow":       mon.pktFlowWindow,
-			"congestion": mon.pktCongestionWindow,
-			"flight":     mon.pktFlightSize,
-		},
-		"link": map[string]interface{}{
-			"rtt":          mon.msRTT,
-			"bandwidth":    mon.mbpsBandwidth,
-			"maxBandwidth": mon.mbpsMaxBW,
-		},
-		"send": map[string]interface{}{
-			"packets":              mon.pktSent,
-			"packetsLost":          mon.pktSndLoss,
-			"packetsDropped":       mon.pktSndDrop,
-			"packetsRetransmitted": mon.pktRetrans,
-			"packetsFilterExtra":   mon.pktSndFilterExtra,
-			"bytes":                mon.byteSent,
-			"bytesDropped":         mon.byteSndDrop,
-			"mbitRate":             mon.mbpsSendRate,
-		},
-		"recv": map[string]interface{}{
-			"packets":              mon.pktRecv,
-			"packetsLost":          mon.pktRcvLoss,
-			"packetsDropped":       mon.pktRcvDrop,
-			"packetsRetransmitted": mon.pktRcvRetrans,
-			"packetsBelated":       mon.pktRcvBelated,
-			"packetsFilterExtra":   mon.pktRcvFilterExtra,
-			"packetsFilterSupply":  mon.pktRcvFilterSupply,
-			"packetsFilterLoss":    mon.pktRcvFilterLoss,
-			"bytes":                mon.byteRecv,
-			"bytesLost":            mon.byteRcvLoss,
-			"bytesDropped":         mon.byteRcvDrop,
-			"mbitRate":             mon.mbpsRecvRate,
-		},
-	}
-
-	return output
+	if instantaneous {
+		instStats = 1
+	}
+	stat := C.srt_bistats(C.SRTSOCKET(fd), &mon, clearStats, instStats)
+	if stat == APIError {
+		return nil, getLastError()
+	}
+	stats = &TraceBStats{
+		MsTimeStamp:             int64(mon.msTimeStamp),
+		PktSentTotal:            int64(mon.pktSentTotal),
+		PktRecvTotal:            int64(mon.pktRecvTotal),
+		PktSndLossTotal:         int(mon.pktSndLossTotal),
+		PktRcvLossTotal:         int(mon.pktRcvLossTotal),
+		PktRetransTotal:         int(mon.pktRetransTotal),
+		PktSentACKTotal:         int(mon.pktSentACKTotal),
+		PktRecvACKTotal:         int(mon.pktRecvACKTotal),
+		PktSentNAKTotal:         int(mon.pktSentNAKTotal),
+		PktRecvNAKTotal:         int(mon.pktRecvNAKTotal),
+		UsSndDurationTotal:      int64(mon.usSndDurationTotal),
+		PktSndDropTotal:         int(mon.pktSndDropTotal),
+		PktRcvDropTotal:         int(mon.pktRcvDropTotal),
+		PktRcvUndecryptTotal:    int(mon.pktRcvUndecryptTotal),
+		ByteSentTotal:           uint64(mon.byteSentTotal),
+		ByteRecvTotal:           uint64(mon.byteRecvTotal),
+		ByteRcvLossTotal:        uint64(mon.byteRcvLossTotal),
+		ByteRetransTotal:        uint64(mon.byteRetransTotal),
+		ByteSndDropTotal:        uint64(mon.byteSndDropTotal),
+		ByteRcvDropTotal:        uint64(mon.byteRcvDropTotal),
+		ByteRcvUndecryptTotal:   uint64(mon.byteRcvUndecryptTotal),
+		PktSent:                 int64(mon.pktSent),
+		PktRecv:                 int64(mon.pktRecv),
+		PktSndLoss:              int(mon.pktSndLoss),
+		PktRcvLoss:              int(mon.pktRcvLoss),
+		PktRetrans:              int(mon.pktRetrans),
+		PktRcvRetrans:           int(mon.pktRcvRetrans),
+		PktSentACK:              int(mon.pktSentACK),
+		PktRecvACK:              int(mon.pktRecvACK),
+		PktSentNAK:              int(mon.pktSentNAK),
+		PktRecvNAK:              int(mon.pktRecvNAK),
+		MbpsSendRate:            float64(mon.mbpsSendRate),
+		MbpsRecvRate:            float64(mon.mbpsRecvRate),
+		UsSndDuration:           int64(mon.usSndDuration),
+		PktReorderDistance:      int(mon.pktReorderDistance),
+		PktRcvAvgBelatedTime:    float64(mon.pktRcvAvgBelatedTime),
+		PktRcvBelated:           int64(mon.pktRcvBelated),
+		PktSndDrop:              int(mon.pktSndDrop),
+		PktRcvDrop:              int(mon.pktRcvDrop),
+		PktRcvUndecrypt:         int(mon.pktRcvUndecrypt),
+		ByteSent:                uint64(mon.byteSent),
+		ByteRecv:                uint64(mon.byteRecv),
+		ByteRcvLoss:             uint64(mon.byteRcvLoss),
+		ByteRetrans:             uint64(mon.byteRetrans),
+		ByteSndDrop:             uint64(mon.byteSndDrop),
+		ByteRcvDrop:             uint64(mon.byteRcvDrop),
+		ByteRcvUndecrypt:        uint64(mon.byteRcvUndecrypt),
+		UsPktSndPeriod:          float64(mon.usPktSndPeriod),
+		PktFlowWindow:           int(mon.pktFlowWindow),
+		PktCongestionWindow:     int(mon.pktCongestionWindow),
+		PktFlightSize:           int(mon.pktFlightSize),
+		MsRTT:                   float64(mon.msRTT),
+		MbpsBandwidth:           float64(mon.mbpsBandwidth),
+		ByteAvailSndBuf:         int(mon.byteAvailSndBuf),
+		ByteAvailRcvBuf:         int(mon.byteAvailRcvBuf),
+		MbpsMaxBW:               float64(mon.mbpsMaxBW),
+		ByteMSS:                 int(mon.byteMSS),
+		PktSndBuf:               int(mon.pktSndBuf),
+		ByteSndBuf:              int(mon.byteSndBuf),
+		MsSndBuf:                int(mon.msSndBuf),
+		MsSndTsbPdDelay:         int(mon.msSndTsbPdDelay),
+		PktRcvBuf:               int(mon.pktRcvBuf),
+		ByteRcvBuf:              int(mon.byteRcvBuf),
+		MsRcvBuf:                int(mon.msRcvBuf),
+		MsRcvTsbPdDelay:         int(mon.msRcvTsbPdDelay),
+		PktSndFilterExtraTotal:  int(mon.pktSndFilterExtraTotal),
+		PktRcvFilterExtraTotal:  int(mon.pktRcvFilterExtraTotal),
+		PktRcvFilterSupplyTotal: int(mon.pktRcvFilterSupplyTotal),
+		PktRcvFilterLossTotal:   int(mon.pktRcvFilterLossTotal),
+		PktSndFilterExtra:       int(mon.pktSndFilterExtra),
+		PktRcvFilterExtra:       int(mon.pktRcvFilterExtra),
+		PktRcvFilterSupply:      int(mon.pktRcvFilterSupply),
+		PktRcvFilterLoss:        int(mon.pktRcvFilterLoss),
+		PktReorderTolerance:     int(mon.pktReorderTolerance),
+	}
+	return stats, nil
 }
@@ -0,0 +1,880 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build srtmock
+
+package srtapi
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// SrtListenCallbackFunc listen callback function type
+type SrtListenCallbackFunc func(ns int, hsversion int, peeraddr syscall.Sockaddr, streamid string) int
+
+var mockLastErr int32
+
+// mockErr records err as the last error and returns it.
+func mockErr(err error) error {
+	if e, ok := err.(Errno); ok {
+		atomic.StoreInt32(&mockLastErr, int32(e))
+	}
+	return err
+}
+
+// mockSocketOf runs the Fail hook for call and locks mock. It returns
+// the socket fd, or an error if the hook fails or fd does not exist;
+// mock is unlocked then.
+func mockSocketOf(call string, fd int) (*mockSocket, error) {
+	if err := mockFail(call, fd); err != nil {
+		return nil, err
+	}
+	mock.Lock()
+	s := mock.socks[fd]
+	if s == nil {
+		mock.Unlock()
+		return nil, mockErr(EINVSOCK)
+	}
+	return s, nil
+}
+
+// mockEpollOf is like mockSocketOf for epolls.
+func mockEpollOf(call string, epfd int) (*mockEpoll, error) {
+	if err := mockFail(call, epfd); err != nil {
+		return nil, err
+	}
+	mock.Lock()
+	ep := mock.epolls[epfd]
+	if ep == nil {
+		mock.Unlock()
+		return nil, mockErr(EINVPOLLID)
+	}
+	return ep, nil
+}
+
+// Startup call srt_startup
+func Startup() (err error) {
+	return mockFail("srt_startup", 0)
+}
+
+// Cleanup call srt_cleanup
+func Cleanup() (err error) {
+	if err = mockFail("srt_cleanup", 0); err != nil {
+		return err
+	}
+	mock.Lock()
+	defer mock.Unlock()
+	for _, s := range mock.socks {
+		s.close()
+	}
+	mock.epolls = map[int]*mockEpoll{}
+	mock.cond.Broadcast()
+	return nil
+}
+
+// EpollCreate call srt_epoll_create
+func EpollCreate() (epfd int, err error) {
+	if err = mockFail("srt_epoll_create", 0); err != nil {
+		return APIError, err
+	}
+	mock.Lock()
+	defer mock.Unlock()
+	mock.lastEid++
+	ep := &mockEpoll{id: mock.lastEid, subs: map[int]int{}, ready: map[int]int{}}
+	mock.epolls[ep.id] = ep
+	return ep.id, nil
+}
+
+// EpollAddUsock call srt_epoll_add_usock
+func EpollAddUsock(epfd int, fd int, events int) (err error) {
+	return epollSubscribe("srt_epoll_add_usock", epfd, fd, events)
+}
+
+// EpollUpdateUsock call srt_epoll_update_usock
+func EpollUpdateUsock(epfd int, fd int, events int) (err error) {
+	return epollSubscribe("srt_epoll_update_usock", epfd, fd, events)
+}
+
+func epollSubscribe(call string, epfd int, fd int, events int) error {
+	ep, err := mockEpollOf(call, epfd)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	s := mock.socks[fd]
+	if s == nil {
+		return mockErr(EINVSOCK)
+	}
+	ep.subscribe(s, events)
+	return nil
+}
+
+// EpollRemoveUsock call srt_epoll_remove_usock
+func EpollRemoveUsock(epfd int, fd int) (err error) {
+	ep, err := mockEpollOf("srt_epoll_remove_usock", epfd)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	delete(ep.subs, fd)
+	delete(ep.ready, fd)
+	if s := mock.socks[fd]; s != nil {
+		delete(s.epolls, epfd)
+	}
+	return nil
+}
+
+// EpollWait call srt_epoll_wait. A timeout is not an error; it
+// returns 0 then.
+func EpollWait(epfd int, rfds *SrtSocket, rfdslen *int, wfds *SrtSocket, wfdslen *int, timeout int64) (n int, err error) {
+	if err = mockFail("srt_epoll_wait", epfd); err != nil {
+		return 0, err
+	}
+	rs := mockSockets(rfds, *rfdslen)
+	ws := mockSockets(wfds, *wfdslen)
+	mock.Lock()
+	defer mock.Unlock()
+	deadline := mockDeadline(int(timeout))
+	for {
+		ep := mock.epolls[epfd]
+		if ep == nil {
+			return 0, mockErr(EINVPOLLID)
+		}
+		if len(ep.subs) == 0 && ep.flags&EpollEnableEmpty == 0 {
+			return 0, mockErr(epollEmpty)
+		}
+		events := make([]SrtEpollEvent, len(ep.subs))
+		events = events[:ep.wait(events)]
+		var rn, wn int
+		for _, e := range events {
+			if e.events&(EpollIn|EpollErr) != 0 && rn < len(rs) {
+				rs[rn] = e.fd
+				rn++
+			}
+			if e.events&(EpollOut|EpollErr) != 0 && wn < len(ws) {
+				ws[wn] = e.fd
+				wn++
+			}
+		}
+		if rn+wn > 0 {
+			*rfdslen, *wfdslen = rn, wn
+			return rn + wn, nil
+		}
+		if mockWait(deadline) {
+			*rfdslen, *wfdslen = 0, 0
+			return 0, nil
+		}
+	}
+}
+
+func mockSockets(p *SrtSocket, n int) []SrtSocket {
+	if p == nil || n <= 0 {
+		return nil
+	}
+	return (*[1 << 26]SrtSocket)(unsafe.Pointer(p))[:n:n]
+}
+
+// EpollUwait call srt_epoll_uwait. It returns the number of ready
+// sockets, which may be greater than fdsSize if not all of them fit
+// into fdsSet. A timeout is not an error; it returns 0 then.
+func EpollUwait(epfd int, fdsSet *SrtEpollEvent, fdsSize int, msTimeOut int64) (n int, err error) {
+	if err = mockFail("srt_epoll_uwait", epfd); err != nil {
+		return 0, err
+	}
+	var events []SrtEpollEvent
+	if fdsSet != nil && fdsSize > 0 {
+		events = (*[1 << 26]SrtEpollEvent)(unsafe.Pointer(fdsSet))[:fdsSize:fdsSize]
+	}
+	mock.Lock()
+	defer mock.Unlock()
+	deadline := mockDeadline(int(msTimeOut))
+	for {
+		ep := mock.epolls[epfd]
+		if ep == nil {
+			return 0, mockErr(EINVPOLLID)
+		}
+		if len(ep.subs) == 0 && ep.flags&EpollEnableEmpty == 0 {
+			return 0, mockErr(epollEmpty)
+		}
+		if n = ep.wait(events); n > 0 {
+			return n, nil
+		}
+		if mockWait(deadline) {
+			return 0, nil
+		}
+	}
+}
+
+// EpollRelease call srt_epoll_release
+func EpollRelease(epfd int) (err error) {
+	ep, err := mockEpollOf("srt_epoll_release", epfd)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	for fd := range ep.subs {
+		if s := mock.socks[fd]; s != nil {
+			delete(s.epolls, epfd)
+		}
+	}
+	delete(mock.epolls, epfd)
+	mock.cond.Broadcast()
+	return nil
+}
+
+// GetFdFromEpollEvent return fd from SrtEpollEvent
+func GetFdFromEpollEvent(fds *SrtEpollEvent) SrtSocket {
+	return fds.fd
+}
+
+// GetEventsFromEpollEvent return events from SrtEpollEvent
+func GetEventsFromEpollEvent(fds *SrtEpollEvent) int {
+	return int(fds.events)
+}
+
+// EpollSet call srt_epoll_set
+func EpollSet(epfd int, flags int) (oflags int, err error) {
+	ep, err := mockEpollOf("srt_epoll_set", epfd)
+	if err != nil {
+		return APIError, err
+	}
+	defer mock.Unlock()
+	if flags != -1 {
+		ep.flags = flags
+	}
+	return ep.flags, nil
+}
+
+// mockRawSockaddr copies sa to rsa.
+func mockRawSockaddr(sa syscall.Sockaddr, rsa *syscall.RawSockaddrAny, addrlen *_Socklen) error {
+	p, n, err := sockaddr(sa)
+	if err != nil {
+		return err
+	}
+	if n > *addrlen {
+		return EINVPARAM
+	}
+	copy((*[SizeofSockaddrAny]byte)(unsafe.Pointer(rsa))[:n], (*[SizeofSockaddrAny]byte)(p)[:n])
+	*addrlen = n
+	return nil
+}
+
+// mockSockaddr converts a raw address of addrlen bytes.
+func mockSockaddr(addr unsafe.Pointer, addrlen _Socklen) (syscall.Sockaddr, error) {
+	if addr == nil || addrlen <= 0 || addrlen > SizeofSockaddrAny {
+		return nil, EINVPARAM
+	}
+	var rsa syscall.RawSockaddrAny
+	copy((*[SizeofSockaddrAny]byte)(unsafe.Pointer(&rsa))[:addrlen], (*[SizeofSockaddrAny]byte)(addr)[:addrlen])
+	return anyToSockaddr(&rsa)
+}
+
+func accept(s int, rsa *syscall.RawSockaddrAny, addrlen *_Socklen) (fd int, err error) {
+	l, err := mockSocketOf("srt_accept", s)
+	if err != nil {
+		return APIError, err
+	}
+	defer mock.Unlock()
+	deadline := mockDeadline(l.intOpt(OptionRcvtimeo))
+	for len(l.pending) == 0 {
+		if mock.socks[s] != l {
+			return APIError, mockErr(EINVSOCK)
+		}
+		if l.state != StatusListening {
+			return APIError, mockErr(ENOLISTEN)
+		}
+		if !l.boolOpt(OptionRcvsyn) {
+			return APIError, mockErr(EASYNCRCV)
+		}
+		if mockWait(deadline) {
+			return APIError, mockErr(ETIMEOUT)
+		}
+	}
+	ns := l.pending[0]
+	l.pending = l.pending[1:]
+	if err := mockRawSockaddr(ns.raddr, rsa, addrlen); err != nil {
+		return APIError, mockErr(err)
+	}
+	return ns.id, nil
+}
+
+func getsockname(s int, rsa *syscall.RawSockaddrAny, addrlen *_Socklen) (err error) {
+	so, err := mockSocketOf("srt_getsockname", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	if !so.bound {
+		return mockErr(EUNBOUNDSOCK)
+	}
+	return mockErr(mockRawSockaddr(so.laddr, rsa, addrlen))
+}
+
+func getpeername(s int, rsa *syscall.RawSockaddrAny, addrlen *_Socklen) (err error) {
+	so, err := mockSocketOf("srt_getpeername", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	if so.state != StatusConnected || so.raddr == nil {
+		return mockErr(ENOCONN)
+	}
+	return mockErr(mockRawSockaddr(so.raddr, rsa, addrlen))
+}
+
+func bind(s int, addr unsafe.Pointer, addrlen _Socklen) (err error) {
+	sa, err := mockSockaddr(addr, addrlen)
+	if err != nil {
+		return mockErr(err)
+	}
+	so, err := mockSocketOf("srt_bind", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	if so.state != StatusInit {
+		return mockErr(EINVOP)
+	}
+	return so.bind(sa)
+}
+
+func connect(s int, addr unsafe.Pointer, addrlen _Socklen) (err error) {
+	sa, err := mockSockaddr(addr, addrlen)
+	if err != nil {
+		return mockErr(err)
+	}
+	so, err := mockSocketOf("srt_connect", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	switch so.state {
+	case StatusInit:
+		if so.boolOpt(OptionRendezvous) {
+			return mockErr(ERDVUNBOUND)
+		}
+		// Everything is local, so the address of the peer serves
+		// as the local one.
+		so.bind(mockAddr(sa, 0))
+	case StatusOpened:
+	case StatusListening:
+		return mockErr(EINVOP)
+	default:
+		return mockErr(ECONNSOCK)
+	}
+	so.state = StatusConnecting
+	so.raddr = sa
+	go so.handshake(sa, mock.net.Delay)
+	if !so.boolOpt(OptionRcvsyn) {
+		return nil
+	}
+	for so.state == StatusConnecting {
+		mockWait(time.Time{})
+	}
+	switch {
+	case so.state == StatusConnected:
+		return nil
+	case so.reject == RejectTimeout:
+		return mockErr(ENOSERVER)
+	case so.reject != RejectUnknown:
+		return mockErr(ECONNREJ)
+	}
+	return mockErr(ECONNSETUP)
+}
+
+func socket() (fd int, err error) {
+	if err = mockFail("srt_create_socket", 0); err != nil {
+		return APIError, err
+	}
+	mock.Lock()
+	defer mock.Unlock()
+	s := newMockSocket()
+	mock.socks[s.id] = s
+	return s.id, nil
+}
+
+func getsockflag(s int, name int, val unsafe.Pointer, vallen *_Socklen) (err error) {
+	so, err := mockSocketOf("srt_getsockflag", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	b, err := so.getOpt(name)
+	if err != nil {
+		return mockErr(err)
+	}
+	if len(b) > int(*vallen) {
+		return mockErr(EINVPARAM)
+	}
+	if len(b) > 0 {
+		copy((*[1 << 20]byte)(val)[:len(b)], b)
+	}
+	*vallen = _Socklen(len(b))
+	return nil
+}
+
+func setsockflag(s int, name int, val unsafe.Pointer, vallen uintptr) (err error) {
+	so, err := mockSocketOf("srt_setsockflag", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	var b []byte
+	if vallen > 0 {
+		b = (*[1 << 20]byte)(val)[:vallen:vallen]
+	}
+	if err = so.setOpt(name, b); err != nil {
+		return mockErr(err)
+	}
+	mock.cond.Broadcast()
+	return nil
+}
+
+func getsockopt(s int, level int, name int, val unsafe.Pointer, vallen *_Socklen) (err error) {
+	return getsockflag(s, name, val, vallen)
+}
+
+func setsockopt(s int, level int, name int, val unsafe.Pointer, vallen uintptr) (err error) {
+	return setsockflag(s, name, val, vallen)
+}
+
+// Listen call srt_listen
+func Listen(s int, n int) (err error) {
+	so, err := mockSocketOf("srt_listen", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	switch {
+	case so.boolOpt(OptionRendezvous):
+		return mockErr(ERDVNOSERV)
+	case n <= 0:
+		return mockErr(EINVPARAM)
+	case so.state == StatusListening:
+		so.backlog = n
+		return nil
+	case so.state == StatusInit:
+		return mockErr(EUNBOUNDSOCK)
+	case so.state != StatusOpened:
+		return mockErr(ECONNSOCK)
+	}
+	if l := lookupListener(so.laddr); l != nil {
+		return mockErr(EDUPLISTEN)
+	}
+	so.state = StatusListening
+	so.backlog = n
+	return nil
+}
+
+// ListenCallback call srt_listen_callback
+func ListenCallback(s int, callback SrtListenCallbackFunc) (err error) {
+	so, err := mockSocketOf("srt_listen_callback", s)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	so.callback = callback
+	return nil
+}
+
+// SetRejectReason call srt_setrejectreason
+func SetRejectReason(fd int, reason int) (err error) {
+	so, err := mockSocketOf("srt_setrejectreason", fd)
+	if err != nil {
+		return err
+	}
+	defer mock.Unlock()
+	if reason < RejectPredefined {
+		return mockErr(EINVPARAM)
+	}
+	so.reject = reason
+	return nil
+}
+
+// GetRejectReason call srt_getrejectreason
+func GetRejectReason(fd int) int {
+	mock.Lock()
+	defer mock.Unlock()
+	if s := mock.socks[fd]; s != nil {
+		return s.reject
+	}
+	return RejectUnknown
+}
+
+var mockRejectReasons = []string{
+	RejectUnknown:    "Unknown or erroneous",
+	RejectSystem:     "Error in system calls",
+	RejectPeer:       "Peer rejected connection",
+	RejectResource:   "Resource allocation failure",
+	RejectRogue:      "Rogue peer or incorrect parameters",
+	RejectBacklog:    "Listener's backlog exceeded",
+	RejectIPE:        "Internal Program Error",
+	RejectClose:      "Socket is being closed",
+	RejectVersion:    "Peer version too old",
+	RejectRdvcookie:  "Rendezvous-mode cookie collision",
+	RejectBadsecret:  "Incorrect passphrase",
+	RejectUnsecure:   "Password required or unexpected",
+	RejectMessageapi: "MessageAPI/StreamAPI collision",
+	RejectCongestion: "Congestion controller type collision",
+	RejectFilter:     "Packet Filter settings error",
+	RejectGroup:      "Group settings collision",
+	RejectTimeout:    "Connection timeout",
+}
+
+// RejectReasonString call srt_rejectreason_str
+func RejectReasonString(reason int) string {
+	switch {
+	case reason >= RejectUserdefined:
+		return "Application-defined rejection reason"
+	case reason >= RejectPredefined:
+		return "Application-defined rejection reason"
+	case reason >= 0 && reason < len(mockRejectReasons):
+		return mockRejectReasons[reason]
+	}
+	return mockRejectReasons[RejectUnknown]
+}
+
+// Close call srt_close. Like srt_close, it succeeds for a socket that
+// does not exist (anymore).
+func Close(fd int) (err error) {
+	if err = mockFail("srt_close", fd); err != nil {
+		return err
+	}
+	mock.Lock()
+	defer mock.Unlock()
+	if so := mock.socks[fd]; so != nil {
+		so.close()
+	}
+	return nil
+}
+
+// mockRecv receives from fd, blocking in synchronous mode.
+func mockRecv(call string, fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	so, err := mockSocketOf(call, fd)
+	if err != nil {
+		return APIError, err
+	}
+	defer mock.Unlock()
+	deadline := mockDeadline(so.intOpt(OptionRcvtimeo))
+	for {
+		n, err = so.recv(p, mctrl)
+		if err != EASYNCRCV || !so.boolOpt(OptionRcvsyn) {
+			break
+		}
+		if mockWait(deadline) {
+			err = ETIMEOUT
+			break
+		}
+	}
+	if err != nil {
+		return APIError, mockErr(err)
+	}
+	return n, nil
+}
+
+// mockSend sends to fd, blocking in synchronous mode.
+func mockSend(call string, fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	so, err := mockSocketOf(call, fd)
+	if err != nil {
+		return APIError, err
+	}
+	defer mock.Unlock()
+	deadline := mockDeadline(so.intOpt(OptionSndtimeo))
+	for {
+		n, err = so.send(p, mctrl)
+		if err != EASYNCSND || !so.boolOpt(OptionSndsyn) {
+			break
+		}
+		if mockWait(deadline) {
+			err = ETIMEOUT
+			break
+		}
+	}
+	if err != nil {
+		return APIError, mockErr(err)
+	}
+	return n, nil
+}
+
+func read(fd int, p []byte) (n int, err error) {
+	return mockRecv("srt_recv", fd, p, nil)
+}
+
+func recvmsg2(fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	return mockRecv("srt_recvmsg2", fd, p, mctrl)
+}
+
+func sendmsg2(fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	return mockSend("srt_sendmsg2", fd, p, mctrl)
+}
+
+func write(fd int, p []byte) (n int, err error) {
+	return mockSend("srt_send", fd, p, nil)
+}
+
+// TimeNow call srt_time_now
+func TimeNow() int64 {
+	return mockSince(mockNow())
+}
+
+func sendfile(outfd int, r io.Reader, offset *int64, count int, block int) (written int, err error) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return APIError, mockErr(EINVPARAM)
+	}
+	// srt_sendfile opens the file by name before looking at the socket.
+	in, oerr := os.Open(f.Name())
+	if oerr != nil {
+		return APIError, mockErr(ERDPERM)
+	}
+	defer in.Close()
+	so, err := mockSocketOf("srt_sendfile", outfd)
+	if err != nil {
+		return APIError, err
+	}
+	messageAPI := so.boolOpt(OptionMessageapi)
+	mock.Unlock()
+	if messageAPI {
+		return APIError, mockErr(EINVALBUFFERAPI)
+	}
+	var off int64
+	if offset != nil {
+		off = *offset
+	}
+	defer func() {
+		if offset != nil {
+			*offset = off
+		}
+	}()
+	if block <= 0 {
+		block = DefaultSendfileBlock
+	}
+	if count < block {
+		block = count
+	}
+	buf := make([]byte, block)
+	for written < count {
+		b := buf
+		if count-written < len(b) {
+			b = b[:count-written]
+		}
+		n, rerr := in.ReadAt(b, off)
+		// srt_sendfile always blocks until the data is sent.
+		for sent := 0; sent < n; {
+			mock.Lock()
+			m, err := so.send(b[sent:n], nil)
+			if err == EASYNCSND {
+				mockWait(time.Time{})
+				err = nil
+			}
+			mock.Unlock()
+			if err != nil {
+				return APIError, mockErr(err)
+			}
+			sent += m
+			off += int64(m)
+			written += m
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return APIError, mockErr(ERDPERM)
+		}
+	}
+	return written, nil
+}
+
+func recvfile(infd int, w io.Writer, offset *int64, count int) (received int, err error) {
+	f, ok := w.(*os.File)
+	if !ok {
+		return APIError, mockErr(EINVPARAM)
+	}
+	// srt_recvfile opens the file by name before looking at the socket.
+	out, oerr := os.OpenFile(f.Name(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if oerr != nil {
+		return APIError, mockErr(EWRPERM)
+	}
+	defer out.Close()
+	so, err := mockSocketOf("srt_recvfile", infd)
+	if err != nil {
+		return APIError, err
+	}
+	messageAPI, live := so.boolOpt(OptionMessageapi), so.boolOpt(OptionTsbpdmode)
+	mock.Unlock()
+	if messageAPI {
+		return APIError, mockErr(EINVALBUFFERAPI)
+	}
+	if live {
+		return APIError, mockErr(EINVRDOFF)
+	}
+	var off int64
+	if offset != nil {
+		off = *offset
+	}
+	block := DefaultRecvfileBlock
+	if count < block {
+		block = count
+	}
+	buf := make([]byte, block)
+	for received < count {
+		b := buf
+		if count-received < len(b) {
+			b = b[:count-received]
+		}
+		// srt_recvfile always blocks until the data is received.
+		mock.Lock()
+		n, err := so.recv(b, nil)
+		for err == EASYNCRCV {
+			mockWait(time.Time{})
+			n, err = so.recv(b, nil)
+		}
+		if n == 0 && err == nil {
+			// Unlike srt_recv, srt_recvfile fails at the end of a
+			// stream the peer closed.
+			err = ECONNLOST
+		}
+		mock.Unlock()
+		if err != nil {
+			if offset != nil {
+				*offset = off
+			}
+			return APIError, mockErr(err)
+		}
+		if _, werr := out.WriteAt(b[:n], off); werr != nil {
+			if offset != nil {
+				*offset = off
+			}
+			return APIError, mockErr(EWRPERM)
+		}
+		off += int64(n)
+		received += n
+	}
+	if offset != nil {
+		*offset = off
+	}
+	return received, nil
+}
+
+func getlasterror() int {
+	return int(atomic.LoadInt32(&mockLastErr))
+}
+
+var mockErrors = map[Errno]string{
+	EUNKNOWN:        "Unknown error",
+	SUCCESS:         "Success",
+	ECONNSETUP:      "Connection setup failure",
+	ENOSERVER:       "Connection setup failure: connection timed out",
+	ECONNREJ:        "Connection setup failure: connection rejected",
+	ESOCKFAIL:       "Connection setup failure: unable to create/configure SRT socket",
+	ESECFAIL:        "Connection setup failure: abort for security reasons",
+	ECONNFAIL:       "Connection failure",
+	ECONNLOST:       "Connection was broken",
+	ENOCONN:         "Connection does not exist",
+	ERESOURCE:       "System resource failure",
+	ETHREAD:         "System resource failure: unable to create new threads",
+	ENOBUF:          "System resource failure: unable to allocate buffers",
+	EFILE:           "File system failure",
+	EINVRDOFF:       "File system failure: cannot seek read position",
+	ERDPERM:         "File system failure: failure in read",
+	EINVWROFF:       "File system failure: cannot seek write position",
+	EWRPERM:         "File system failure: failure in write",
+	EINVOP:          "Operation not supported",
+	EBOUNDSOCK:      "Operation not supported: Cannot do this operation on a BOUND socket",
+	ECONNSOCK:       "Operation not supported: Cannot do this operation on a CONNECTED socket",
+	EINVPARAM:       "Operation not supported: Bad parameters",
+	EINVSOCK:        "Operation not supported: Invalid socket ID",
+	EUNBOUNDSOCK:    "Operation not supported: Cannot do this operation on an UNBOUND socket",
+	ENOLISTEN:       "Operation not supported: Socket is not in listening state",
+	ERDVNOSERV:      "Operation not supported: Listen/accept is not supported in rendezous connection setup",
+	ERDVUNBOUND:     "Operation not supported: Cannot call connect on UNBOUND socket in rendezvous connection setup",
+	EINVALMSGAPI:    "Operation not supported: Incorrect use of Message API (sendmsg/recvmsg).",
+	EINVALBUFFERAPI: "Operation not supported: Incorrect use of Buffer API (send/recv) or File API (sendfile/recvfile).",
+	EDUPLISTEN:      "Operation not supported: Another socket is already listening on the same port",
+	ELARGEMSG:       "Operation not supported: Message is too large to send (it must be less than the SRT send buffer size)",
+	EINVPOLLID:      "Operation not supported: Invalid epoll ID",
+	epollEmpty:      "Operation not supported: All sockets removed from epoll, waiting would deadlock",
+	EASYNCFAIL:      "Non-blocking call failure",
+	EASYNCSND:       "Non-blocking call failure: no buffer available for sending",
+	EASYNCRCV:       "Non-blocking call failure: no data available for reading",
+	ETIMEOUT:        "The operation timed out",
+	ECONGEST:        "Congestion control failure",
+	EPEERERR:        "The peer side has signaled an error",
+}
+
+func strerror(code int, errnoval int) string {
+	if s, ok := mockErrors[Errno(code)]; ok {
+		return s
+	}
+	return mockErrors[EUNKNOWN]
+}
+
+// ClearLastError call srt_clearlasterror
+func ClearLastError() {
+	atomic.StoreInt32(&mockLastErr, 0)
+}
+
+// SetLogLevel call srt_setloglevel. The mock does not log.
+func SetLogLevel(level int) {}
+
+// AddLogFA call srt_addlogfa. The mock does not log.
+func AddLogFA(fa int) {}
+
+// DelLogFA call srt_dellogfa. The mock does not log.
+func DelLogFA(fa int) {}
+
+// ResetLogFA call srt_resetlogfa. The mock does not log.
+func ResetLogFA(fas []int) {}
+
+// SetLogFlags call srt_setlogflags. The mock does not log.
+func SetLogFlags(flags int) {}
+
+// Bistats call srt_bistats
+func Bistats(fd int, clear bool, instantaneous bool) (stats *TraceBStats, err error) {
+	so, err := mockSocketOf("srt_bistats", fd)
+	if err != nil {
+		return nil, err
+	}
+	defer mock.Unlock()
+	if so.state != StatusConnected && so.state != StatusBroken {
+		return nil, mockErr(ENOCONN)
+	}
+	return so.stats(clear), nil
+}
+
+// Socket groups are not supported by the mock.
+
+// CreateGroup call srt_create_group
+func CreateGroup(typ int) (fd int, err error) {
+	if err = mockFail("srt_create_group", 0); err != nil {
+		return APIError, err
+	}
+	return APIError, mockErr(EINVOP)
+}
+
+// GroupOf call srt_groupof
+func GroupOf(fd int) (gfd int, err error) {
+	if _, err = mockSocketOf("srt_groupof", fd); err != nil {
+		return APIError, err
+	}
+	mock.Unlock()
+	return APIError, mockErr(EINVPARAM)
+}
+
+// ConnectGroup call srt_connect_group
+func ConnectGroup(group int, members []GroupMemberConfig) (fd int, err error) {
+	if len(members) == 0 {
+		return APIError, EINVPARAM
+	}
+	return APIError, mockErr(EINVSOCK)
+}
+
+// GroupData call srt_group_data
+func GroupData(group int) (members []GroupMemberData, err error) {
+	return nil, mockErr(EINVSOCK)
+}
@@ -43,6 +43,18 @@ func Write(fd int, p []byte) (n int, err error) {
 	return
 }
 
+// Recvmsg2 call srt_recvmsg2
+func Recvmsg2(fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	n, err = recvmsg2(fd, p, mctrl)
+	return
+}
+
+// Sendmsg2 call srt_sendmsg2
+func Sendmsg2(fd int, p []byte, mctrl *MsgCtrl) (n int, err error) {
+	n, err = sendmsg2(fd, p, mctrl)
+	return
+}
+
 // Bind call srt_bind
 func Bind(fd int, sa syscall.Sockaddr) (err error) {
 	ptr, n, err := sockaddr(sa)
@@ -99,6 +111,31 @@ func GetsockflagInt(fd, opt int) (value int, err error) {
 	return int(n), err
 }
 
+// GetsockflagInt64 call srt_getsockflag
+func GetsockflagInt64(fd, opt int) (value int64, err error) {
+	var n int64
+	vallen := _Socklen(8)
+	err = getsockflag(fd, opt, unsafe.Pointer(&n), &vallen)
+	return n, err
+}
+
+// GetsockflagBool call srt_getsockflag
+func GetsockflagBool(fd, opt int) (value bool, err error) {
+	// Depending on the option and the library version, the value
+	// is returned as a 1 byte bool or a 4 byte int.
+	var buf [4]byte
+	vallen := _Socklen(len(buf))
+	if err = getsockflag(fd, opt, unsafe.Pointer(&buf[0]), &vallen); err != nil {
+		return false, err
+	}
+	for _, b := range buf[:vallen] {
+		if b != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // GetsockflagString returns the string value of the socket flag for the
 // socket associated with a fd
 func GetsockflagString(fd, opt int) (string, error) {
@@ -179,8 +216,27 @@ func Socket() (fd int, err error) {
 	return
 }
 
+// Sendfile call srt_sendfile. srt_sendfile opens the file of r, which
+// must be an *os.File, by name and sends count bytes of it from *offset
+// on, in blocks of DefaultSendfileBlock bytes. It blocks until the data
+// is handed to the library or the connection fails; *offset is
+// advanced past the data sent in any case.
 func Sendfile(outfd int, r io.Reader, offset *int64, count int) (written int, err error) {
-	return sendfile(outfd, r, offset, count)
+	return sendfile(outfd, r, offset, count, DefaultSendfileBlock)
+}
+
+// SendfileBlock is Sendfile with a block size of block bytes; a block
+// of 0 is DefaultSendfileBlock.
+func SendfileBlock(outfd int, r io.Reader, offset *int64, count int, block int) (written int, err error) {
+	return sendfile(outfd, r, offset, count, block)
+}
+
+// Recvfile call srt_recvfile. srt_recvfile opens the file of w by name,
+// truncating it, and writes the data from *offset on. It blocks until
+// count bytes are received or the connection is closed; *offset is
+// advanced past the data written in any case.
+func Recvfile(infd int, w io.Writer, offset *int64, count int) (received int, err error) {
+	return recvfile(infd, w, offset, count)
 }
 
 // Accept call srt_accept
@@ -1,15 +1,13 @@
 // Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
 // https://github.com/openfresh/gosrt
 
+// +build !srtmock
+
 package srtapi
 
 // #cgo LDFLAGS: -lsrt
 // #include <srt/srt.h>
 import "C"
-import (
-	"syscall"
-	"unsafe"
-)
 
 type _Socklen C.int
 
@@ -19,22 +17,6 @@ type SrtSocket C.SRTSOCKET
 // SrtEpollEvent represent SRT C API SRT_EPOLL_EVENT structure
 type SrtEpollEvent C.SRT_EPOLL_EVENT
 
-//lint:ignore U1000 we want to use it to calculate size
-var rsa syscall.RawSockaddrAny
-
-//lint:ignore U1000 we want to use it to calculate size
-var rs4 syscall.RawSockaddrInet4
-
-//lint:ignore U1000 we want to use it to calculate size
-var rs6 syscall.RawSockaddrInet6
-
-// Size of raw sock addr structures
-const (
-	SizeofSockaddrAny   = _Socklen(unsafe.Sizeof(rsa))
-	SizeofSockaddrInet4 = _Socklen(unsafe.Sizeof(rs4))
-	SizeofSockaddrInet6 = _Socklen(unsafe.Sizeof(rs6))
-)
-
 // SRT socket status
 const (
 	StatusInit       = C.SRTS_INIT
@@ -103,6 +85,32 @@ const (
 	OptionIpv60only     = C.SRTO_IPV6ONLY
 	OptionPeeridletimeo = C.SRTO_PEERIDLETIMEO
 	OptionPacketfilter = C.SRTO_PACKETFILTER
+	OptionGroupconnect  = C.SRTO_GROUPCONNECT
+	OptionGroupminstabletimeo = C.SRTO_GROUPMINSTABLETIMEO
+	OptionGrouptype     = C.SRTO_GROUPTYPE
+)
+
+// SRT reject reasons
+const (
+	RejectUnknown     = C.SRT_REJ_UNKNOWN
+	RejectSystem      = C.SRT_REJ_SYSTEM
+	RejectPeer        = C.SRT_REJ_PEER
+	RejectResource    = C.SRT_REJ_RESOURCE
+	RejectRogue       = C.SRT_REJ_ROGUE
+	RejectBacklog     = C.SRT_REJ_BACKLOG
+	RejectIPE         = C.SRT_REJ_IPE
+	RejectClose       = C.SRT_REJ_CLOSE
+	RejectVersion     = C.SRT_REJ_VERSION
+	RejectRdvcookie   = C.SRT_REJ_RDVCOOKIE
+	RejectBadsecret   = C.SRT_REJ_BADSECRET
+	RejectUnsecure    = C.SRT_REJ_UNSECURE
+	RejectMessageapi  = C.SRT_REJ_MESSAGEAPI
+	RejectCongestion  = C.SRT_REJ_CONGESTION
+	RejectFilter      = C.SRT_REJ_FILTER
+	RejectGroup       = C.SRT_REJ_GROUP
+	RejectTimeout     = C.SRT_REJ_TIMEOUT
+	RejectPredefined  = C.SRT_REJC_PREDEFINED
+	RejectUserdefined = C.SRT_REJC_USERDEFINED
 )
 
 // SRT trans type
@@ -112,50 +120,22 @@ const (
 	TypeInvalid = C.SRTT_INVALID
 )
 
-// SRT log level
-const (
-	LogEmerg   = 0
-	LogAlert   = 1
-	LogFatal   = 2
-	LogError   = 3
-	LogWarning = 4
-	LogNote    = 5
-	LogInfo    = 6
-	LogDebug   = 7
-)
-
-// SRT log FA
-const (
-	LogFAGeneral = 0
-	LogFABstats  = 1
-	LogFAControl = 2
-	LogFAData    = 3
-	LogFATsbpd   = 4
-	LogFARexmit  = 5
-)
-
-// SRT log flags
+// SRT KM state
 const (
-	LogFlagDisableTime       = 1
-	LogFlagDisableThreadname = 2
-	LogFlagDisableSeverity   = 4
-	LogFlagDisableEOF        = 8
+	KmStateUnsecured = C.SRT_KM_S_UNSECURED
+	KmStateSecuring  = C.SRT_KM_S_SECURING
+	KmStateSecured   = C.SRT_KM_S_SECURED
+	KmStateNosecret  = C.SRT_KM_S_NOSECRET
+	KmStateBadsecret = C.SRT_KM_S_BADSECRET
 )
 
 // SRT epoll opt
 const (
-	EpollIn  = C.SRT_EPOLL_IN
-	EpollOut = C.SRT_EPOLL_OUT
-	EpollErr = C.SRT_EPOLL_ERR
-	EpollEt  = C.SRT_EPOLL_ET
-)
-
-// SRT const
-const (
-	InvalidSock          = -1
-	APIError             = -1
-	DefaultSendfileBlock = 364000
-	DefaultRecvfileBlock = 7280000
+	EpollIn     = C.SRT_EPOLL_IN
+	EpollOut    = C.SRT_EPOLL_OUT
+	EpollErr    = C.SRT_EPOLL_ERR
+	EpollUpdate = C.SRT_EPOLL_UPDATE
+	EpollEt     = C.SRT_EPOLL_ET
 )
 
 // SRT_EPOLL_FLAGS
@@ -0,0 +1,207 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build srtmock
+
+package srtapi
+
+// The values below mirror the ones of the SRT C API, so that code
+// built with the srtmock tag sees the same numbers as with libsrt.
+
+type _Socklen int32
+
+// SrtSocket represents SRT C API SRTSOCKET type
+type SrtSocket int32
+
+// SrtEpollEvent represent SRT C API SRT_EPOLL_EVENT structure
+type SrtEpollEvent struct {
+	fd     SrtSocket
+	events int32
+}
+
+// SRT socket status
+const (
+	StatusInit       = 1
+	StatusOpened     = 2
+	StatusListening  = 3
+	StatusConnecting = 4
+	StatusConnected  = 5
+	StatusBroken     = 6
+	StatusClosing    = 7
+	StatusClosed     = 8
+	StatusNonexist   = 9
+)
+
+// SRT socket options
+const (
+	OptionMss                 = 0
+	OptionSndsyn              = 1
+	OptionRcvsyn              = 2
+	OptionIsn                 = 3
+	OptionFc                  = 4
+	OptionSndbuf              = 5
+	OptionRcvbuf              = 6
+	OptionLinger              = 7
+	OptionUDPSndbuf           = 8
+	OptionUDPRcvbuf           = 9
+	OptionRendezvous          = 12
+	OptionSndtimeo            = 13
+	OptionRcvtimeo            = 14
+	OptionReuseaddr           = 15
+	OptionMaxbw               = 16
+	OptionState               = 17
+	OptionEvent               = 18
+	OptionSnddata             = 19
+	OptionRcvdata             = 20
+	OptionSender              = 21
+	OptionTsbpdmode           = 22
+	OptionLatency             = 23
+	OptionInputbw             = 24
+	OptionOheadbw             = 25
+	OptionPassphrase          = 26
+	OptionPbkeylen            = 27
+	OptionKmstate             = 28
+	OptionIpttl               = 29
+	OptionIptos               = 30
+	OptionTlpktdrop           = 31
+	OptionSnddropdelay        = 32
+	OptionNakreport           = 33
+	OptionVersion             = 34
+	OptionPeerversion         = 35
+	OptionConntimeo           = 36
+	OptionSndkmstate          = 40
+	OptionRcvkmstate          = 41
+	OptionLossmaxttl          = 42
+	OptionRcvlatency          = 43
+	OptionPeerlatency         = 44
+	OptionMinversion          = 45
+	OptionStreamid            = 46
+	OptionCongestion          = 47
+	OptionMessageapi          = 48
+	OptionPayloadsize         = 49
+	OptionTranstype           = 50
+	OptionKmrefreshrate       = 51
+	OptionKmpreannounce       = 52
+	OptionEnforcedencryption  = 53
+	OptionIpv60only           = 54
+	OptionPeeridletimeo       = 55
+	OptionPacketfilter        = 60
+	OptionGroupconnect        = 57
+	OptionGroupminstabletimeo = 58
+	OptionGrouptype           = 59
+)
+
+// SRT reject reasons
+const (
+	RejectUnknown     = 0
+	RejectSystem      = 1
+	RejectPeer        = 2
+	RejectResource    = 3
+	RejectRogue       = 4
+	RejectBacklog     = 5
+	RejectIPE         = 6
+	RejectClose       = 7
+	RejectVersion     = 8
+	RejectRdvcookie   = 9
+	RejectBadsecret   = 10
+	RejectUnsecure    = 11
+	RejectMessageapi  = 12
+	RejectCongestion  = 13
+	RejectFilter      = 14
+	RejectGroup       = 15
+	RejectTimeout     = 16
+	RejectPredefined  = 1000
+	RejectUserdefined = 2000
+)
+
+// SRT trans type
+const (
+	TypeLive    = 0
+	TypeFile    = 1
+	TypeInvalid = 2
+)
+
+// SRT KM state
+const (
+	KmStateUnsecured = 0
+	KmStateSecuring  = 1
+	KmStateSecured   = 2
+	KmStateNosecret  = 3
+	KmStateBadsecret = 4
+)
+
+// SRT epoll opt
+const (
+	EpollIn     = 0x1
+	EpollOut    = 0x4
+	EpollErr    = 0x8
+	EpollUpdate = 0x10
+	EpollEt     = 1 << 31
+)
+
+// SRT_EPOLL_FLAGS
+const (
+	EpollEnableEmpty       = 1
+	EpollEnableOutputcheck = 2
+)
+
+// SRT group types
+const (
+	GroupTypeUndefined = 0
+	GroupTypeBroadcast = 1
+	GroupTypeBackup    = 2
+	GroupTypeBalancing = 3
+)
+
+// SRT group member status
+const (
+	MemberStatusPending = 0
+	MemberStatusIdle    = 1
+	MemberStatusRunning = 2
+	MemberStatusBroken  = 3
+)
+
+// Errors
+const (
+	EUNKNOWN        = Errno(-1)
+	SUCCESS         = Errno(0)
+	ECONNSETUP      = Errno(1000)
+	ENOSERVER       = Errno(1001)
+	ECONNREJ        = Errno(1002)
+	ESOCKFAIL       = Errno(1003)
+	ESECFAIL        = Errno(1004)
+	ECONNFAIL       = Errno(2000)
+	ECONNLOST       = Errno(2001)
+	ENOCONN         = Errno(2002)
+	ERESOURCE       = Errno(3000)
+	ETHREAD         = Errno(3001)
+	ENOBUF          = Errno(3002)
+	EFILE           = Errno(4000)
+	EINVRDOFF       = Errno(4001)
+	ERDPERM         = Errno(4002)
+	EINVWROFF       = Errno(4003)
+	EWRPERM         = Errno(4004)
+	EINVOP          = Errno(5000)
+	EBOUNDSOCK      = Errno(5001)
+	ECONNSOCK       = Errno(5002)
+	EINVPARAM       = Errno(5003)
+	EINVSOCK        = Errno(5004)
+	EUNBOUNDSOCK    = Errno(5005)
+	ENOLISTEN       = Errno(5006)
+	ERDVNOSERV      = Errno(5007)
+	ERDVUNBOUND     = Errno(5008)
+	EINVALMSGAPI    = Errno(5009)
+	EINVALBUFFERAPI = Errno(5010)
+	EDUPLISTEN      = Errno(5011)
+	ELARGEMSG       = Errno(5012)
+	EINVPOLLID      = Errno(5013)
+	EASYNCFAIL      = Errno(6000)
+	EASYNCSND       = Errno(6001)
+	EASYNCRCV       = Errno(6002)
+	ETIMEOUT        = Errno(6003)
+	ECONGEST        = Errno(6004)
+	EPEERERR        = Errno(7000)
+)
+
+// epollEmpty is SRT_EPOLLEMPTY, which has no exported name.
+const epollEmpty = Errno(5014)